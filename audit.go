@@ -0,0 +1,432 @@
+package driplimit
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// AuditResult is the result of an audited call.
+type AuditResult string
+
+const (
+	AuditSuccess AuditResult = "success"
+	AuditFailure AuditResult = "failure"
+)
+
+// AuditEntry records a mutating call made to the service.
+type AuditEntry struct {
+	AEID      string          `json:"aeid"`
+	SKID      string          `json:"skid"`
+	Action    string          `json:"action"`
+	TargetIDs []string        `json:"target_ids"`
+	Payload   json.RawMessage `json:"payload"`
+	Result    AuditResult     `json:"result"`
+	Error     string          `json:"error,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// AuditList represents a list of audit entries.
+type AuditList struct {
+	List    ListMetadata  `json:"list"`
+	Entries []*AuditEntry `json:"entries"`
+}
+
+// AuditListPayload represents the payload for listing audit entries, most recent first.
+type AuditListPayload struct {
+	*payload
+
+	List     ListPayload `json:"list" description:"The list options"`
+	SKID     string      `json:"skid,omitempty" description:"Only list the calls made by this service key"`
+	Action   string      `json:"action,omitempty" description:"Only list the calls of this action (eg. keyspaces.delete)"`
+	TargetID string      `json:"target_id,omitempty" description:"Only list the calls targeting this id (eg. a keyspace or key id)"`
+	Result   AuditResult `json:"result,omitempty" validate:"omitempty,oneof=success failure" description:"Only list the successful or failed calls"`
+	Since    time.Time   `json:"since" description:"Only list the calls made at or after this time"`
+	Until    time.Time   `json:"until" description:"Only list the calls made before this time"`
+}
+
+// Validate validates the audit list payload.
+func (a *AuditListPayload) Validate(validator *validator.Validate) error {
+	err := a.List.Validate(validator)
+	if err != nil {
+		return err
+	}
+	return validator.Struct(a)
+}
+
+// WithServiceToken adds authentication infos to payload
+func (a *AuditListPayload) WithServiceToken(token string) *AuditListPayload {
+	a.payload = &payload{
+		serviceToken: token,
+	}
+	return a
+}
+
+// AuditRecorder records audit entries.
+type AuditRecorder interface {
+	RecordAudit(ctx context.Context, entry AuditEntry) error
+}
+
+// redactedFields are the payload fields never written to the audit log. Bundles are
+// redacted as a whole as they hold keys token hashes and can be large.
+var redactedFields = map[string]bool{
+	"token":      true,
+	"token_hash": true,
+	"bundle":     true,
+}
+
+const redacted = "[redacted]"
+
+// Auditor is an audit wrapper recording every mutating call with its caller, targets and
+// result. It implements the Service interface and is meant to wrap the Authorizer so that
+// unauthorized calls are recorded as well.
+type Auditor struct {
+	driplimit Service
+	recorder  AuditRecorder
+	logger    *slog.Logger
+}
+
+// NewAuditor wraps a Driplimit Service with an auditor recording to recorder. Recording
+// errors are logged and never fail the audited call.
+func NewAuditor(driplimit Service, recorder AuditRecorder, logger *slog.Logger) *Auditor {
+	return &Auditor{
+		driplimit: driplimit,
+		recorder:  recorder,
+		logger:    logger,
+	}
+}
+
+// caller returns the id of the service key making the call. It is taken from the Caller of
+// the context when already resolved, otherwise it is resolved through the wrapped service
+// before the call is made. Unknown callers are recorded without skid.
+func (a *Auditor) caller(ctx context.Context, payload Payload) (skid string) {
+	token := payload.ServiceToken()
+	if sk := callerFrom(ctx).get(token); sk != nil {
+		return sk.SKID
+	}
+	current := ServiceKeyGetPayload{Token: token}
+	sk, err := a.driplimit.ServiceKeyGet(ctx, *current.WithServiceToken(token))
+	if err != nil {
+		return ""
+	}
+	return sk.SKID
+}
+
+// record records the call of action by skid with its redacted payload, its result and the non
+// empty targets.
+func (a *Auditor) record(ctx context.Context, skid string, action string, payload Payload, err error, targets ...string) {
+	entry := AuditEntry{
+		SKID:      skid,
+		Action:    action,
+		TargetIDs: make([]string, 0, len(targets)),
+		Payload:   redactPayload(payload),
+		Result:    AuditSuccess,
+		CreatedAt: now(),
+	}
+	for _, target := range targets {
+		if target != "" {
+			entry.TargetIDs = append(entry.TargetIDs, target)
+		}
+	}
+	if err != nil {
+		entry.Result = AuditFailure
+		entry.Error = err.Error()
+	}
+
+	recordErr := a.recorder.RecordAudit(context.WithoutCancel(ctx), entry)
+	if recordErr != nil {
+		a.logger.Error("failed to record audit entry", "action", action, "err", recordErr)
+	}
+}
+
+// redactPayload returns the json payload with the redacted fields replaced.
+func redactPayload(payload Payload) json.RawMessage {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil
+	}
+	var decoded any
+	err = json.Unmarshal(raw, &decoded)
+	if err != nil {
+		return nil
+	}
+	raw, err = json.Marshal(redact(decoded))
+	if err != nil {
+		return nil
+	}
+	return raw
+}
+
+func redact(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		for field, fieldValue := range value {
+			if redactedFields[field] {
+				value[field] = redacted
+				continue
+			}
+			value[field] = redact(fieldValue)
+		}
+	case []any:
+		for i, item := range value {
+			value[i] = redact(item)
+		}
+	}
+	return value
+}
+
+func (a *Auditor) KeyCheck(ctx context.Context, payload KeysCheckPayload) (key *Key, err error) {
+	return a.driplimit.KeyCheck(ctx, payload)
+}
+
+func (a *Auditor) KeyCheckBatch(ctx context.Context, payload KeysCheckBatchPayload) (batch *KeysCheckBatch, err error) {
+	return a.driplimit.KeyCheckBatch(ctx, payload)
+}
+
+func (a *Auditor) KeyCreate(ctx context.Context, payload KeyCreatePayload) (key *Key, err error) {
+	skid := a.caller(ctx, payload)
+	key, err = a.driplimit.KeyCreate(ctx, payload)
+	kid := ""
+	if key != nil {
+		kid = key.KID
+	}
+	a.record(ctx, skid, "keys.create", payload, err, payload.KSID, kid)
+	return key, err
+}
+
+func (a *Auditor) KeyGet(ctx context.Context, payload KeyGetPayload) (key *Key, err error) {
+	return a.driplimit.KeyGet(ctx, payload)
+}
+
+func (a *Auditor) KeyList(ctx context.Context, payload KeyListPayload) (klist *KeyList, err error) {
+	return a.driplimit.KeyList(ctx, payload)
+}
+
+func (a *Auditor) KeyDelete(ctx context.Context, payload KeyDeletePayload) (err error) {
+	skid := a.caller(ctx, payload)
+	err = a.driplimit.KeyDelete(ctx, payload)
+	a.record(ctx, skid, "keys.delete", payload, err, payload.KSID, payload.KID)
+	return err
+}
+
+func (a *Auditor) KeyDeleteMany(ctx context.Context, payload KeyDeleteManyPayload) (result *KeyDeleteManyResult, err error) {
+	skid := a.caller(ctx, payload)
+	result, err = a.driplimit.KeyDeleteMany(ctx, payload)
+	a.record(ctx, skid, "keys.delete_many", payload, err, append([]string{payload.KSID}, payload.KIDs...)...)
+	return result, err
+}
+
+func (a *Auditor) KeyReset(ctx context.Context, payload KeyResetPayload) (key *Key, err error) {
+	skid := a.caller(ctx, payload)
+	key, err = a.driplimit.KeyReset(ctx, payload)
+	a.record(ctx, skid, "keys.reset", payload, err, payload.KSID, payload.KID)
+	return key, err
+}
+
+func (a *Auditor) KeyMove(ctx context.Context, payload KeyMovePayload) (key *Key, err error) {
+	skid := a.caller(ctx, payload)
+	key, err = a.driplimit.KeyMove(ctx, payload)
+	a.record(ctx, skid, "keys.move", payload, err, payload.KSID, payload.KID, payload.TargetKSID)
+	return key, err
+}
+
+func (a *Auditor) KeyspaceGet(ctx context.Context, payload KeyspaceGetPayload) (keyspace *Keyspace, err error) {
+	return a.driplimit.KeyspaceGet(ctx, payload)
+}
+
+func (a *Auditor) KeyspaceCreate(ctx context.Context, payload KeyspaceCreatePayload) (keyspace *Keyspace, err error) {
+	skid := a.caller(ctx, payload)
+	keyspace, err = a.driplimit.KeyspaceCreate(ctx, payload)
+	ksid := ""
+	if keyspace != nil {
+		ksid = keyspace.KSID
+	}
+	a.record(ctx, skid, "keyspaces.create", payload, err, ksid)
+	return keyspace, err
+}
+
+func (a *Auditor) KeyspaceList(ctx context.Context, payload KeyspaceListPayload) (kslist *KeyspaceList, err error) {
+	return a.driplimit.KeyspaceList(ctx, payload)
+}
+
+func (a *Auditor) KeyspaceUpdate(ctx context.Context, payload KeyspaceUpdatePayload) (keyspace *Keyspace, err error) {
+	skid := a.caller(ctx, payload)
+	keyspace, err = a.driplimit.KeyspaceUpdate(ctx, payload)
+	a.record(ctx, skid, "keyspaces.update", payload, err, payload.KSID)
+	return keyspace, err
+}
+
+func (a *Auditor) KeyspaceDelete(ctx context.Context, payload KeyspaceDeletePayload) (err error) {
+	skid := a.caller(ctx, payload)
+	err = a.driplimit.KeyspaceDelete(ctx, payload)
+	a.record(ctx, skid, "keyspaces.delete", payload, err, payload.KSID)
+	return err
+}
+
+func (a *Auditor) KeyspaceClone(ctx context.Context, payload KeyspaceClonePayload) (keyspace *Keyspace, err error) {
+	skid := a.caller(ctx, payload)
+	keyspace, err = a.driplimit.KeyspaceClone(ctx, payload)
+	ksid := ""
+	if keyspace != nil {
+		ksid = keyspace.KSID
+	}
+	a.record(ctx, skid, "keyspaces.clone", payload, err, payload.KSID, ksid)
+	return keyspace, err
+}
+
+func (a *Auditor) KeyspaceStats(ctx context.Context, payload KeyspaceStatsPayload) (stats *KeyspaceStats, err error) {
+	return a.driplimit.KeyspaceStats(ctx, payload)
+}
+
+func (a *Auditor) KeyspaceExport(ctx context.Context, payload KeyspaceExportPayload) (bundle *KeyspaceBundle, err error) {
+	return a.driplimit.KeyspaceExport(ctx, payload)
+}
+
+func (a *Auditor) KeyspaceImport(ctx context.Context, payload KeyspaceImportPayload) (result *KeyspaceImportResult, err error) {
+	skid := a.caller(ctx, payload)
+	result, err = a.driplimit.KeyspaceImport(ctx, payload)
+	ksid := ""
+	if payload.Bundle.Keyspace != nil {
+		ksid = payload.Bundle.Keyspace.KSID
+	}
+	a.record(ctx, skid, "keyspaces.import", payload, err, ksid)
+	return result, err
+}
+
+func (a *Auditor) PlanGet(ctx context.Context, payload PlanGetPayload) (plan *Plan, err error) {
+	return a.driplimit.PlanGet(ctx, payload)
+}
+
+func (a *Auditor) PlanCreate(ctx context.Context, payload PlanCreatePayload) (plan *Plan, err error) {
+	skid := a.caller(ctx, payload)
+	plan, err = a.driplimit.PlanCreate(ctx, payload)
+	plid := ""
+	if plan != nil {
+		plid = plan.PLID
+	}
+	a.record(ctx, skid, "plans.create", payload, err, payload.KSID, plid)
+	return plan, err
+}
+
+func (a *Auditor) PlanList(ctx context.Context, payload PlanListPayload) (plist *PlanList, err error) {
+	return a.driplimit.PlanList(ctx, payload)
+}
+
+func (a *Auditor) PlanUpdate(ctx context.Context, payload PlanUpdatePayload) (plan *Plan, err error) {
+	skid := a.caller(ctx, payload)
+	plan, err = a.driplimit.PlanUpdate(ctx, payload)
+	a.record(ctx, skid, "plans.update", payload, err, payload.KSID, payload.PLID)
+	return plan, err
+}
+
+func (a *Auditor) PlanDelete(ctx context.Context, payload PlanDeletePayload) (err error) {
+	skid := a.caller(ctx, payload)
+	err = a.driplimit.PlanDelete(ctx, payload)
+	a.record(ctx, skid, "plans.delete", payload, err, payload.KSID, payload.PLID)
+	return err
+}
+
+func (a *Auditor) RoleGet(ctx context.Context, payload RoleGetPayload) (role *Role, err error) {
+	return a.driplimit.RoleGet(ctx, payload)
+}
+
+func (a *Auditor) RoleCreate(ctx context.Context, payload RoleCreatePayload) (role *Role, err error) {
+	skid := a.caller(ctx, payload)
+	role, err = a.driplimit.RoleCreate(ctx, payload)
+	rlid := ""
+	if role != nil {
+		rlid = role.RLID
+	}
+	a.record(ctx, skid, "roles.create", payload, err, rlid)
+	return role, err
+}
+
+func (a *Auditor) RoleList(ctx context.Context, payload RoleListPayload) (rlist *RoleList, err error) {
+	return a.driplimit.RoleList(ctx, payload)
+}
+
+func (a *Auditor) RoleUpdate(ctx context.Context, payload RoleUpdatePayload) (role *Role, err error) {
+	skid := a.caller(ctx, payload)
+	role, err = a.driplimit.RoleUpdate(ctx, payload)
+	a.record(ctx, skid, "roles.update", payload, err, payload.RLID)
+	return role, err
+}
+
+func (a *Auditor) RoleDelete(ctx context.Context, payload RoleDeletePayload) (err error) {
+	skid := a.caller(ctx, payload)
+	err = a.driplimit.RoleDelete(ctx, payload)
+	a.record(ctx, skid, "roles.delete", payload, err, payload.RLID)
+	return err
+}
+
+func (a *Auditor) ServiceKeyGet(ctx context.Context, payload ServiceKeyGetPayload) (sk *ServiceKey, err error) {
+	return a.driplimit.ServiceKeyGet(ctx, payload)
+}
+
+func (a *Auditor) ServiceKeyCreate(ctx context.Context, payload ServiceKeyCreatePayload) (sk *ServiceKey, err error) {
+	skid := a.caller(ctx, payload)
+	sk, err = a.driplimit.ServiceKeyCreate(ctx, payload)
+	target := ""
+	if sk != nil {
+		target = sk.SKID
+	}
+	a.record(ctx, skid, "serviceKeys.create", payload, err, target)
+	return sk, err
+}
+
+func (a *Auditor) ServiceKeyList(ctx context.Context, payload ServiceKeyListPayload) (sklist *ServiceKeyList, err error) {
+	return a.driplimit.ServiceKeyList(ctx, payload)
+}
+
+func (a *Auditor) ServiceKeyDelete(ctx context.Context, payload ServiceKeyDeletePayload) (err error) {
+	skid := a.caller(ctx, payload)
+	err = a.driplimit.ServiceKeyDelete(ctx, payload)
+	a.record(ctx, skid, "serviceKeys.delete", payload, err, payload.SKID)
+	return err
+}
+
+func (a *Auditor) ServiceKeySetToken(ctx context.Context, payload ServiceKeySetTokenPayload) (err error) {
+	skid := a.caller(ctx, payload)
+	err = a.driplimit.ServiceKeySetToken(ctx, payload)
+	a.record(ctx, skid, "serviceKeys.set_token", payload, err, payload.SKID)
+	return err
+}
+
+func (a *Auditor) ServiceKeyRotate(ctx context.Context, payload ServiceKeyRotatePayload) (sk *ServiceKey, err error) {
+	skid := a.caller(ctx, payload)
+	sk, err = a.driplimit.ServiceKeyRotate(ctx, payload)
+	a.record(ctx, skid, "serviceKeys.rotate", payload, err, payload.SKID)
+	return sk, err
+}
+
+func (a *Auditor) ServiceKeyPromote(ctx context.Context, payload ServiceKeyPromotePayload) (sk *ServiceKey, err error) {
+	skid := a.caller(ctx, payload)
+	sk, err = a.driplimit.ServiceKeyPromote(ctx, payload)
+	a.record(ctx, skid, "serviceKeys.promote", payload, err, payload.SKID)
+	return sk, err
+}
+
+func (a *Auditor) ServiceKeyRevokePrevious(ctx context.Context, payload ServiceKeyRevokePreviousPayload) (sk *ServiceKey, err error) {
+	skid := a.caller(ctx, payload)
+	sk, err = a.driplimit.ServiceKeyRevokePrevious(ctx, payload)
+	a.record(ctx, skid, "serviceKeys.revoke_previous", payload, err, payload.SKID)
+	return sk, err
+}
+
+func (a *Auditor) ServiceKeyUpdate(ctx context.Context, payload ServiceKeyUpdatePayload) (sk *ServiceKey, err error) {
+	skid := a.caller(ctx, payload)
+	sk, err = a.driplimit.ServiceKeyUpdate(ctx, payload)
+	a.record(ctx, skid, "serviceKeys.update", payload, err, payload.SKID)
+	return sk, err
+}
+
+func (a *Auditor) ServiceKeyCan(ctx context.Context, payload ServiceKeyCanPayload) (permission *Permission, err error) {
+	return a.driplimit.ServiceKeyCan(ctx, payload)
+}
+
+func (a *Auditor) AuditList(ctx context.Context, payload AuditListPayload) (alist *AuditList, err error) {
+	return a.driplimit.AuditList(ctx, payload)
+}
@@ -2,22 +2,57 @@ package driplimit
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 )
 
-// PolicyAction represents an action that can be performed. eg. read, write
+// PolicyAction represents an action that can be performed. eg. check, read, create
 type PolicyAction string
 
 const (
-	Read  PolicyAction = "read"
-	Write PolicyAction = "write"
+	Check          PolicyAction = "check"
+	Read           PolicyAction = "read"
+	Create         PolicyAction = "create"
+	Update         PolicyAction = "update"
+	Delete         PolicyAction = "delete"
+	ManagePolicies PolicyAction = "manage_policies"
 )
 
-// Policy gives the read and write permissions.
+// Policy gives the permissions of each action.
 type Policy struct {
-	Read  bool `json:"read" description:"Read permission"`
-	Write bool `json:"write" description:"Write permission"`
+	Check          bool `json:"check" description:"Check permission (keys.check), given by the read permission when omitted"`
+	Read           bool `json:"read" description:"Read permission (get, list and stats)"`
+	Create         bool `json:"create" description:"Create permission"`
+	Update         bool `json:"update" description:"Update permission"`
+	Delete         bool `json:"delete" description:"Delete permission"`
+	ManagePolicies bool `json:"manage_policies" description:"Permission to manage the service keys policies"`
+	Deny           bool `json:"deny" description:"Deny the flagged actions instead of allowing them. Deny policies override allow policies"`
+}
+
+// UnmarshalJSON unmarshals a policy. Legacy policies are expanded as their stored rows were
+// migrated: the read permission also allows checking keys unless the check permission is
+// given, and the write permission is expanded to the create, update and delete permissions.
+func (k *Policy) UnmarshalJSON(data []byte) error {
+	type policy Policy
+	legacy := struct {
+		*policy
+		Check *bool `json:"check"`
+		Write bool  `json:"write"`
+	}{policy: (*policy)(k)}
+	err := json.Unmarshal(data, &legacy)
+	if err != nil {
+		return err
+	}
+	k.Check = k.Read
+	if legacy.Check != nil {
+		k.Check = *legacy.Check
+	}
+	if legacy.Write {
+		k.Create, k.Update, k.Delete = true, true, true
+	}
+	return nil
 }
 
 // all is a wildcard for all ids. This can be found in items policies
@@ -26,37 +61,233 @@ const all = "*"
 // Can checks if the policy allows the action.
 func (k Policy) Can(action PolicyAction) bool {
 	switch action {
-	case "read":
+	case Check:
+		return k.Check
+	case Read:
 		return k.Read
-	case "write":
-		return k.Write
+	case Create:
+		return k.Create
+	case Update:
+		return k.Update
+	case Delete:
+		return k.Delete
+	case ManagePolicies:
+		return k.ManagePolicies
 	default:
 		return false
 	}
 }
 
-// Policies is a map of policies.
+// Policies is a map of policies. Map keys are either item ids, the "*" wildcard or glob
+// patterns over the items ids and names (eg. "prod-*").
 type Policies map[string]Policy
 
-// Can checks if the action can be performed on item id.
-func (policies Policies) Can(action PolicyAction, id string) bool {
-	policy, found := policies[all]
-	if found {
-		if policy.Can(action) {
+// Can checks if the action can be performed on the item identified by ids (eg. the keyspace
+// id and name). Policies are evaluated as follows, regardless of their order:
+//  1. the policies matching the item are selected. A policy matches when its key equals one of
+//     the ids or, for patterns, when it globs one of them (* matches any sequence of characters
+//     and ? a single character). The "*" wildcard matches every item.
+//  2. the action is denied if a matching deny policy flags it.
+//  3. the action is allowed if a matching allow policy flags it.
+//  4. the action is denied otherwise.
+func (policies Policies) Can(action PolicyAction, ids ...string) bool {
+	allow, deny := policies.decide(action, ids)
+	return allow != "" && deny == ""
+}
+
+// decide returns the keys of the matching allow and deny policies flagging the action, empty
+// when there is none. When several policies match, the most specific key is returned.
+func (policies Policies) decide(action PolicyAction, ids []string) (allow, deny string) {
+	for key, policy := range policies {
+		if !policy.Can(action) || !policyMatches(key, ids) {
+			continue
+		}
+		if policy.Deny {
+			deny = mostSpecificPolicy(deny, key)
+		} else {
+			allow = mostSpecificPolicy(allow, key)
+		}
+	}
+	return allow, deny
+}
+
+// PolicyRule is the kind of rule an authorization decision is based on.
+type PolicyRule string
+
+const (
+	RuleAdmin    PolicyRule = "admin"
+	RuleExpired  PolicyRule = "expired"
+	RulePolicy   PolicyRule = "policy"
+	RulePattern  PolicyRule = "pattern"
+	RuleWildcard PolicyRule = "wildcard"
+	RuleNone     PolicyRule = "none"
+)
+
+// policyRule returns the rule of the policy key.
+func policyRule(key string) PolicyRule {
+	switch {
+	case key == all:
+		return RuleWildcard
+	case IsPolicyPattern(key):
+		return RulePattern
+	default:
+		return RulePolicy
+	}
+}
+
+// policyRuleRanks orders the policy rules from the most to the least specific.
+var policyRuleRanks = map[PolicyRule]int{RulePolicy: 0, RulePattern: 1, RuleWildcard: 2}
+
+// mostSpecificPolicy returns the most specific of the two policy keys: explicit ids come
+// first, then patterns and finally the wildcard. Ties are broken by key order so that
+// decisions are reported consistently.
+func mostSpecificPolicy(current, key string) string {
+	if current == "" {
+		return key
+	}
+	currentRank, keyRank := policyRuleRanks[policyRule(current)], policyRuleRanks[policyRule(key)]
+	if keyRank < currentRank || (keyRank == currentRank && key < current) {
+		return key
+	}
+	return current
+}
+
+// PolicyDecision explains an authorization decision: the rule it is based on and, for
+// policies, the key of the deciding policy and the role holding it.
+type PolicyDecision struct {
+	Allowed bool       `json:"allowed"`
+	Rule    PolicyRule `json:"rule"`
+	Policy  string     `json:"policy,omitempty"`
+	Deny    bool       `json:"deny"`
+	RLID    string     `json:"rlid,omitempty"`
+}
+
+// Can checks if the service key is allowed to perform the action on the item identified by ids.
+// The service key policies and the policies of its roles are evaluated as a single set of
+// policies (see Policies.Can), a deny policy of a role overrides the service key own policies.
+func (sk *ServiceKey) Can(action PolicyAction, ids ...string) bool {
+	return sk.decide(action, ids).Allowed
+}
+
+// Explain returns the decision the Authorizer makes when the service key performs the
+// action on the item identified by ids. Expired service keys are always denied and admin
+// service keys always allowed, other service keys are evaluated as in ServiceKey.Can.
+func (sk *ServiceKey) Explain(action PolicyAction, ids ...string) PolicyDecision {
+	switch {
+	case sk.Expired():
+		return PolicyDecision{Rule: RuleExpired}
+	case sk.Admin:
+		return PolicyDecision{Allowed: true, Rule: RuleAdmin}
+	}
+	return sk.decide(action, ids)
+}
+
+// decide evaluates the service key and roles policies. The service key own policies are
+// reported first when both match.
+func (sk *ServiceKey) decide(action PolicyAction, ids []string) PolicyDecision {
+	allow, deny := sk.KeyspacesPolicies.decide(action, ids)
+	allowed := PolicyDecision{Allowed: true, Policy: allow}
+	denied := PolicyDecision{Deny: true, Policy: deny}
+	for _, role := range sk.Roles {
+		roleAllow, roleDeny := role.KeyspacesPolicies.decide(action, ids)
+		if allowed.Policy == "" && roleAllow != "" {
+			allowed = PolicyDecision{Allowed: true, Policy: roleAllow, RLID: role.RLID}
+		}
+		if denied.Policy == "" && roleDeny != "" {
+			denied = PolicyDecision{Deny: true, Policy: roleDeny, RLID: role.RLID}
+		}
+	}
+	switch {
+	case denied.Policy != "":
+		denied.Rule = policyRule(denied.Policy)
+		return denied
+	case allowed.Policy != "":
+		allowed.Rule = policyRule(allowed.Policy)
+		return allowed
+	}
+	return PolicyDecision{Rule: RuleNone}
+}
+
+// HasPatterns returns true if the service key or its roles policies contain patterns.
+func (sk *ServiceKey) HasPatterns() bool {
+	if sk.KeyspacesPolicies.HasPatterns() {
+		return true
+	}
+	for _, role := range sk.Roles {
+		if role.KeyspacesPolicies.HasPatterns() {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPatterns returns true if the policies contain patterns other than the "*" wildcard.
+// Such policies need the item name to be evaluated.
+func (policies Policies) HasPatterns() bool {
+	for key := range policies {
+		if key != all && IsPolicyPattern(key) {
 			return true
 		}
 	}
+	return false
+}
 
-	policy, found = policies[id]
-	if !found {
-		return false
+// Validate validates the policies keys. Patterns only support the * and ? wildcards.
+func (policies Policies) Validate() error {
+	for key := range policies {
+		if key == "" || (IsPolicyPattern(key) && strings.ContainsAny(key, "[]")) {
+			return ErrInvalidPayload
+		}
+	}
+	return nil
+}
+
+// IsPolicyPattern returns true if the policy key is a pattern instead of an item id.
+func IsPolicyPattern(key string) bool {
+	return strings.ContainsAny(key, "*?")
+}
+
+// policyMatches returns true if the policy key matches one of the ids.
+func policyMatches(key string, ids []string) bool {
+	for _, id := range ids {
+		if key == id || (IsPolicyPattern(key) && glob([]rune(key), []rune(id))) {
+			return true
+		}
+	}
+	return false
+}
+
+// glob matches s against the pattern with the same semantics as the sqlite GLOB operator
+// restricted to the * and ? wildcards, so that keyspaces can also be filtered in the store.
+func glob(pattern, s []rune) bool {
+	px, sx := 0, 0
+	starPx, starSx := -1, 0
+	for sx < len(s) {
+		switch {
+		case px < len(pattern) && pattern[px] == '*':
+			starPx, starSx = px, sx
+			px++
+		case px < len(pattern) && (pattern[px] == '?' || pattern[px] == s[sx]):
+			px++
+			sx++
+		case starPx >= 0:
+			// backtrack: let the last star consume one more character
+			starSx++
+			px, sx = starPx+1, starSx
+		default:
+			return false
+		}
 	}
-	return policy.Can(action)
+	for px < len(pattern) && pattern[px] == '*' {
+		px++
+	}
+	return px == len(pattern)
 }
 
 // Authorizer is an authorization wrapper. It implements the Service interface.
 type Authorizer struct {
 	driplimit Service
+	usage     *UsageTracker
 }
 
 // NewAuthorizer wraps a Driplimit ServiceWithToken with an authorizer.
@@ -66,16 +297,50 @@ func NewAuthorizer(driplimit Service) *Authorizer {
 	}
 }
 
-// caller gets the service key from the context.
+// WithUsageTracker tracks the last use of the service keys authenticated by the authorizer.
+func (a *Authorizer) WithUsageTracker(tracker *UsageTracker) *Authorizer {
+	a.usage = tracker
+	return a
+}
+
+// caller gets the service key from the context. It is resolved once per call and kept in
+// the Caller of the context, if any.
 func (a *Authorizer) caller(ctx context.Context, payload Payload) (sk *ServiceKey, err error) {
-	sk, err = a.driplimit.ServiceKeyGet(ctx, ServiceKeyGetPayload{Token: payload.ServiceToken()})
+	caller := callerFrom(ctx)
+	sk = caller.get(payload.ServiceToken())
+	if sk == nil {
+		sk, err = a.driplimit.ServiceKeyGet(ctx, ServiceKeyGetPayload{Token: payload.ServiceToken()})
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return nil, ErrUnauthorized
+			}
+			return nil, fmt.Errorf("failed to get service key: %w", err)
+		}
+		if a.usage != nil && !sk.Expired() {
+			a.usage.Track(ctx, sk.SKID)
+		}
+		caller.set(payload.ServiceToken(), sk)
+	}
+	if sk.Expired() {
+		return nil, ErrUnauthorized
+	}
+	return sk, nil
+}
+
+// can checks if the service key policies allow the action on the keyspace ksid. The keyspace
+// is only looked up when the policies contain patterns that may match its name.
+func (a *Authorizer) can(ctx context.Context, sk *ServiceKey, action PolicyAction, ksid string) bool {
+	if !sk.HasPatterns() {
+		return sk.Can(action, ksid)
+	}
+	ks, err := a.driplimit.KeyspaceGet(ctx, KeyspaceGetPayload{KSID: ksid})
 	if err != nil {
 		if errors.Is(err, ErrNotFound) {
-			return nil, ErrUnauthorized
+			return sk.Can(action, ksid)
 		}
-		return nil, fmt.Errorf("failed to get service key: %w", err)
+		return false
 	}
-	return sk, nil
+	return sk.Can(action, ks.KSID, ks.Name)
 }
 
 func (a *Authorizer) KeyCheck(ctx context.Context, payload KeysCheckPayload) (key *Key, err error) {
@@ -83,18 +348,31 @@ func (a *Authorizer) KeyCheck(ctx context.Context, payload KeysCheckPayload) (ke
 	if err != nil {
 		return nil, err
 	}
-	if sk.Admin || sk.KeyspacesPolicies.Can(Read, payload.KSID) {
+	if sk.Admin || a.can(ctx, sk, Check, payload.KSID) {
 		return a.driplimit.KeyCheck(ctx, payload)
 	}
 	return nil, ErrUnauthorized
 }
 
+func (a *Authorizer) KeyCheckBatch(ctx context.Context, payload KeysCheckBatchPayload) (batch *KeysCheckBatch, err error) {
+	sk, err := a.caller(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	return checkBatchSubset(ctx, payload, func(check KeysCheckPayload) error {
+		if sk.Admin || a.can(ctx, sk, Check, check.KSID) {
+			return nil
+		}
+		return ErrUnauthorized
+	}, a.driplimit.KeyCheckBatch)
+}
+
 func (a *Authorizer) KeyCreate(ctx context.Context, payload KeyCreatePayload) (key *Key, err error) {
 	sk, err := a.caller(ctx, payload)
 	if err != nil {
 		return nil, err
 	}
-	if sk.Admin || sk.KeyspacesPolicies.Can(Write, payload.KSID) {
+	if sk.Admin || a.can(ctx, sk, Create, payload.KSID) {
 		return a.driplimit.KeyCreate(ctx, payload)
 	}
 	return nil, ErrUnauthorized
@@ -105,7 +383,7 @@ func (a *Authorizer) KeyGet(ctx context.Context, payload KeyGetPayload) (key *Ke
 	if err != nil {
 		return nil, err
 	}
-	if sk.Admin || sk.KeyspacesPolicies.Can(Read, payload.KSID) {
+	if sk.Admin || a.can(ctx, sk, Read, payload.KSID) {
 		return a.driplimit.KeyGet(ctx, payload)
 	}
 	return nil, ErrUnauthorized
@@ -116,7 +394,7 @@ func (a *Authorizer) KeyList(ctx context.Context, payload KeyListPayload) (klist
 	if err != nil {
 		return nil, err
 	}
-	if sk.Admin || sk.KeyspacesPolicies.Can(Read, payload.KSID) {
+	if sk.Admin || a.can(ctx, sk, Read, payload.KSID) {
 		return a.driplimit.KeyList(ctx, payload)
 	}
 	return nil, ErrUnauthorized
@@ -127,18 +405,51 @@ func (a *Authorizer) KeyDelete(ctx context.Context, payload KeyDeletePayload) (e
 	if err != nil {
 		return err
 	}
-	if sk.Admin || sk.KeyspacesPolicies.Can("write", payload.KSID) {
+	if sk.Admin || a.can(ctx, sk, Delete, payload.KSID) {
 		return a.driplimit.KeyDelete(ctx, payload)
 	}
 	return ErrUnauthorized
 }
 
+func (a *Authorizer) KeyDeleteMany(ctx context.Context, payload KeyDeleteManyPayload) (result *KeyDeleteManyResult, err error) {
+	sk, err := a.caller(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if sk.Admin || a.can(ctx, sk, Delete, payload.KSID) {
+		return a.driplimit.KeyDeleteMany(ctx, payload)
+	}
+	return nil, ErrUnauthorized
+}
+
+func (a *Authorizer) KeyReset(ctx context.Context, payload KeyResetPayload) (key *Key, err error) {
+	sk, err := a.caller(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if sk.Admin || a.can(ctx, sk, Update, payload.KSID) {
+		return a.driplimit.KeyReset(ctx, payload)
+	}
+	return nil, ErrUnauthorized
+}
+
+func (a *Authorizer) KeyMove(ctx context.Context, payload KeyMovePayload) (key *Key, err error) {
+	sk, err := a.caller(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if sk.Admin || (a.can(ctx, sk, Delete, payload.KSID) && a.can(ctx, sk, Create, payload.TargetKSID)) {
+		return a.driplimit.KeyMove(ctx, payload)
+	}
+	return nil, ErrUnauthorized
+}
+
 func (a *Authorizer) KeyspaceGet(ctx context.Context, payload KeyspaceGetPayload) (keyspace *Keyspace, err error) {
 	sk, err := a.caller(ctx, payload)
 	if err != nil {
 		return nil, err
 	}
-	if sk.Admin || sk.KeyspacesPolicies.Can(Read, payload.KSID) {
+	if sk.Admin || a.can(ctx, sk, Read, payload.KSID) {
 		return a.driplimit.KeyspaceGet(ctx, payload)
 	}
 	return nil, ErrUnauthorized
@@ -160,14 +471,25 @@ func (a *Authorizer) KeyspaceList(ctx context.Context, payload KeyspaceListPaylo
 	if err != nil {
 		return nil, err
 	}
-	if sk.Admin || sk.KeyspacesPolicies.Can(Read, all) {
+	if sk.Admin {
 		return a.driplimit.KeyspaceList(ctx, payload)
 	}
-	// if the service key is not allowed to read all keyspaces, then filter by SKID
+	// the store evaluates the service key policies, including patterns and deny policies
 	payload.FilterBySKIDKeyspacesPolicies = sk.SKID
 	return a.driplimit.KeyspaceList(ctx, payload)
 }
 
+func (a *Authorizer) KeyspaceUpdate(ctx context.Context, payload KeyspaceUpdatePayload) (keyspace *Keyspace, err error) {
+	sk, err := a.caller(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if sk.Admin || a.can(ctx, sk, Update, payload.KSID) {
+		return a.driplimit.KeyspaceUpdate(ctx, payload)
+	}
+	return nil, ErrUnauthorized
+}
+
 func (a *Authorizer) KeyspaceDelete(ctx context.Context, payload KeyspaceDeletePayload) (err error) {
 	sk, err := a.caller(ctx, payload)
 	if err != nil {
@@ -179,11 +501,169 @@ func (a *Authorizer) KeyspaceDelete(ctx context.Context, payload KeyspaceDeleteP
 	return ErrUnauthorized
 }
 
+func (a *Authorizer) KeyspaceClone(ctx context.Context, payload KeyspaceClonePayload) (keyspace *Keyspace, err error) {
+	sk, err := a.caller(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if sk.Admin {
+		return a.driplimit.KeyspaceClone(ctx, payload)
+	}
+	return nil, ErrUnauthorized
+}
+
+func (a *Authorizer) KeyspaceStats(ctx context.Context, payload KeyspaceStatsPayload) (stats *KeyspaceStats, err error) {
+	sk, err := a.caller(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if sk.Admin || a.can(ctx, sk, Read, payload.KSID) {
+		return a.driplimit.KeyspaceStats(ctx, payload)
+	}
+	return nil, ErrUnauthorized
+}
+
+func (a *Authorizer) KeyspaceExport(ctx context.Context, payload KeyspaceExportPayload) (bundle *KeyspaceBundle, err error) {
+	sk, err := a.caller(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if sk.Admin {
+		return a.driplimit.KeyspaceExport(ctx, payload)
+	}
+	return nil, ErrUnauthorized
+}
+
+func (a *Authorizer) KeyspaceImport(ctx context.Context, payload KeyspaceImportPayload) (result *KeyspaceImportResult, err error) {
+	sk, err := a.caller(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if sk.Admin {
+		return a.driplimit.KeyspaceImport(ctx, payload)
+	}
+	return nil, ErrUnauthorized
+}
+
+func (a *Authorizer) PlanGet(ctx context.Context, payload PlanGetPayload) (plan *Plan, err error) {
+	sk, err := a.caller(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if sk.Admin || a.can(ctx, sk, Read, payload.KSID) {
+		return a.driplimit.PlanGet(ctx, payload)
+	}
+	return nil, ErrUnauthorized
+}
+
+func (a *Authorizer) PlanCreate(ctx context.Context, payload PlanCreatePayload) (plan *Plan, err error) {
+	sk, err := a.caller(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if sk.Admin || a.can(ctx, sk, Create, payload.KSID) {
+		return a.driplimit.PlanCreate(ctx, payload)
+	}
+	return nil, ErrUnauthorized
+}
+
+func (a *Authorizer) PlanList(ctx context.Context, payload PlanListPayload) (plist *PlanList, err error) {
+	sk, err := a.caller(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if sk.Admin || a.can(ctx, sk, Read, payload.KSID) {
+		return a.driplimit.PlanList(ctx, payload)
+	}
+	return nil, ErrUnauthorized
+}
+
+func (a *Authorizer) PlanUpdate(ctx context.Context, payload PlanUpdatePayload) (plan *Plan, err error) {
+	sk, err := a.caller(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if sk.Admin || a.can(ctx, sk, Update, payload.KSID) {
+		return a.driplimit.PlanUpdate(ctx, payload)
+	}
+	return nil, ErrUnauthorized
+}
+
+func (a *Authorizer) PlanDelete(ctx context.Context, payload PlanDeletePayload) (err error) {
+	sk, err := a.caller(ctx, payload)
+	if err != nil {
+		return err
+	}
+	if sk.Admin || a.can(ctx, sk, Delete, payload.KSID) {
+		return a.driplimit.PlanDelete(ctx, payload)
+	}
+	return ErrUnauthorized
+}
+
+func (a *Authorizer) RoleGet(ctx context.Context, payload RoleGetPayload) (role *Role, err error) {
+	sk, err := a.caller(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if sk.Admin {
+		return a.driplimit.RoleGet(ctx, payload)
+	}
+	return nil, ErrUnauthorized
+}
+
+func (a *Authorizer) RoleCreate(ctx context.Context, payload RoleCreatePayload) (role *Role, err error) {
+	sk, err := a.caller(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if sk.Admin {
+		return a.driplimit.RoleCreate(ctx, payload)
+	}
+	return nil, ErrUnauthorized
+}
+
+func (a *Authorizer) RoleList(ctx context.Context, payload RoleListPayload) (rlist *RoleList, err error) {
+	sk, err := a.caller(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if sk.Admin {
+		return a.driplimit.RoleList(ctx, payload)
+	}
+	return nil, ErrUnauthorized
+}
+
+func (a *Authorizer) RoleUpdate(ctx context.Context, payload RoleUpdatePayload) (role *Role, err error) {
+	sk, err := a.caller(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if sk.Admin {
+		return a.driplimit.RoleUpdate(ctx, payload)
+	}
+	return nil, ErrUnauthorized
+}
+
+func (a *Authorizer) RoleDelete(ctx context.Context, payload RoleDeletePayload) (err error) {
+	sk, err := a.caller(ctx, payload)
+	if err != nil {
+		return err
+	}
+	if sk.Admin {
+		return a.driplimit.RoleDelete(ctx, payload)
+	}
+	return ErrUnauthorized
+}
+
 func (a *Authorizer) ServiceKeyGet(ctx context.Context, payload ServiceKeyGetPayload) (sk *ServiceKey, err error) {
 	sk, err = a.caller(ctx, payload)
 	if err != nil {
 		return nil, err
 	}
+	// the caller is the requested service key
+	if payload.SKID == "" && payload.Token == payload.ServiceToken() {
+		return sk, nil
+	}
 	if sk.Admin {
 		return a.driplimit.ServiceKeyGet(ctx, payload)
 	}
@@ -248,3 +728,108 @@ func (a *Authorizer) ServiceKeySetToken(ctx context.Context, payload ServiceKeyS
 
 	return ErrUnauthorized
 }
+
+// ServiceKeyRotate is allowed for admins and for the service key rotating its own token.
+func (a *Authorizer) ServiceKeyRotate(ctx context.Context, payload ServiceKeyRotatePayload) (sk *ServiceKey, err error) {
+	sk, err = a.caller(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if sk.Admin || sk.SKID == payload.SKID {
+		return a.driplimit.ServiceKeyRotate(ctx, payload)
+	}
+	return nil, ErrUnauthorized
+}
+
+// ServiceKeyPromote is allowed for admins and for the service key rotating its own token.
+func (a *Authorizer) ServiceKeyPromote(ctx context.Context, payload ServiceKeyPromotePayload) (sk *ServiceKey, err error) {
+	sk, err = a.caller(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if sk.Admin || sk.SKID == payload.SKID {
+		return a.driplimit.ServiceKeyPromote(ctx, payload)
+	}
+	return nil, ErrUnauthorized
+}
+
+// ServiceKeyRevokePrevious is allowed for admins and for the service key rotating its own token.
+func (a *Authorizer) ServiceKeyRevokePrevious(ctx context.Context, payload ServiceKeyRevokePreviousPayload) (sk *ServiceKey, err error) {
+	sk, err = a.caller(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if sk.Admin || sk.SKID == payload.SKID {
+		return a.driplimit.ServiceKeyRevokePrevious(ctx, payload)
+	}
+	return nil, ErrUnauthorized
+}
+
+func (a *Authorizer) ServiceKeyUpdate(ctx context.Context, payload ServiceKeyUpdatePayload) (sk *ServiceKey, err error) {
+	sk, err = a.caller(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if sk.Admin {
+		return a.driplimit.ServiceKeyUpdate(ctx, payload)
+	}
+	// non admin service keys can only change the policies of the keyspaces they manage, on
+	// other service keys so that they cannot grant themselves more actions
+	if payload.SKID == sk.SKID || payload.Description != nil || payload.Admin != nil || payload.ExpiresAt != nil || payload.Roles != nil || payload.ClientIdentity != nil || payload.AllowedCIDRs != nil || payload.APIRatelimit != nil || payload.ChecksRatelimit != nil || payload.RotateSigningSecret || payload.KeyspacesPolicies == nil {
+		return nil, ErrUnauthorized
+	}
+	target, err := a.driplimit.ServiceKeyGet(ctx, ServiceKeyGetPayload{SKID: payload.SKID})
+	if err != nil {
+		return nil, err
+	}
+	if target.Admin {
+		return nil, ErrUnauthorized
+	}
+	policies := make(Policies, len(payload.KeyspacesPolicies))
+	for ksid, policy := range payload.KeyspacesPolicies {
+		if current, found := target.KeyspacesPolicies[ksid]; (!found || current != policy) && !a.canManage(ctx, sk, ksid) {
+			return nil, ErrUnauthorized
+		}
+		policies[ksid] = policy
+	}
+	// policies of keyspaces the caller does not manage are kept as is
+	for ksid, policy := range target.KeyspacesPolicies {
+		if _, found := policies[ksid]; !found && !a.canManage(ctx, sk, ksid) {
+			policies[ksid] = policy
+		}
+	}
+	payload.KeyspacesPolicies = policies
+	return a.driplimit.ServiceKeyUpdate(ctx, payload)
+}
+
+// ServiceKeyCan is allowed to admins and to service keys simulating their own actions.
+func (a *Authorizer) ServiceKeyCan(ctx context.Context, payload ServiceKeyCanPayload) (permission *Permission, err error) {
+	sk, err := a.caller(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if sk.Admin || sk.SKID == payload.SKID {
+		return a.driplimit.ServiceKeyCan(ctx, payload)
+	}
+	return nil, ErrUnauthorized
+}
+
+func (a *Authorizer) AuditList(ctx context.Context, payload AuditListPayload) (alist *AuditList, err error) {
+	sk, err := a.caller(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if sk.Admin {
+		return a.driplimit.AuditList(ctx, payload)
+	}
+	return nil, ErrUnauthorized
+}
+
+// canManage checks if the service key can manage the policies of the keyspace ksid. Only
+// admins can manage pattern policies as they may cover keyspaces the caller does not manage.
+func (a *Authorizer) canManage(ctx context.Context, sk *ServiceKey, ksid string) bool {
+	if IsPolicyPattern(ksid) {
+		return false
+	}
+	return a.can(ctx, sk, ManagePolicies, ksid)
+}
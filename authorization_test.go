@@ -0,0 +1,164 @@
+package driplimit_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/i4n-co/driplimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyLegacyWrite(t *testing.T) {
+	policy := driplimit.Policy{}
+	err := json.Unmarshal([]byte(`{"read":true,"write":true}`), &policy)
+	assert.NoError(t, err)
+	assert.Equal(t, driplimit.Policy{
+		Check:  true,
+		Read:   true,
+		Create: true,
+		Update: true,
+		Delete: true,
+	}, policy)
+
+	// the read permission only allows checking keys when the check permission is omitted
+	policy = driplimit.Policy{}
+	err = json.Unmarshal([]byte(`{"read":true}`), &policy)
+	assert.NoError(t, err)
+	assert.Equal(t, driplimit.Policy{Check: true, Read: true}, policy)
+	policy = driplimit.Policy{}
+	err = json.Unmarshal([]byte(`{"check":false,"read":true}`), &policy)
+	assert.NoError(t, err)
+	assert.Equal(t, driplimit.Policy{Read: true}, policy)
+
+	policies := driplimit.Policies{"*": {Check: true}, "ks_abc": {Read: true}}
+	assert.True(t, policies.Can(driplimit.Check, "ks_abc"))
+	assert.True(t, policies.Can(driplimit.Read, "ks_abc"))
+	assert.False(t, policies.Can(driplimit.Read, "ks_xyz"))
+	assert.False(t, policies.Can(driplimit.ManagePolicies, "ks_abc"))
+}
+
+func TestPoliciesPatterns(t *testing.T) {
+	policies := driplimit.Policies{
+		"prod-*":    {Check: true, Read: true},
+		"prod-eu-?": {Read: true, Deny: true},
+		"ks_abc":    {Delete: true},
+	}
+	assert.True(t, policies.HasPatterns())
+
+	// patterns match ids or names
+	assert.True(t, policies.Can(driplimit.Read, "ks_xyz", "prod-us"))
+	assert.False(t, policies.Can(driplimit.Read, "ks_xyz", "staging"))
+	assert.True(t, policies.Can(driplimit.Delete, "ks_abc", "staging"))
+
+	// deny policies override the allow policies of the flagged actions only
+	assert.False(t, policies.Can(driplimit.Read, "ks_xyz", "prod-eu-1"))
+	assert.True(t, policies.Can(driplimit.Check, "ks_xyz", "prod-eu-1"))
+	assert.True(t, policies.Can(driplimit.Read, "ks_xyz", "prod-eu-12"))
+
+	assert.False(t, driplimit.Policies{"*": {Read: true}}.HasPatterns())
+	assert.ErrorIs(t, driplimit.Policies{"prod-[ab]*": {Read: true}}.Validate(), driplimit.ErrInvalidPayload)
+	assert.NoError(t, policies.Validate())
+}
+
+func TestServiceKeyRolesPolicies(t *testing.T) {
+	sk := driplimit.ServiceKey{
+		KeyspacesPolicies: driplimit.Policies{"ks_abc": {Read: true}},
+		Roles: []*driplimit.Role{
+			{KeyspacesPolicies: driplimit.Policies{"ks_abc": {Check: true}}},
+			{KeyspacesPolicies: driplimit.Policies{"prod-*": {Read: true, Deny: true}}},
+		},
+	}
+	// permissions are the union of the service key and roles policies
+	assert.True(t, sk.Can(driplimit.Check, "ks_abc"))
+	assert.True(t, sk.Can(driplimit.Read, "ks_abc"))
+	assert.False(t, sk.Can(driplimit.Delete, "ks_abc"))
+	assert.True(t, sk.HasPatterns())
+
+	// deny policies of roles override the service key own policies
+	assert.False(t, sk.Can(driplimit.Read, "ks_abc", "prod-eu"))
+	assert.True(t, sk.Can(driplimit.Check, "ks_abc", "prod-eu"))
+}
+
+func TestServiceKeyExplain(t *testing.T) {
+	sk := driplimit.ServiceKey{
+		KeyspacesPolicies: driplimit.Policies{
+			"*":      {Check: true},
+			"ks_abc": {Read: true},
+			"prod-*": {Read: true},
+		},
+		Roles: []*driplimit.Role{
+			{RLID: "rl_abc", KeyspacesPolicies: driplimit.Policies{"prod-eu": {Delete: true, Deny: true}}},
+		},
+	}
+	// the most specific matching policy decides
+	assert.Equal(t, driplimit.PolicyDecision{Allowed: true, Rule: driplimit.RulePolicy, Policy: "ks_abc"},
+		sk.Explain(driplimit.Read, "ks_abc", "prod-eu"))
+	assert.Equal(t, driplimit.PolicyDecision{Allowed: true, Rule: driplimit.RulePattern, Policy: "prod-*"},
+		sk.Explain(driplimit.Read, "ks_xyz", "prod-us"))
+	assert.Equal(t, driplimit.PolicyDecision{Allowed: true, Rule: driplimit.RuleWildcard, Policy: "*"},
+		sk.Explain(driplimit.Check, "ks_xyz"))
+	assert.Equal(t, driplimit.PolicyDecision{Rule: driplimit.RuleNone},
+		sk.Explain(driplimit.Update, "ks_abc"))
+	assert.Equal(t, driplimit.PolicyDecision{Rule: driplimit.RulePolicy, Policy: "prod-eu", Deny: true, RLID: "rl_abc"},
+		sk.Explain(driplimit.Delete, "ks_xyz", "prod-eu"))
+
+	// explanations never drift from the evaluation
+	for _, action := range []driplimit.PolicyAction{driplimit.Check, driplimit.Read, driplimit.Delete} {
+		assert.Equal(t, sk.Can(action, "ks_xyz", "prod-eu"), sk.Explain(action, "ks_xyz", "prod-eu").Allowed)
+	}
+
+	sk.Admin = true
+	assert.Equal(t, driplimit.PolicyDecision{Allowed: true, Rule: driplimit.RuleAdmin}, sk.Explain(driplimit.Update, "ks_abc"))
+}
+
+// serviceKeysStub is a service holding service keys by token.
+type serviceKeysStub struct {
+	driplimit.Service
+	keys    map[string]*driplimit.ServiceKey
+	updated *driplimit.ServiceKeyUpdatePayload
+}
+
+func (s *serviceKeysStub) ServiceKeyGet(ctx context.Context, payload driplimit.ServiceKeyGetPayload) (*driplimit.ServiceKey, error) {
+	for token, sk := range s.keys {
+		if payload.Token == token || payload.SKID == sk.SKID {
+			return sk, nil
+		}
+	}
+	return nil, driplimit.ErrNotFound
+}
+
+func (s *serviceKeysStub) ServiceKeyUpdate(ctx context.Context, payload driplimit.ServiceKeyUpdatePayload) (*driplimit.ServiceKey, error) {
+	s.updated = &payload
+	return &driplimit.ServiceKey{SKID: payload.SKID, KeyspacesPolicies: payload.KeyspacesPolicies}, nil
+}
+
+func TestAuthorizerServiceKeyUpdatePolicies(t *testing.T) {
+	ctx := context.Background()
+	stub := &serviceKeysStub{keys: map[string]*driplimit.ServiceKey{
+		"m4n4g3r": {SKID: "sk_manager", KeyspacesPolicies: driplimit.Policies{"ks_abc": {ManagePolicies: true}}},
+		"g4t3w4y": {SKID: "sk_gateway", KeyspacesPolicies: driplimit.Policies{"ks_abc": {Check: true}, "ks_xyz": {Read: true}}},
+	}}
+	authorizer := driplimit.NewAuthorizer(stub)
+
+	// service keys managing the policies of a keyspace can grant actions on it to other keys
+	update := driplimit.ServiceKeyUpdatePayload{
+		SKID:              "sk_gateway",
+		KeyspacesPolicies: driplimit.Policies{"ks_abc": {Check: true, Read: true}},
+	}
+	_, err := authorizer.ServiceKeyUpdate(ctx, *update.WithServiceToken("m4n4g3r"))
+	assert.NoError(t, err)
+	if assert.NotNil(t, stub.updated) {
+		assert.Equal(t, driplimit.Policies{"ks_abc": {Check: true, Read: true}, "ks_xyz": {Read: true}}, stub.updated.KeyspacesPolicies)
+	}
+
+	// but not to themselves
+	stub.updated = nil
+	update = driplimit.ServiceKeyUpdatePayload{
+		SKID:              "sk_manager",
+		KeyspacesPolicies: driplimit.Policies{"ks_abc": {Check: true, Read: true, Create: true, Update: true, Delete: true, ManagePolicies: true}},
+	}
+	_, err = authorizer.ServiceKeyUpdate(ctx, *update.WithServiceToken("m4n4g3r"))
+	assert.ErrorIs(t, err, driplimit.ErrUnauthorized)
+	assert.Nil(t, stub.updated)
+}
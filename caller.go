@@ -0,0 +1,48 @@
+package driplimit
+
+import (
+	"context"
+	"sync"
+)
+
+// CallerKey is the context key of the *Caller of a call, set by the API server so that the
+// service token of a request is resolved once by the Authorizer and reused by every layer.
+const CallerKey contextKey = "caller"
+
+// Caller holds the service key resolved from the service token of a call. The zero value
+// holds no service key yet.
+type Caller struct {
+	mu         sync.Mutex
+	token      string
+	serviceKey *ServiceKey
+}
+
+// get returns the service key resolved from token, nil if it was not resolved yet.
+func (c *Caller) get(token string) *ServiceKey {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.serviceKey == nil || c.token != token {
+		return nil
+	}
+	return c.serviceKey
+}
+
+// set records the service key resolved from token.
+func (c *Caller) set(token string, sk *ServiceKey) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+	c.serviceKey = sk
+}
+
+// callerFrom returns the caller of the context, nil if the context has none.
+func callerFrom(ctx context.Context) *Caller {
+	caller, _ := ctx.Value(CallerKey).(*Caller)
+	return caller
+}
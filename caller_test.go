@@ -0,0 +1,61 @@
+package driplimit_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/i4n-co/driplimit"
+	"github.com/stretchr/testify/assert"
+)
+
+// lookupCounter is a service counting the service key lookups.
+type lookupCounter struct {
+	driplimit.Service
+	lookups int
+	entries []driplimit.AuditEntry
+}
+
+func (l *lookupCounter) ServiceKeyGet(ctx context.Context, payload driplimit.ServiceKeyGetPayload) (*driplimit.ServiceKey, error) {
+	l.lookups++
+	return &driplimit.ServiceKey{SKID: "sk_admin", Admin: true}, nil
+}
+
+func (l *lookupCounter) KeyCreate(ctx context.Context, payload driplimit.KeyCreatePayload) (*driplimit.Key, error) {
+	return &driplimit.Key{KID: "k_abc", KSID: payload.KSID}, nil
+}
+
+func (l *lookupCounter) RecordAudit(ctx context.Context, entry driplimit.AuditEntry) error {
+	l.entries = append(l.entries, entry)
+	return nil
+}
+
+func TestCallerResolvedOnce(t *testing.T) {
+	counter := new(lookupCounter)
+	service := driplimit.NewAuditor(driplimit.NewAuthorizer(counter), counter, slog.Default())
+	ctx := context.WithValue(context.Background(), driplimit.CallerKey, new(driplimit.Caller))
+
+	// the API server resolves the caller first, then makes the call
+	current := driplimit.ServiceKeyGetPayload{Token: "t0k3n"}
+	sk, err := service.ServiceKeyGet(ctx, *current.WithServiceToken("t0k3n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "sk_admin", sk.SKID)
+	create := driplimit.KeyCreatePayload{KSID: "ks_abc"}
+	_, err = service.KeyCreate(ctx, *create.WithServiceToken("t0k3n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, counter.lookups)
+	if assert.Len(t, counter.entries, 1) {
+		assert.Equal(t, "sk_admin", counter.entries[0].SKID)
+	}
+
+	// other service tokens are resolved apart
+	_, err = service.KeyCreate(ctx, *create.WithServiceToken("other"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, counter.lookups)
+
+	// without caller, the service key is resolved by each layer
+	counter.lookups = 0
+	_, err = service.KeyCreate(context.Background(), *create.WithServiceToken("t0k3n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, counter.lookups)
+}
@@ -0,0 +1,35 @@
+package driplimit
+
+import (
+	"net/url"
+	"strings"
+)
+
+// ClientCertificateScheme prefixes the credentials of the clients authenticated by a verified
+// TLS client certificate.
+const ClientCertificateScheme = "X509-CLIENT-CERTIFICATE"
+
+// NewClientCertificateCredential returns the credential of a client authenticated by a
+// verified certificate with the given identities (subject common name and SANs), in order of
+// precedence. It is used as the service token of the payloads and resolved to the service
+// key whose client identity matches by the authoritative service.
+func NewClientCertificateCredential(identities []string) string {
+	return ClientCertificateScheme + " " + url.Values{"identity": identities}.Encode()
+}
+
+// IsClientCertificateCredential returns true if the service token is a client certificate credential.
+func IsClientCertificateCredential(token string) bool {
+	return strings.HasPrefix(token, ClientCertificateScheme+" ")
+}
+
+// ParseClientCertificateCredential returns the identities of the client certificate credential.
+func ParseClientCertificateCredential(token string) ([]string, error) {
+	if !IsClientCertificateCredential(token) {
+		return nil, ErrUnauthorized
+	}
+	values, err := url.ParseQuery(strings.TrimPrefix(token, ClientCertificateScheme+" "))
+	if err != nil || len(values["identity"]) == 0 {
+		return nil, ErrUnauthorized
+	}
+	return values["identity"], nil
+}
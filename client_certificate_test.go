@@ -0,0 +1,22 @@
+package driplimit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientCertificateCredential(t *testing.T) {
+	identities := []string{"billing", "billing.internal", "spiffe://internal/billing?region=eu&env=prod"}
+	credential := NewClientCertificateCredential(identities)
+	assert.True(t, IsClientCertificateCredential(credential))
+	parsed, err := ParseClientCertificateCredential(credential)
+	assert.NoError(t, err)
+	assert.Equal(t, identities, parsed)
+
+	// credentials without identity are rejected
+	_, err = ParseClientCertificateCredential(NewClientCertificateCredential(nil))
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	_, err = ParseClientCertificateCredential("Bearer t0k3n")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/i4n-co/driplimit"
+	"github.com/i4n-co/driplimit/pkg/authoritative"
+	"github.com/i4n-co/driplimit/pkg/config"
+)
+
+// exportKeyspace writes the bundle of the given keyspace to stdout.
+func exportKeyspace(ctx context.Context, cfg *config.Config, ksid string) error {
+	store, err := initStore(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize store: %w", err)
+	}
+
+	service := driplimit.NewServiceValidator(authoritative.NewService(store))
+	bundle, err := service.KeyspaceExport(ctx, driplimit.KeyspaceExportPayload{KSID: ksid})
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(bundle)
+}
+
+// importKeyspace imports the keyspace bundle found at path using the given strategy.
+func importKeyspace(ctx context.Context, cfg *config.Config, path string, strategy string) error {
+	store, err := initStore(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize store: %w", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read bundle: %w", err)
+	}
+	payload := driplimit.KeyspaceImportPayload{Strategy: driplimit.ImportStrategy(strategy)}
+	err = json.Unmarshal(data, &payload.Bundle)
+	if err != nil {
+		return fmt.Errorf("failed to decode bundle: %w", err)
+	}
+
+	service := driplimit.NewServiceValidator(authoritative.NewService(store))
+	result, err := service.KeyspaceImport(ctx, payload)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\nKeyspace imported successfully.\n\n")
+	fmt.Printf("Keyspace ID:            %s\n", result.Keyspace.KSID)
+	fmt.Printf("Keyspace name:          %s\n", result.Keyspace.Name)
+	fmt.Printf("Created items:          %d\n", result.Created)
+	fmt.Printf("Overwritten items:      %d\n", result.Overwritten)
+	fmt.Printf("Skipped items:          %d\n\n", result.Skipped)
+
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"os/signal"
 	"time"
 
+	"github.com/i4n-co/driplimit"
 	"github.com/i4n-co/driplimit/pkg/api"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -20,6 +21,9 @@ func main() {
 	initAdminFlag := flag.Bool("init-admin", false, "initialize an admin service key")
 	printDefaultsFlag := flag.Bool("print-defaults", false, "print the default configuration")
 	configPathFlag := flag.String("config", "", "path to the configuration file")
+	exportKeyspaceFlag := flag.String("export-keyspace", "", "export the keyspace with the given id as a json bundle to stdout")
+	importKeyspaceFlag := flag.String("import-keyspace", "", "import the keyspace json bundle at the given path")
+	importStrategyFlag := flag.String("import-strategy", string(driplimit.ImportFail), "how existing items are handled on import: skip, overwrite or fail")
 	flag.Parse()
 
 	cfg, err := loadConfig(ctx, *configPathFlag)
@@ -37,12 +41,30 @@ func main() {
 		os.Exit(0)
 	}
 
+	if *exportKeyspaceFlag != "" {
+		err = exportKeyspace(ctx, cfg, *exportKeyspaceFlag)
+		if err != nil {
+			cfg.Logger().Error("failed to export keyspace", "err", err.Error())
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if *importKeyspaceFlag != "" {
+		err = importKeyspace(ctx, cfg, *importKeyspaceFlag, *importStrategyFlag)
+		if err != nil {
+			cfg.Logger().Error("failed to import keyspace", "err", err.Error())
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	if *printDefaultsFlag {
 		cfg.PrintDefaults()
 		os.Exit(0)
 	}
 
-	service, err := initService(ctx, cfg)
+	service, usage, err := initService(ctx, cfg)
 	if err != nil {
 		cfg.Logger().Error("failed to initialize service", "err", err, "database_path", cfg.DatabasePath())
 		os.Exit(1)
@@ -65,4 +87,11 @@ func main() {
 		cfg.Logger().Error("failed to shutdown api", "err", err)
 		os.Exit(1)
 	}
+	if usage != nil {
+		err = usage.Close(ctx)
+		if err != nil {
+			cfg.Logger().Error("failed to record service keys usage", "err", err)
+			os.Exit(1)
+		}
+	}
 }
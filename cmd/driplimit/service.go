@@ -8,6 +8,7 @@ import (
 	"github.com/i4n-co/driplimit/pkg/authoritative"
 	"github.com/i4n-co/driplimit/pkg/client"
 	"github.com/i4n-co/driplimit/pkg/config"
+	"github.com/i4n-co/driplimit/pkg/jwt"
 	"github.com/i4n-co/driplimit/pkg/proxycache"
 	"github.com/i4n-co/driplimit/pkg/store"
 	"github.com/jmoiron/sqlx"
@@ -16,39 +17,58 @@ import (
 // initService initializes the driplimit service. If the configuration specifies
 // a proxy, it will use the proxy cache. If not, it will use the authoritative service.
 // If the configuration specifies async authoritative, it will wrap the authoritative
-// service with the proxy cache.
-func initService(ctx context.Context, cfg *config.Config) (driplimit.Service, error) {
+// service with the proxy cache. The returned usage tracker, nil in proxy mode, must be
+// closed on shutdown.
+func initService(ctx context.Context, cfg *config.Config) (driplimit.Service, *driplimit.UsageTracker, error) {
 	if cfg.IsProxy() {
 		return driplimit.NewServiceValidator(
 			proxycache.New(ctx, cfg,
 				client.New(cfg.UpstreamURL),
 			),
-		), nil
+		), nil, nil
 	}
 
 	store, err := initStore(ctx, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize store: %w", err)
+		return nil, nil, fmt.Errorf("failed to initialize store: %w", err)
 	}
 
 	if cfg.RootServiceKeyToken != "" {
 		err = store.InitRootServiceKeyToken(ctx, cfg.RootServiceKeyToken)
 		if err != nil {
-			return nil, fmt.Errorf("failed to init root service key token: %w", err)
+			return nil, nil, fmt.Errorf("failed to init root service key token: %w", err)
 		}
 
 		cfg.Logger().Info("root service token successfully set", "skid", "sk_root")
 	}
 
+	var jwtAuthentication *authoritative.JWTAuthentication
+	if cfg.JWTJWKS != "" {
+		verifier, err := jwt.NewVerifier(ctx, cfg.JWTJWKS, cfg.JWTIssuer, cfg.JWTAudience)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to initialize jwt verifier: %w", err)
+		}
+		jwtAuthentication = &authoritative.JWTAuthentication{
+			Verifier:      verifier,
+			SKIDClaim:     cfg.JWTSKIDClaim,
+			PoliciesClaim: cfg.JWTPoliciesClaim,
+		}
+	}
+
 	authoritative := authoritative.NewService(store)
-	authzservice := driplimit.NewAuthorizer(authoritative)
+	if jwtAuthentication != nil {
+		authoritative.WithJWTAuthentication(*jwtAuthentication)
+	}
+	usage := driplimit.NewUsageTracker(ctx, store, cfg.ServiceKeysUsageInterval, cfg.Logger().With("component", "usage"))
+	authzservice := driplimit.NewAuthorizer(authoritative).WithUsageTracker(usage)
+	auditor := driplimit.NewAuditor(authzservice, store, cfg.Logger())
 	if cfg.IsAsyncAuthoritative() {
 		return driplimit.NewServiceValidator(
-			proxycache.New(ctx, cfg, authzservice),
-		), nil
+			proxycache.New(ctx, cfg, auditor),
+		), usage, nil
 	}
 
-	return driplimit.NewServiceValidator(authzservice), nil
+	return driplimit.NewServiceValidator(auditor), usage, nil
 }
 
 // initStore initializes the database connection. If the configuration specifies
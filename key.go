@@ -1,7 +1,10 @@
 package driplimit
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/i4n-co/driplimit/pkg/generate"
@@ -11,13 +14,16 @@ import (
 
 // Key represents a driplimit key.
 type Key struct {
-	KID       string     `json:"kid"`
-	KSID      string     `json:"ksid"`
-	Token     string     `json:"token,omitempty"`
-	LastUsed  time.Time  `json:"last_used"`
-	ExpiresAt time.Time  `json:"expires_at"`
-	CreatedAt time.Time  `json:"created_at"`
-	Ratelimit *Ratelimit `json:"ratelimit,omitempty"`
+	KID         string       `json:"kid"`
+	KSID        string       `json:"ksid"`
+	PLID        string       `json:"plid,omitempty"`
+	Token       string       `json:"token,omitempty"`
+	LastUsed    time.Time    `json:"last_used"`
+	ExpiresAt   time.Time    `json:"expires_at"`
+	GracePeriod Milliseconds `json:"grace_period"`
+	InGrace     bool         `json:"in_grace,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	Ratelimit   *Ratelimit   `json:"ratelimit,omitempty"`
 }
 
 // MarshalJSON implements the json.Marshaler interface.
@@ -64,28 +70,7 @@ func (k *Key) UpdateRemaining() (updated bool) {
 	if !k.ConfiguredRatelimit() {
 		return false
 	}
-	if k.Ratelimit.State == nil {
-		k.Ratelimit.State = &RatelimitState{}
-	}
-	if k.Ratelimit.RefillInterval.Duration == 0 || k.Ratelimit.RefillRate == 0 {
-		return false
-	}
-	defer func() {
-		if updated {
-			k.Ratelimit.State.LastRefilled = now()
-		}
-	}()
-	sinceLastRefill := since(k.Ratelimit.State.LastRefilled)
-	refills := sinceLastRefill.Nanoseconds() / k.Ratelimit.RefillInterval.Nanoseconds()
-	refilled := refills * k.Ratelimit.RefillRate
-	remaining := k.Ratelimit.State.Remaining + refilled
-	updated = remaining != k.Ratelimit.State.Remaining
-	if remaining > k.Ratelimit.Limit {
-		k.Ratelimit.State.Remaining = k.Ratelimit.Limit
-		return updated
-	}
-	k.Ratelimit.State.Remaining = remaining
-	return updated
+	return k.Ratelimit.UpdateRemaining()
 }
 
 // ConfiguredRatelimit returns true if the rate limit is configured for the key.
@@ -93,29 +78,45 @@ func (k *Key) ConfiguredRatelimit() bool {
 	return k.Ratelimit.Configured()
 }
 
-// Expired returns true if the key is expired.
+// Expired returns true if the key is expired and its grace period is over.
 func (k *Key) Expired() bool {
 	if k.ExpiresAt.IsZero() {
 		return false
 	}
-	return since(k.ExpiresAt) > 0
+	return since(k.ExpiresAt) > k.GracePeriod.Duration
+}
+
+// InGracePeriod returns true if the key passed its expiration time but is
+// still usable because of its grace period.
+func (k *Key) InGracePeriod() bool {
+	if k.ExpiresAt.IsZero() || k.GracePeriod.Duration == 0 {
+		return false
+	}
+	elapsed := since(k.ExpiresAt)
+	return elapsed > 0 && elapsed <= k.GracePeriod.Duration
 }
 
 // KeyCreatePayload is the payload for creating a key.
 type KeyCreatePayload struct {
 	*payload
-	KSID      string           `json:"ksid" validate:"required" description:"The id of the keyspace to which the key belongs to"`
-	ExpiresIn Milliseconds     `json:"expires_in" description:"The duration in milliseconds after which the key expires"`
-	ExpiresAt time.Time        `json:"expires_at" description:"The time at which the key expires (expires_at takes precedence over expires_in)"`
-	Ratelimit RatelimitPayload `json:"ratelimit" validate:"required" description:"The rate limit configuration for the key"`
+	KSID        string           `json:"ksid" validate:"required" description:"The id of the keyspace to which the key belongs to"`
+	PLID        string           `json:"plid,omitempty" description:"The id of the plan providing the key rate limit (the key rate limit takes precedence)"`
+	ExpiresIn   Milliseconds     `json:"expires_in" description:"The duration in milliseconds after which the key expires"`
+	ExpiresAt   time.Time        `json:"expires_at" description:"The time at which the key expires (expires_at takes precedence over expires_in, the keyspace default applies when none is given)"`
+	GracePeriod *Milliseconds    `json:"grace_period,omitempty" description:"The duration in milliseconds during which the key is still accepted after its expiration (overrides the keyspace grace period, 0 disabling it)"`
+	Ratelimit   RatelimitPayload `json:"ratelimit" validate:"required" description:"The rate limit configuration for the key"`
 }
 
 // Validate validates the key create payload.
 func (k *KeyCreatePayload) Validate(validator *validator.Validate) error {
-	if k.ExpiresIn.Duration == 0 && k.ExpiresAt.IsZero() {
+	if k.ExpiresIn.Duration < 0 {
 		return ErrInvalidExpiration
 	}
 
+	if k.GracePeriod != nil && k.GracePeriod.Duration < 0 {
+		return ErrInvalidPayload
+	}
+
 	if k.ExpiresIn.Duration > 0 && k.ExpiresAt.IsZero() {
 		k.ExpiresAt = time.Now().Add(k.ExpiresIn.Duration)
 	}
@@ -151,6 +152,102 @@ func (k *KeysCheckPayload) WithServiceToken(token string) *KeysCheckPayload {
 	return k
 }
 
+// KeysCheckBatchPayload is the payload for checking multiple keys in a single request.
+type KeysCheckBatchPayload struct {
+	*payload
+
+	Checks []KeysCheckPayload `json:"checks" validate:"required,gte=1,lte=100" description:"The keys to check (up to 100)"`
+}
+
+// Validate validates the batch payload. Checks are validated individually so
+// that an invalid check does not fail the whole batch.
+func (k *KeysCheckBatchPayload) Validate(validator *validator.Validate) error {
+	return validator.Struct(k)
+}
+
+// WithServiceToken adds authentication infos to payload
+func (k *KeysCheckBatchPayload) WithServiceToken(token string) *KeysCheckBatchPayload {
+	k.payload = &payload{
+		serviceToken: token,
+	}
+	return k
+}
+
+// KeysCheckBatch is the result of a batch check. Results are in the same order as the checks.
+type KeysCheckBatch struct {
+	Results []KeysCheckBatchResult `json:"results"`
+}
+
+// KeysCheckBatchResult is the result of a single check of a batch.
+type KeysCheckBatchResult struct {
+	Key   *Key   `json:"key,omitempty"`
+	Code  int    `json:"code"`
+	Error string `json:"error,omitempty"`
+
+	err error
+}
+
+// NewKeysCheckBatchResult creates the result of a single check of a batch.
+// The code and the error message are derived from err.
+func NewKeysCheckBatchResult(key *Key, err error) KeysCheckBatchResult {
+	if err == nil {
+		return KeysCheckBatchResult{Key: key, Code: 200}
+	}
+	result := KeysCheckBatchResult{Code: HTTPCodeFromErr(err), err: err}
+	itemNotFound := ErrItemNotFound("")
+	switch {
+	case errors.As(err, &itemNotFound):
+		result.Error = itemNotFound.Error()
+	case result.Code == 500:
+		result.Error = "internal server error"
+	default:
+		result.Error = ErrFromHTTPCode(result.Code).Error()
+	}
+	return result
+}
+
+// Err returns the error of the check, nil if the check succeeded.
+func (r KeysCheckBatchResult) Err() error {
+	if r.err != nil || r.Code == 200 {
+		return r.err
+	}
+	if err := ErrFromHTTPCode(r.Code); err != nil {
+		return err
+	}
+	return errors.New(r.Error)
+}
+
+// checkBatchSubset calls next with the checks that are not rejected, then merges
+// its results with the rejections while preserving the order of the checks.
+func checkBatchSubset(ctx context.Context, payload KeysCheckBatchPayload, reject func(check KeysCheckPayload) error, next func(ctx context.Context, payload KeysCheckBatchPayload) (*KeysCheckBatch, error)) (*KeysCheckBatch, error) {
+	batch := &KeysCheckBatch{Results: make([]KeysCheckBatchResult, len(payload.Checks))}
+	accepted := make([]int, 0, len(payload.Checks))
+	subset := KeysCheckBatchPayload{payload: payload.payload}
+	for i, check := range payload.Checks {
+		if err := reject(check); err != nil {
+			batch.Results[i] = NewKeysCheckBatchResult(nil, err)
+			continue
+		}
+		accepted = append(accepted, i)
+		subset.Checks = append(subset.Checks, check)
+	}
+	if len(accepted) == 0 {
+		return batch, nil
+	}
+
+	results, err := next(ctx, subset)
+	if err != nil {
+		return nil, err
+	}
+	if len(results.Results) != len(accepted) {
+		return nil, fmt.Errorf("expected %d batch results, got %d", len(accepted), len(results.Results))
+	}
+	for j, i := range accepted {
+		batch.Results[i] = results.Results[j]
+	}
+	return batch, nil
+}
+
 // KeyGetPayload is the payload for getting a key.
 type KeyGetPayload struct {
 	*payload
@@ -242,3 +339,81 @@ func (k *KeyDeletePayload) WithServiceToken(token string) *KeyDeletePayload {
 	}
 	return k
 }
+
+// KeyDeleteManyPayload is the payload for deleting multiple keys of a keyspace at once.
+// Filters are combined, at least one of them is required.
+type KeyDeleteManyPayload struct {
+	*payload
+
+	KSID          string    `json:"ksid" validate:"required" description:"The id of the keyspace to which the keys belong to"`
+	KIDs          []string  `json:"kids" validate:"lte=1000" description:"The ids of the keys to delete (up to 1000)"`
+	ExpiredBefore time.Time `json:"expired_before" description:"Delete the keys expired before this time"`
+	DryRun        bool      `json:"dry_run" description:"Only count the keys that would be deleted"`
+}
+
+// Validate validates the key delete many payload.
+func (k *KeyDeleteManyPayload) Validate(validator *validator.Validate) error {
+	if len(k.KIDs) == 0 && k.ExpiredBefore.IsZero() {
+		return fmt.Errorf("at least one filter is required: %w", ErrInvalidPayload)
+	}
+	return validator.Struct(k)
+}
+
+// WithServiceToken adds authentication infos to payload
+func (k *KeyDeleteManyPayload) WithServiceToken(token string) *KeyDeleteManyPayload {
+	k.payload = &payload{
+		serviceToken: token,
+	}
+	return k
+}
+
+// KeyDeleteManyResult is the result of a bulk key deletion.
+type KeyDeleteManyResult struct {
+	Deleted int64 `json:"deleted"`
+	DryRun  bool  `json:"dry_run"`
+}
+
+// KeyResetPayload is the payload for resetting the rate limit state of a key.
+type KeyResetPayload struct {
+	*payload
+
+	KSID      string `json:"ksid" validate:"required" description:"The id of the keyspace to which the key belongs to"`
+	KID       string `json:"kid" validate:"required" description:"The id of the key to reset"`
+	Remaining *int64 `json:"remaining,omitempty" validate:"omitempty,gte=0" description:"The remaining count to set (defaults to the rate limit)"`
+}
+
+// Validate validates the key reset payload.
+func (k *KeyResetPayload) Validate(validator *validator.Validate) error {
+	return validator.Struct(k)
+}
+
+// WithServiceToken adds authentication infos to payload
+func (k *KeyResetPayload) WithServiceToken(token string) *KeyResetPayload {
+	k.payload = &payload{
+		serviceToken: token,
+	}
+	return k
+}
+
+// KeyMovePayload is the payload for moving a key to another keyspace.
+type KeyMovePayload struct {
+	*payload
+
+	KSID       string `json:"ksid" validate:"required" description:"The id of the keyspace to which the key belongs to"`
+	KID        string `json:"kid" validate:"required" description:"The id of the key to move"`
+	TargetKSID string `json:"target_ksid" validate:"required,nefield=KSID" description:"The id of the keyspace to move the key to"`
+	KeepToken  bool   `json:"keep_token" description:"Keep the token even if the keyspaces keys prefixes differ. Otherwise, a new token is generated with the target keyspace prefix"`
+}
+
+// Validate validates the key move payload.
+func (k *KeyMovePayload) Validate(validator *validator.Validate) error {
+	return validator.Struct(k)
+}
+
+// WithServiceToken adds authentication infos to payload
+func (k *KeyMovePayload) WithServiceToken(token string) *KeyMovePayload {
+	k.payload = &payload{
+		serviceToken: token,
+	}
+	return k
+}
@@ -55,3 +55,30 @@ func TestKeyUpdateRemaining(t *testing.T) {
 	assert.True(t, updated)
 	assert.Equal(t, int64(10), key.Ratelimit.State.Remaining)
 }
+
+func TestKeyGracePeriod(t *testing.T) {
+	now = func() time.Time {
+		return time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC)
+	}
+	since = func(t time.Time) time.Duration {
+		return now().Sub(t)
+	}
+
+	key := Key{
+		ExpiresAt: now().Add(-time.Minute),
+	}
+	assert.True(t, key.Expired())
+	assert.False(t, key.InGracePeriod())
+
+	key.GracePeriod = Milliseconds{Duration: time.Hour}
+	assert.False(t, key.Expired())
+	assert.True(t, key.InGracePeriod())
+
+	key.ExpiresAt = now().Add(-2 * time.Hour)
+	assert.True(t, key.Expired())
+	assert.False(t, key.InGracePeriod())
+
+	key.ExpiresAt = now().Add(time.Minute)
+	assert.False(t, key.Expired())
+	assert.False(t, key.InGracePeriod())
+}
@@ -1,13 +1,36 @@
 package driplimit
 
-import "github.com/go-playground/validator/v10"
+import (
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
 
 // Keyspace represents a driplimit keyspace.
 type Keyspace struct {
-	KSID       string     `json:"ksid"`
-	Name       string     `json:"name"`
-	KeysPrefix string     `json:"keys_prefix"`
-	Ratelimit  *Ratelimit `json:"ratelimit,omitempty"`
+	KSID             string       `json:"ksid"`
+	Name             string       `json:"name"`
+	KeysPrefix       string       `json:"keys_prefix"`
+	GracePeriod      Milliseconds `json:"grace_period"`
+	DefaultExpiresIn Milliseconds `json:"default_expires_in"`
+	MaxExpiresIn     Milliseconds `json:"max_expires_in"`
+	Ratelimit        *Ratelimit   `json:"ratelimit,omitempty"`
+}
+
+// KeyExpiration returns the expiration of a key created at now in the keyspace.
+// The keyspace default applies when expiresAt is zero. ErrInvalidExpiration is returned
+// if no expiration can be determined or if it exceeds the keyspace maximum.
+func (ks *Keyspace) KeyExpiration(expiresAt time.Time, now time.Time) (time.Time, error) {
+	if expiresAt.IsZero() {
+		if ks.DefaultExpiresIn.Duration <= 0 {
+			return time.Time{}, ErrInvalidExpiration
+		}
+		expiresAt = now.Add(ks.DefaultExpiresIn.Duration)
+	}
+	if ks.MaxExpiresIn.Duration > 0 && expiresAt.After(now.Add(ks.MaxExpiresIn.Duration)) {
+		return time.Time{}, ErrInvalidExpiration
+	}
+	return expiresAt, nil
 }
 
 // ConfiguredRateLimit returns true if the rate limit is configured for the keyspace.
@@ -19,13 +42,22 @@ func (ks *Keyspace) ConfiguredRateLimit() bool {
 type KeyspaceCreatePayload struct {
 	*payload
 
-	Name       string           `json:"name" validate:"required" description:"The name of the keyspace"`
-	KeysPrefix string           `json:"keys_prefix" validate:"required,gte=1,lte=16" description:"The prefix for the keys in the keyspace"`
-	Ratelimit  RatelimitPayload `json:"ratelimit,omitempty" description:"The default rate limit configuration for keys in the keyspace"`
+	Name             string           `json:"name" validate:"required" description:"The name of the keyspace"`
+	KeysPrefix       string           `json:"keys_prefix" validate:"required,gte=1,lte=16" description:"The prefix for the keys in the keyspace"`
+	GracePeriod      Milliseconds     `json:"grace_period" description:"The default duration in milliseconds during which keys are still accepted after their expiration"`
+	DefaultExpiresIn Milliseconds     `json:"default_expires_in" description:"The default lifetime in milliseconds of keys created without expiration (0 requires an expiration on every key)"`
+	MaxExpiresIn     Milliseconds     `json:"max_expires_in" description:"The maximum lifetime in milliseconds of keys in the keyspace (0 means unlimited)"`
+	Ratelimit        RatelimitPayload `json:"ratelimit,omitempty" description:"The default rate limit configuration for keys in the keyspace"`
 }
 
 // Validate validates the keyspace create payload.
 func (ks *KeyspaceCreatePayload) Validate(validator *validator.Validate) error {
+	if ks.GracePeriod.Duration < 0 || ks.DefaultExpiresIn.Duration < 0 || ks.MaxExpiresIn.Duration < 0 {
+		return ErrInvalidPayload
+	}
+	if ks.MaxExpiresIn.Duration > 0 && ks.DefaultExpiresIn.Duration > ks.MaxExpiresIn.Duration {
+		return ErrInvalidPayload
+	}
 	return validator.Struct(ks)
 }
 
@@ -87,6 +119,99 @@ func (k *KeyspaceListPayload) WithServiceToken(token string) *KeyspaceListPayloa
 	return k
 }
 
+// KeyspaceUpdatePayload represents the payload for updating a keyspace.
+// Only the provided fields are updated.
+type KeyspaceUpdatePayload struct {
+	*payload
+
+	KSID             string            `json:"ksid" validate:"required" description:"The id of the keyspace to update"`
+	Name             *string           `json:"name,omitempty" validate:"omitempty,gte=1" description:"The new name of the keyspace"`
+	GracePeriod      *Milliseconds     `json:"grace_period,omitempty" description:"The new default grace period in milliseconds for keys in the keyspace"`
+	DefaultExpiresIn *Milliseconds     `json:"default_expires_in,omitempty" description:"The new default lifetime in milliseconds of keys created without expiration"`
+	MaxExpiresIn     *Milliseconds     `json:"max_expires_in,omitempty" description:"The new maximum lifetime in milliseconds of keys in the keyspace (0 means unlimited)"`
+	Ratelimit        *RatelimitPayload `json:"ratelimit,omitempty" description:"The new default rate limit configuration for keys in the keyspace (a zero limit removes it)"`
+}
+
+// Validate validates the keyspace update payload.
+func (k *KeyspaceUpdatePayload) Validate(validator *validator.Validate) error {
+	if k.GracePeriod != nil && k.GracePeriod.Duration < 0 {
+		return ErrInvalidPayload
+	}
+	if k.DefaultExpiresIn != nil && k.DefaultExpiresIn.Duration < 0 {
+		return ErrInvalidPayload
+	}
+	if k.MaxExpiresIn != nil && k.MaxExpiresIn.Duration < 0 {
+		return ErrInvalidPayload
+	}
+	return validator.Struct(k)
+}
+
+// WithServiceToken adds authentication infos to payload
+func (k *KeyspaceUpdatePayload) WithServiceToken(token string) *KeyspaceUpdatePayload {
+	k.payload = &payload{
+		serviceToken: token,
+	}
+	return k
+}
+
+// KeyspaceClonePayload represents the payload for cloning a keyspace.
+type KeyspaceClonePayload struct {
+	*payload
+
+	KSID       string `json:"ksid" validate:"required" description:"The id of the keyspace to clone"`
+	NameSuffix string `json:"name_suffix" validate:"required" description:"The suffix appended to the source keyspace name to name the clone"`
+	KeysPrefix string `json:"keys_prefix" validate:"required,gte=1,lte=16" description:"The prefix for the keys in the clone, it must not be used by another keyspace"`
+	CopyKeys   bool   `json:"copy_keys" description:"Copy the keys of the source keyspace (their tokens remain valid in the clone)"`
+}
+
+// Validate validates the keyspace clone payload.
+func (k *KeyspaceClonePayload) Validate(validator *validator.Validate) error {
+	return validator.Struct(k)
+}
+
+// WithServiceToken adds authentication infos to payload
+func (k *KeyspaceClonePayload) WithServiceToken(token string) *KeyspaceClonePayload {
+	k.payload = &payload{
+		serviceToken: token,
+	}
+	return k
+}
+
+// KeyspaceStatsPayload represents the payload for getting the statistics of a keyspace.
+type KeyspaceStatsPayload struct {
+	*payload
+
+	KSID string `json:"ksid" validate:"required" description:"The id of the keyspace"`
+}
+
+// Validate validates the keyspace stats payload.
+func (k *KeyspaceStatsPayload) Validate(validator *validator.Validate) error {
+	return validator.Struct(k)
+}
+
+// WithServiceToken adds authentication infos to payload
+func (k *KeyspaceStatsPayload) WithServiceToken(token string) *KeyspaceStatsPayload {
+	k.payload = &payload{
+		serviceToken: token,
+	}
+	return k
+}
+
+// KeyspaceStats represents the key statistics of a keyspace. Except for deleted keys,
+// counts only cover keys that are not deleted.
+type KeyspaceStats struct {
+	KSID                 string    `json:"ksid"`
+	ActiveKeys           int64     `json:"active_keys"`
+	ExpiredKeys          int64     `json:"expired_keys"`
+	DeletedKeys          int64     `json:"deleted_keys"`
+	CustomRatelimitKeys  int64     `json:"custom_ratelimit_keys"`
+	DefaultRatelimitKeys int64     `json:"default_ratelimit_keys"`
+	UsedLast24h          int64     `json:"used_last_24h"`
+	UsedLast7d           int64     `json:"used_last_7d"`
+	ExhaustedKeys        int64     `json:"exhausted_keys"`
+	ComputedAt           time.Time `json:"computed_at"`
+}
+
 // KeyspaceDeletePayload is the payload for deleting a keyspace.
 type KeyspaceDeletePayload struct {
 	*payload
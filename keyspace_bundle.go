@@ -0,0 +1,124 @@
+package driplimit
+
+import (
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// KeyspaceBundleVersion is the version of the keyspace bundle format produced by exports.
+const KeyspaceBundleVersion = 2
+
+// KeyspaceBundle is a portable document holding a keyspace with its plans, keys and
+// service keys policies. It is produced by keyspace exports and consumed by imports.
+type KeyspaceBundle struct {
+	Version    int                  `json:"version" validate:"required" description:"The version of the bundle format"`
+	ExportedAt time.Time            `json:"exported_at" description:"The time at which the bundle was exported"`
+	Keyspace   *Keyspace            `json:"keyspace" validate:"required" description:"The keyspace configuration"`
+	Plans      []*Plan              `json:"plans" description:"The plans of the keyspace"`
+	Keys       []*KeyspaceBundleKey `json:"keys" description:"The keys of the keyspace"`
+	Policies   Policies             `json:"policies" description:"The keyspace policies by service key id"`
+}
+
+// KeyspaceBundleKey is a key as stored in a keyspace bundle. Only the token hash is
+// exported, tokens remain valid after import. The rate limit is the key own configuration,
+// a zero limit meaning the key relies on its plan or keyspace. A key without grace period
+// relies on its keyspace one.
+type KeyspaceBundleKey struct {
+	KID         string           `json:"kid"`
+	PLID        string           `json:"plid,omitempty"`
+	TokenHash   string           `json:"token_hash"`
+	LastUsed    time.Time        `json:"last_used"`
+	ExpiresAt   time.Time        `json:"expires_at"`
+	CreatedAt   time.Time        `json:"created_at"`
+	GracePeriod *Milliseconds    `json:"grace_period,omitempty"`
+	Ratelimit   RatelimitPayload `json:"ratelimit"`
+	State       RatelimitState   `json:"state"`
+}
+
+// KeyspaceExportPayload represents the payload for exporting a keyspace.
+type KeyspaceExportPayload struct {
+	*payload
+
+	KSID string `json:"ksid" validate:"required" description:"The id of the keyspace to export"`
+}
+
+// Validate validates the keyspace export payload.
+func (k *KeyspaceExportPayload) Validate(validator *validator.Validate) error {
+	return validator.Struct(k)
+}
+
+// WithServiceToken adds authentication infos to payload
+func (k *KeyspaceExportPayload) WithServiceToken(token string) *KeyspaceExportPayload {
+	k.payload = &payload{
+		serviceToken: token,
+	}
+	return k
+}
+
+// ImportStrategy defines how existing items are handled when importing a keyspace bundle.
+type ImportStrategy string
+
+const (
+	// ImportSkip keeps existing items untouched and only imports the missing ones.
+	ImportSkip ImportStrategy = "skip"
+	// ImportOverwrite replaces existing items with the bundle ones.
+	ImportOverwrite ImportStrategy = "overwrite"
+	// ImportFail aborts the import if any item already exists.
+	ImportFail ImportStrategy = "fail"
+)
+
+// KeyspaceImportPayload represents the payload for importing a keyspace bundle.
+type KeyspaceImportPayload struct {
+	*payload
+
+	Bundle   KeyspaceBundle `json:"bundle" description:"The keyspace bundle to import"`
+	Strategy ImportStrategy `json:"strategy" validate:"required,oneof=skip overwrite fail" description:"How existing items are handled: skip, overwrite or fail"`
+}
+
+// Validate validates the keyspace import payload.
+func (k *KeyspaceImportPayload) Validate(validator *validator.Validate) error {
+	if k.Bundle.Version != KeyspaceBundleVersion && k.Bundle.Version != 1 {
+		return ErrInvalidPayload
+	}
+	if k.Bundle.Keyspace == nil || k.Bundle.Keyspace.KSID == "" || k.Bundle.Keyspace.Name == "" || k.Bundle.Keyspace.KeysPrefix == "" {
+		return ErrInvalidPayload
+	}
+	plans := make(map[string]bool, len(k.Bundle.Plans))
+	for _, plan := range k.Bundle.Plans {
+		if plan.PLID == "" || plan.Name == "" {
+			return ErrInvalidPayload
+		}
+		plans[plan.PLID] = true
+	}
+	for _, key := range k.Bundle.Keys {
+		if key.KID == "" || key.TokenHash == "" {
+			return ErrInvalidPayload
+		}
+		if key.PLID != "" && !plans[key.PLID] {
+			return ErrInvalidPayload
+		}
+		// version 1 bundles hold a zero grace period for keys relying on their keyspace one
+		if k.Bundle.Version == 1 && key.GracePeriod != nil && key.GracePeriod.Duration == 0 {
+			key.GracePeriod = nil
+		}
+	}
+	return validator.Struct(k)
+}
+
+// WithServiceToken adds authentication infos to payload
+func (k *KeyspaceImportPayload) WithServiceToken(token string) *KeyspaceImportPayload {
+	k.payload = &payload{
+		serviceToken: token,
+	}
+	return k
+}
+
+// KeyspaceImportResult reports the outcome of a keyspace import. Counts cover
+// the keyspace, its plans, keys and policies.
+type KeyspaceImportResult struct {
+	Keyspace    *Keyspace `json:"keyspace"`
+	Created     int       `json:"created"`
+	Overwritten int       `json:"overwritten"`
+	Skipped     int       `json:"skipped"`
+}
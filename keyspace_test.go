@@ -0,0 +1,38 @@
+package driplimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyspaceKeyExpiration(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	// no default, an expiration is required
+	ks := Keyspace{}
+	_, err := ks.KeyExpiration(time.Time{}, now)
+	assert.ErrorIs(t, err, ErrInvalidExpiration)
+
+	expiresAt, err := ks.KeyExpiration(now.Add(365*day), now)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(365*day), expiresAt)
+
+	// default applies when no expiration is given
+	ks = Keyspace{
+		DefaultExpiresIn: Milliseconds{Duration: 30 * day},
+		MaxExpiresIn:     Milliseconds{Duration: 90 * day},
+	}
+	expiresAt, err = ks.KeyExpiration(time.Time{}, now)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(30*day), expiresAt)
+
+	// expirations beyond the maximum are rejected
+	expiresAt, err = ks.KeyExpiration(now.Add(90*day), now)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(90*day), expiresAt)
+	_, err = ks.KeyExpiration(now.Add(91*day), now)
+	assert.ErrorIs(t, err, ErrInvalidExpiration)
+}
@@ -1,8 +1,23 @@
 package api_test
 
 import (
+	"bytes"
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
 	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -12,6 +27,9 @@ import (
 	"github.com/i4n-co/driplimit/pkg/authoritative"
 	"github.com/i4n-co/driplimit/pkg/client"
 	"github.com/i4n-co/driplimit/pkg/config"
+	"github.com/i4n-co/driplimit/pkg/jwt"
+	"github.com/i4n-co/driplimit/pkg/jwt/jwttest"
+	"github.com/i4n-co/driplimit/pkg/proxycache"
 	"github.com/i4n-co/driplimit/pkg/store"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
@@ -19,7 +37,13 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
-var cli *client.HTTP
+var (
+	cli     *client.HTTP
+	server  *api.Server
+	service driplimit.Service
+	usage   *driplimit.UsageTracker
+	jwtKey  *ecdsa.PrivateKey
+)
 
 func init() {
 	ctx := context.Background()
@@ -43,11 +67,42 @@ func init() {
 		log.Fatal(err)
 	}
 
-	authoritative := authoritative.NewService(store)
-	authorizer := driplimit.NewAuthorizer(authoritative)
-	validator := driplimit.NewServiceValidator(authorizer)
+	// JWT bearer tokens are verified against a locally generated key pair
+	jwtKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		log.Fatal(err)
+	}
+	jwks, err := jwttest.NewJWKS(map[string]crypto.PublicKey{"test": jwtKey.Public()})
+	if err != nil {
+		log.Fatal(err)
+	}
+	jwksFile, err := os.CreateTemp("", "jwks-*.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.Remove(jwksFile.Name())
+	_, err = jwksFile.Write(jwks)
+	if err != nil {
+		log.Fatal(err)
+	}
+	jwksFile.Close()
+	verifier, err := jwt.NewVerifier(ctx, jwksFile.Name(), "https://issuer.test", "driplimit")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	authoritative := authoritative.NewService(store).WithJWTAuthentication(authoritative.JWTAuthentication{
+		Verifier:      verifier,
+		SKIDClaim:     "skid",
+		PoliciesClaim: "keyspaces_policies",
+	})
+	// usages are flushed by the tests
+	usage = driplimit.NewUsageTracker(ctx, store, time.Hour, cfg.Logger())
+	authorizer := driplimit.NewAuthorizer(authoritative).WithUsageTracker(usage)
+	auditor := driplimit.NewAuditor(authorizer, store, cfg.Logger())
+	service = driplimit.NewServiceValidator(auditor)
 
-	server := api.New(cfg, validator)
+	server = api.New(cfg, service)
 
 	cli = client.New("http://localhost.test").WithSendRequestFunc(server.Test)
 }
@@ -133,12 +188,49 @@ func TestDriplimitAPI(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, k.Ratelimit.State.Remaining, int64(9))
 
+	// batch check should return a result per check
+	batch, err := cli.KeyCheckBatch(ctx, driplimit.KeysCheckBatchPayload{
+		Checks: []driplimit.KeysCheckPayload{
+			{KSID: k.KSID, Token: token},
+			{KSID: k.KSID, Token: "test_wrl_unknown"},
+			{KSID: k.KSID},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, batch.Results, 3)
+	assert.NoError(t, batch.Results[0].Err())
+	assert.Equal(t, int64(8), batch.Results[0].Key.Ratelimit.State.Remaining)
+	assert.ErrorIs(t, batch.Results[1].Err(), driplimit.ErrNotFound)
+	assert.Equal(t, "key not found", batch.Results[1].Error)
+	assert.ErrorIs(t, batch.Results[2].Err(), driplimit.ErrInvalidPayload)
+
+	// reset should restore the remaining count to the limit
+	k, err = cli.KeyReset(ctx, driplimit.KeyResetPayload{KSID: k.KSID, KID: k.KID})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), k.Ratelimit.State.Remaining)
+
+	remaining := int64(9)
+	k, err = cli.KeyReset(ctx, driplimit.KeyResetPayload{KSID: k.KSID, KID: k.KID, Remaining: &remaining})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9), k.Ratelimit.State.Remaining)
+
 	lkeys, err := cli.KeyList(ctx, driplimit.KeyListPayload{
 		KSID: withRateLimitKS.KSID,
 	})
 	assert.NoError(t, err)
 	assert.Len(t, lkeys.Keys, 2)
 
+	// delete many requires a filter and only counts keys in dry run mode
+	_, err = cli.KeyDeleteMany(ctx, driplimit.KeyDeleteManyPayload{KSID: withRateLimitKS.KSID})
+	assert.ErrorIs(t, err, driplimit.ErrInvalidPayload)
+	deleted, err := cli.KeyDeleteMany(ctx, driplimit.KeyDeleteManyPayload{
+		KSID:          withRateLimitKS.KSID,
+		ExpiredBefore: time.Now(),
+		DryRun:        true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deleted.Deleted)
+
 	err = cli.KeyDelete(ctx, driplimit.KeyDeletePayload{
 		KSID: withRateLimitKS.KSID,
 		KID:  lkeys.Keys[1].KID,
@@ -154,7 +246,8 @@ func TestDriplimitAPI(t *testing.T) {
 	nsk, err := cli.ServiceKeyCreate(ctx, driplimit.ServiceKeyCreatePayload{
 		KeyspacesPolicies: driplimit.Policies{
 			withRateLimitKS.KSID: driplimit.Policy{
-				Read: true,
+				Check: true,
+				Read:  true,
 			},
 		},
 		Description: "read restricted service key on test_with_ratelimit keyspace",
@@ -175,4 +268,872 @@ func TestDriplimitAPI(t *testing.T) {
 		KID:  k.KID,
 	})
 	assert.ErrorIs(t, driplimit.ErrUnauthorized, err)
+
+	// Should fail as only admins can update service keys
+	_, err = cli.WithServiceToken(nsk.Token).ServiceKeyUpdate(ctx, driplimit.ServiceKeyUpdatePayload{
+		SKID:  nsk.SKID,
+		Admin: new(bool),
+	})
+	assert.ErrorIs(t, err, driplimit.ErrUnauthorized)
+
+	// grant write on the keyspace, the description and token are kept
+	usk, err := cli.ServiceKeyUpdate(ctx, driplimit.ServiceKeyUpdatePayload{
+		SKID: nsk.SKID,
+		KeyspacesPolicies: driplimit.Policies{
+			withRateLimitKS.KSID: driplimit.Policy{Check: true, Read: true, Create: true, Update: true, Delete: true},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, nsk.Description, usk.Description)
+	assert.True(t, usk.KeyspacesPolicies.Can(driplimit.Update, withRateLimitKS.KSID))
+
+	// removing all policies revokes access to the keyspace
+	_, err = cli.ServiceKeyUpdate(ctx, driplimit.ServiceKeyUpdatePayload{
+		SKID:              nsk.SKID,
+		KeyspacesPolicies: driplimit.Policies{},
+	})
+	assert.NoError(t, err)
+	_, err = cli.WithServiceToken(nsk.Token).KeyCheck(ctx, driplimit.KeysCheckPayload{
+		KSID:  withRateLimitKS.KSID,
+		Token: token,
+	})
+	assert.ErrorIs(t, err, driplimit.ErrUnauthorized)
+
+	// service keys cannot be created already expired
+	_, err = cli.ServiceKeyCreate(ctx, driplimit.ServiceKeyCreatePayload{
+		Description: "expired service key",
+		ExpiresAt:   time.Now().Add(-time.Minute),
+	})
+	assert.ErrorIs(t, err, driplimit.ErrInvalidExpiration)
+
+	// expiring the service key revokes it
+	expiredAt := time.Now().Add(-time.Second)
+	_, err = cli.ServiceKeyUpdate(ctx, driplimit.ServiceKeyUpdatePayload{
+		SKID:      nsk.SKID,
+		ExpiresAt: &expiredAt,
+	})
+	assert.NoError(t, err)
+	_, err = cli.WithServiceToken(nsk.Token).ServiceKeyGet(ctx, driplimit.ServiceKeyGetPayload{
+		Token: nsk.Token,
+	})
+	assert.ErrorIs(t, err, driplimit.ErrUnauthorized)
+
+	expired := true
+	skList, err := cli.ServiceKeyList(ctx, driplimit.ServiceKeyListPayload{
+		List:    driplimit.ListPayload{Limit: 10, Page: 1},
+		Expired: &expired,
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, skList.ServiceKeys, 1) {
+		assert.Equal(t, nsk.SKID, skList.ServiceKeys[0].SKID)
+	}
+	expired = false
+	skList, err = cli.ServiceKeyList(ctx, driplimit.ServiceKeyListPayload{
+		List:    driplimit.ListPayload{Limit: 10, Page: 1},
+		Expired: &expired,
+	})
+	assert.NoError(t, err)
+	for _, sk := range skList.ServiceKeys {
+		assert.NotEqual(t, nsk.SKID, sk.SKID)
+	}
+
+	// a check only service key cannot read the keys metadata
+	gatewaySK, err := cli.ServiceKeyCreate(ctx, driplimit.ServiceKeyCreatePayload{
+		KeyspacesPolicies: driplimit.Policies{
+			withRateLimitKS.KSID: driplimit.Policy{Check: true},
+		},
+		Description: "gateway service key",
+	})
+	assert.NoError(t, err)
+	_, err = cli.WithServiceToken(gatewaySK.Token).KeyCheck(ctx, driplimit.KeysCheckPayload{
+		KSID:  withRateLimitKS.KSID,
+		Token: token,
+	})
+	assert.NoError(t, err)
+	_, err = cli.WithServiceToken(gatewaySK.Token).KeyList(ctx, driplimit.KeyListPayload{
+		KSID: withRateLimitKS.KSID,
+		List: driplimit.ListPayload{Limit: 10, Page: 1},
+	})
+	assert.ErrorIs(t, err, driplimit.ErrUnauthorized)
+
+	// a service key managing the keyspace policies can grant actions on it only
+	managerSK, err := cli.ServiceKeyCreate(ctx, driplimit.ServiceKeyCreatePayload{
+		KeyspacesPolicies: driplimit.Policies{
+			withRateLimitKS.KSID: driplimit.Policy{ManagePolicies: true},
+		},
+		Description: "keyspace policies manager",
+	})
+	assert.NoError(t, err)
+	usk, err = cli.WithServiceToken(managerSK.Token).ServiceKeyUpdate(ctx, driplimit.ServiceKeyUpdatePayload{
+		SKID: gatewaySK.SKID,
+		KeyspacesPolicies: driplimit.Policies{
+			withRateLimitKS.KSID: driplimit.Policy{Check: true, Read: true},
+		},
+	})
+	assert.NoError(t, err)
+	assert.True(t, usk.KeyspacesPolicies.Can(driplimit.Read, withRateLimitKS.KSID))
+	kslist, err := cli.WithServiceToken(gatewaySK.Token).KeyspaceList(ctx, driplimit.KeyspaceListPayload{
+		List: driplimit.ListPayload{Limit: 10, Page: 1},
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, kslist.Keyspaces, 1) {
+		assert.Equal(t, withRateLimitKS.KSID, kslist.Keyspaces[0].KSID)
+	}
+	_, err = cli.WithServiceToken(managerSK.Token).ServiceKeyUpdate(ctx, driplimit.ServiceKeyUpdatePayload{
+		SKID: gatewaySK.SKID,
+		KeyspacesPolicies: driplimit.Policies{
+			"*": driplimit.Policy{Read: true},
+		},
+	})
+	assert.ErrorIs(t, err, driplimit.ErrUnauthorized)
+	_, err = cli.WithServiceToken(managerSK.Token).ServiceKeyUpdate(ctx, driplimit.ServiceKeyUpdatePayload{
+		SKID:  gatewaySK.SKID,
+		Admin: new(bool),
+	})
+	assert.ErrorIs(t, err, driplimit.ErrUnauthorized)
+
+	// pattern policies match keyspaces names and deny policies override them
+	patternSK, err := cli.ServiceKeyCreate(ctx, driplimit.ServiceKeyCreatePayload{
+		KeyspacesPolicies: driplimit.Policies{
+			"test*":       driplimit.Policy{Check: true, Read: true},
+			"test_with_*": driplimit.Policy{Read: true, Deny: true},
+		},
+		Description: "pattern service key",
+	})
+	assert.NoError(t, err)
+	kslist, err = cli.WithServiceToken(patternSK.Token).KeyspaceList(ctx, driplimit.KeyspaceListPayload{
+		List: driplimit.ListPayload{Limit: 10, Page: 1},
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, kslist.Keyspaces, 1) {
+		assert.Equal(t, ks1.KSID, kslist.Keyspaces[0].KSID)
+	}
+	_, err = cli.WithServiceToken(patternSK.Token).KeyspaceGet(ctx, driplimit.KeyspaceGetPayload{
+		KSID: withRateLimitKS.KSID,
+	})
+	assert.ErrorIs(t, err, driplimit.ErrUnauthorized)
+	_, err = cli.WithServiceToken(patternSK.Token).KeyCheck(ctx, driplimit.KeysCheckPayload{
+		KSID:  withRateLimitKS.KSID,
+		Token: token,
+	})
+	assert.NoError(t, err)
+
+	// only admins can manage pattern policies
+	_, err = cli.WithServiceToken(managerSK.Token).ServiceKeyUpdate(ctx, driplimit.ServiceKeyUpdatePayload{
+		SKID: gatewaySK.SKID,
+		KeyspacesPolicies: driplimit.Policies{
+			withRateLimitKS.KSID: driplimit.Policy{Check: true, Read: true},
+			"test*":              driplimit.Policy{Read: true},
+		},
+	})
+	assert.ErrorIs(t, err, driplimit.ErrUnauthorized)
+
+	// ROLES
+	role, err := cli.RoleCreate(ctx, driplimit.RoleCreatePayload{
+		Name: "gateway",
+		KeyspacesPolicies: driplimit.Policies{
+			withRateLimitKS.KSID: driplimit.Policy{Check: true},
+		},
+	})
+	assert.NoError(t, err)
+	_, err = cli.RoleCreate(ctx, driplimit.RoleCreatePayload{Name: "gateway"})
+	assert.ErrorIs(t, err, driplimit.ErrAlreadyExists)
+
+	roleSK, err := cli.ServiceKeyCreate(ctx, driplimit.ServiceKeyCreatePayload{
+		Description: "service key holding the gateway role",
+		Roles:       []string{role.RLID},
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, roleSK.Roles, 1) {
+		assert.Equal(t, role.RLID, roleSK.Roles[0].RLID)
+	}
+	_, err = cli.WithServiceToken(roleSK.Token).KeyCheck(ctx, driplimit.KeysCheckPayload{
+		KSID:  withRateLimitKS.KSID,
+		Token: token,
+	})
+	assert.NoError(t, err)
+	_, err = cli.WithServiceToken(roleSK.Token).KeyList(ctx, driplimit.KeyListPayload{
+		KSID: withRateLimitKS.KSID,
+		List: driplimit.ListPayload{Limit: 10, Page: 1},
+	})
+	assert.ErrorIs(t, err, driplimit.ErrUnauthorized)
+
+	// updating the role affects the service keys holding it at once
+	_, err = cli.RoleUpdate(ctx, driplimit.RoleUpdatePayload{
+		RLID: role.RLID,
+		KeyspacesPolicies: driplimit.Policies{
+			withRateLimitKS.KSID: driplimit.Policy{Check: true, Read: true},
+		},
+	})
+	assert.NoError(t, err)
+	_, err = cli.WithServiceToken(roleSK.Token).KeyList(ctx, driplimit.KeyListPayload{
+		KSID: withRateLimitKS.KSID,
+		List: driplimit.ListPayload{Limit: 10, Page: 1},
+	})
+	assert.NoError(t, err)
+	kslist, err = cli.WithServiceToken(roleSK.Token).KeyspaceList(ctx, driplimit.KeyspaceListPayload{
+		List: driplimit.ListPayload{Limit: 10, Page: 1},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, kslist.Keyspaces, 1)
+
+	rlist, err := cli.RoleList(ctx, driplimit.RoleListPayload{List: driplimit.ListPayload{Limit: 10, Page: 1}})
+	assert.NoError(t, err)
+	assert.Len(t, rlist.Roles, 1)
+
+	// deleting the role revokes its policies
+	err = cli.RoleDelete(ctx, driplimit.RoleDeletePayload{RLID: role.RLID})
+	assert.NoError(t, err)
+	_, err = cli.WithServiceToken(roleSK.Token).KeyCheck(ctx, driplimit.KeysCheckPayload{
+		KSID:  withRateLimitKS.KSID,
+		Token: token,
+	})
+	assert.ErrorIs(t, err, driplimit.ErrUnauthorized)
+
+	// AUDIT
+	// mutating calls are recorded with their caller, targets and result
+	alist, err := cli.AuditList(ctx, driplimit.AuditListPayload{
+		List:     driplimit.ListPayload{Limit: 10, Page: 1},
+		TargetID: role.RLID,
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, alist.Entries, 3) {
+		assert.Equal(t, "roles.delete", alist.Entries[0].Action)
+		assert.Equal(t, "roles.update", alist.Entries[1].Action)
+		assert.Equal(t, "roles.create", alist.Entries[2].Action)
+		assert.Equal(t, "sk_root", alist.Entries[0].SKID)
+		assert.Equal(t, driplimit.AuditSuccess, alist.Entries[0].Result)
+	}
+
+	// unauthorized calls are recorded as failures
+	alist, err = cli.AuditList(ctx, driplimit.AuditListPayload{
+		List:   driplimit.ListPayload{Limit: 10, Page: 1},
+		SKID:   managerSK.SKID,
+		Action: "serviceKeys.update",
+		Result: driplimit.AuditFailure,
+	})
+	assert.NoError(t, err)
+	if assert.NotEmpty(t, alist.Entries) {
+		assert.Equal(t, driplimit.ErrUnauthorized.Error(), alist.Entries[0].Error)
+		assert.Equal(t, []string{gatewaySK.SKID}, alist.Entries[0].TargetIDs)
+	}
+
+	// tokens never reach the audit log
+	err = cli.ServiceKeySetToken(ctx, driplimit.ServiceKeySetTokenPayload{
+		SKID:  roleSK.SKID,
+		Token: "s3cr3t",
+	})
+	assert.NoError(t, err)
+	alist, err = cli.AuditList(ctx, driplimit.AuditListPayload{
+		List:   driplimit.ListPayload{Limit: 10, Page: 1},
+		Action: "serviceKeys.set_token",
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, alist.Entries, 1) {
+		assert.NotContains(t, string(alist.Entries[0].Payload), "s3cr3t")
+	}
+
+	// only admins can read the audit log
+	_, err = cli.WithServiceToken(managerSK.Token).AuditList(ctx, driplimit.AuditListPayload{
+		List: driplimit.ListPayload{Limit: 10, Page: 1},
+	})
+	assert.ErrorIs(t, err, driplimit.ErrUnauthorized)
+
+	// PERMISSIONS
+	// simulations explain which rule decides
+	permission, err := cli.ServiceKeyCan(ctx, driplimit.ServiceKeyCanPayload{
+		SKID:   patternSK.SKID,
+		Action: driplimit.Read,
+		KSID:   withRateLimitKS.KSID,
+	})
+	assert.NoError(t, err)
+	assert.True(t, permission.KeyspaceFound)
+	assert.False(t, permission.Allowed)
+	assert.True(t, permission.Deny)
+	assert.Equal(t, driplimit.RulePattern, permission.Rule)
+	assert.Equal(t, "test_with_*", permission.Policy)
+
+	// unknown keyspaces are told apart from missing policies
+	permission, err = cli.WithServiceToken(patternSK.Token).ServiceKeyCan(ctx, driplimit.ServiceKeyCanPayload{
+		SKID:   patternSK.SKID,
+		Action: driplimit.Check,
+		KSID:   "ks_unknown",
+	})
+	assert.NoError(t, err)
+	assert.False(t, permission.KeyspaceFound)
+	assert.Equal(t, driplimit.RuleNone, permission.Rule)
+
+	// only admins can simulate other service keys
+	_, err = cli.WithServiceToken(managerSK.Token).ServiceKeyCan(ctx, driplimit.ServiceKeyCanPayload{
+		SKID:   patternSK.SKID,
+		Action: driplimit.Check,
+		KSID:   withRateLimitKS.KSID,
+	})
+	assert.ErrorIs(t, err, driplimit.ErrUnauthorized)
+}
+
+func TestSignedRequests(t *testing.T) {
+	ctx := context.Background()
+	admin := cli.WithServiceToken("t0k3n")
+
+	// service keys are created with a signing secret disclosed once
+	sk, err := admin.ServiceKeyCreate(ctx, driplimit.ServiceKeyCreatePayload{Description: "signing service key"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, sk.SigningSecret)
+	got, err := admin.ServiceKeyGet(ctx, driplimit.ServiceKeyGetPayload{SKID: sk.SKID})
+	assert.NoError(t, err)
+	assert.Empty(t, got.SigningSecret)
+
+	// requests are signed automatically
+	var signed *http.Request
+	var signedBody []byte
+	signer := client.New("http://localhost.test").WithSendRequestFunc(func(req *http.Request) (*http.Response, error) {
+		signedBody, _ = io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(signedBody))
+		signed = req
+		return server.Test(req)
+	}).WithSigningSecret(sk.SKID, sk.SigningSecret)
+	current, err := signer.ServiceKeyCurrent(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, sk.SKID, current.SKID)
+	assert.True(t, strings.HasPrefix(signed.Header.Get("Authorization"), driplimit.SignatureScheme))
+
+	// replayed requests are rejected
+	replay, err := http.NewRequest(signed.Method, signed.URL.String(), bytes.NewReader(signedBody))
+	assert.NoError(t, err)
+	replay.Header = signed.Header.Clone()
+	resp, err := server.Test(replay)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// forged signatures do not use up nonces
+	signedWith := func(secret string) *http.Response {
+		req, err := http.NewRequest(signed.Method, signed.URL.String(), bytes.NewReader(signedBody))
+		assert.NoError(t, err)
+		req.Header = signed.Header.Clone()
+		signature := driplimit.NewRequestSignature(sk.SKID, signed.Method, signed.URL.Path, signedBody, time.Now(), "f0rg3d").Sign(secret)
+		req.Header.Set("Authorization", signature.Header())
+		resp, err := server.Test(req)
+		assert.NoError(t, err)
+		return resp
+	}
+	assert.Equal(t, http.StatusUnauthorized, signedWith("wrong").StatusCode)
+	assert.Equal(t, http.StatusOK, signedWith(sk.SigningSecret).StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, signedWith(sk.SigningSecret).StatusCode)
+
+	// credentials cannot be used as bearer tokens
+	signature, err := driplimit.ParseRequestSignature(signed.Header.Get("Authorization"))
+	assert.NoError(t, err)
+	signature.Method, signature.Path = signed.Method, signed.URL.Path
+	_, err = cli.WithServiceToken(signature.Credential()).ServiceKeyCurrent(ctx)
+	assert.ErrorIs(t, err, driplimit.ErrUnauthorized)
+
+	_, err = signer.WithSigningSecret(sk.SKID, "wrong").ServiceKeyCurrent(ctx)
+	assert.ErrorIs(t, err, driplimit.ErrUnauthorized)
+
+	// rotating the signing secret revokes the previous one
+	rotated, err := admin.ServiceKeyUpdate(ctx, driplimit.ServiceKeyUpdatePayload{
+		SKID:                sk.SKID,
+		RotateSigningSecret: true,
+	})
+	assert.NoError(t, err)
+	assert.NotEqual(t, sk.SigningSecret, rotated.SigningSecret)
+	_, err = signer.ServiceKeyCurrent(ctx)
+	assert.ErrorIs(t, err, driplimit.ErrUnauthorized)
+	_, err = signer.WithSigningSecret(sk.SKID, rotated.SigningSecret).ServiceKeyCurrent(ctx)
+	assert.NoError(t, err)
+}
+
+func TestJWTAuthentication(t *testing.T) {
+	ctx := context.Background()
+	admin := cli.WithServiceToken("t0k3n")
+	issue := func(claims map[string]any) string {
+		claims["iss"] = "https://issuer.test"
+		claims["aud"] = "driplimit"
+		if _, found := claims["exp"]; !found {
+			claims["exp"] = time.Now().Add(time.Minute).Unix()
+		}
+		token, err := jwttest.Sign(jwtKey, "test", claims)
+		assert.NoError(t, err)
+		return token
+	}
+
+	// a claim maps the token to a service key, which never outlives the token
+	sk, err := admin.ServiceKeyCreate(ctx, driplimit.ServiceKeyCreatePayload{Description: "jwt service key"})
+	assert.NoError(t, err)
+	exp := time.Now().Add(time.Minute).Truncate(time.Second)
+	current, err := cli.WithServiceToken(issue(map[string]any{"skid": sk.SKID, "exp": exp.Unix()})).ServiceKeyCurrent(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, sk.SKID, current.SKID)
+	assert.True(t, exp.Equal(current.ExpiresAt))
+
+	// or directly to keyspaces policies
+	ks, err := admin.KeyspaceCreate(ctx, driplimit.KeyspaceCreatePayload{Name: "jwt", KeysPrefix: "jwt_"})
+	assert.NoError(t, err)
+	policiesToken := issue(map[string]any{
+		"sub":                "billing",
+		"keyspaces_policies": driplimit.Policies{ks.KSID: {Read: true}},
+	})
+	current, err = cli.WithServiceToken(policiesToken).ServiceKeyCurrent(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "jwt:billing", current.SKID)
+	_, err = cli.WithServiceToken(policiesToken).KeyspaceGet(ctx, driplimit.KeyspaceGetPayload{KSID: ks.KSID})
+	assert.NoError(t, err)
+	err = cli.WithServiceToken(policiesToken).KeyspaceDelete(ctx, driplimit.KeyspaceDeletePayload{KSID: ks.KSID})
+	assert.ErrorIs(t, err, driplimit.ErrUnauthorized)
+
+	// expired tokens and tokens signed with other keys are rejected
+	_, err = cli.WithServiceToken(issue(map[string]any{"skid": sk.SKID, "exp": time.Now().Add(-time.Hour).Unix()})).ServiceKeyCurrent(ctx)
+	assert.ErrorIs(t, err, driplimit.ErrUnauthorized)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	forged, err := jwttest.Sign(otherKey, "test", map[string]any{
+		"iss":  "https://issuer.test",
+		"aud":  "driplimit",
+		"skid": "sk_root",
+		"exp":  time.Now().Add(time.Minute).Unix(),
+	})
+	assert.NoError(t, err)
+	_, err = cli.WithServiceToken(forged).ServiceKeyCurrent(ctx)
+	assert.ErrorIs(t, err, driplimit.ErrUnauthorized)
+}
+
+func TestClientCertificates(t *testing.T) {
+	ctx := context.Background()
+	admin := cli.WithServiceToken("t0k3n")
+	dir := t.TempDir()
+
+	// the server and client certificates are issued by a CA generated for the test
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "driplimit test CA"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, caKey.Public(), caKey)
+	assert.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	assert.NoError(t, err)
+	issue := func(serial int64, template *x509.Certificate) tls.Certificate {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		assert.NoError(t, err)
+		template.SerialNumber = big.NewInt(serial)
+		template.NotBefore = time.Now().Add(-time.Minute)
+		template.NotAfter = time.Now().Add(time.Hour)
+		template.KeyUsage = x509.KeyUsageDigitalSignature
+		der, err := x509.CreateCertificate(rand.Reader, template, ca, key.Public(), caKey)
+		assert.NoError(t, err)
+		return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	}
+	writePEM := func(name, blockType string, der []byte) string {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+		return path
+	}
+
+	serverCert := issue(2, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "driplimit"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	serverKey, err := x509.MarshalPKCS8PrivateKey(serverCert.PrivateKey)
+	assert.NoError(t, err)
+
+	cfg, err := config.FromEnv(ctx)
+	assert.NoError(t, err)
+	cfg.TLSCertFile = writePEM("server.pem", "CERTIFICATE", serverCert.Certificate[0])
+	cfg.TLSKeyFile = writePEM("server-key.pem", "PRIVATE KEY", serverKey)
+	cfg.TLSClientCAFile = writePEM("ca.pem", "CERTIFICATE", caDER)
+
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+	tlsServer := api.New(cfg, service)
+	go tlsServer.Listener(ln)
+	defer tlsServer.ShutdownWithContext(ctx)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	tlsClient := func(certificates ...tls.Certificate) *client.HTTP {
+		return client.New("https://" + ln.Addr().String()).WithTLSConfig(&tls.Config{
+			RootCAs:      roots,
+			Certificates: certificates,
+		})
+	}
+
+	// bearer tokens still authenticate over TLS
+	_, err = tlsClient().WithServiceToken("t0k3n").ServiceKeyCurrent(ctx)
+	assert.NoError(t, err)
+
+	// a client certificate authenticates as the service key of its identity
+	sk, err := admin.ServiceKeyCreate(ctx, driplimit.ServiceKeyCreatePayload{
+		Description:    "internal billing service",
+		ClientIdentity: "billing.internal",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "billing.internal", sk.ClientIdentity)
+	billing := tlsClient(issue(3, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "billing"},
+		DNSNames:    []string{"billing.internal"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}))
+	current, err := billing.ServiceKeyCurrent(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, sk.SKID, current.SKID)
+
+	// client identities are unique
+	_, err = admin.ServiceKeyCreate(ctx, driplimit.ServiceKeyCreatePayload{
+		Description:    "duplicate billing service",
+		ClientIdentity: "billing.internal",
+	})
+	assert.ErrorIs(t, err, driplimit.ErrAlreadyExists)
+
+	// unknown identities and missing certificates are rejected
+	_, err = tlsClient(issue(4, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "unknown.internal"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})).ServiceKeyCurrent(ctx)
+	assert.ErrorIs(t, err, driplimit.ErrUnauthorized)
+	_, err = tlsClient().ServiceKeyCurrent(ctx)
+	assert.ErrorIs(t, err, driplimit.ErrUnauthorized)
+
+	// credentials cannot be forged with a bearer token
+	_, err = cli.WithServiceToken(driplimit.NewClientCertificateCredential([]string{"billing.internal"})).ServiceKeyCurrent(ctx)
+	assert.ErrorIs(t, err, driplimit.ErrUnauthorized)
+
+	// removing the client identity revokes the certificate
+	identity := ""
+	_, err = admin.ServiceKeyUpdate(ctx, driplimit.ServiceKeyUpdatePayload{SKID: sk.SKID, ClientIdentity: &identity})
+	assert.NoError(t, err)
+	_, err = billing.ServiceKeyCurrent(ctx)
+	assert.ErrorIs(t, err, driplimit.ErrUnauthorized)
+}
+
+func TestAllowedCIDRs(t *testing.T) {
+	ctx := context.Background()
+	admin := cli.WithServiceToken("t0k3n")
+
+	// invalid networks are rejected
+	_, err := admin.ServiceKeyCreate(ctx, driplimit.ServiceKeyCreatePayload{
+		Description:  "invalid networks service key",
+		AllowedCIDRs: []string{"10.0.0.1"},
+	})
+	assert.ErrorIs(t, err, driplimit.ErrInvalidPayload)
+
+	sk, err := admin.ServiceKeyCreate(ctx, driplimit.ServiceKeyCreatePayload{
+		Description:  "internal network service key",
+		AllowedCIDRs: []string{"10.0.0.0/8"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.0/8"}, sk.AllowedCIDRs)
+
+	// test requests come from 0.0.0.0, trusted as a proxy setting the X-Forwarded-For header
+	cfg, err := config.FromEnv(ctx)
+	assert.NoError(t, err)
+	cfg.TrustedProxies = []string{"0.0.0.0"}
+	proxied := api.New(cfg, service)
+	forwardedFor := func(addrs string) *client.HTTP {
+		return client.New("http://localhost.test").WithServiceToken(sk.Token).WithSendRequestFunc(func(req *http.Request) (*http.Response, error) {
+			req.Header.Set("X-Forwarded-For", addrs)
+			return proxied.Test(req)
+		})
+	}
+
+	_, err = forwardedFor("10.1.2.3").ServiceKeyCurrent(ctx)
+	assert.NoError(t, err)
+	_, err = forwardedFor("203.0.113.7").ServiceKeyCurrent(ctx)
+	assert.ErrorIs(t, err, driplimit.ErrUnauthorized)
+	// only the addresses appended by trusted proxies are used
+	_, err = forwardedFor("10.1.2.3, 203.0.113.7").ServiceKeyCurrent(ctx)
+	assert.ErrorIs(t, err, driplimit.ErrUnauthorized)
+	_, err = forwardedFor("203.0.113.7, 10.1.2.3, 0.0.0.0").ServiceKeyCurrent(ctx)
+	assert.NoError(t, err)
+
+	// the header is ignored when the request does not come from a trusted proxy
+	_, err = cli.WithServiceToken(sk.Token).WithSendRequestFunc(func(req *http.Request) (*http.Response, error) {
+		req.Header.Set("X-Forwarded-For", "10.1.2.3")
+		return server.Test(req)
+	}).ServiceKeyCurrent(ctx)
+	assert.ErrorIs(t, err, driplimit.ErrUnauthorized)
+
+	// removing the allowed networks lifts the restriction
+	_, err = admin.ServiceKeyUpdate(ctx, driplimit.ServiceKeyUpdatePayload{SKID: sk.SKID, AllowedCIDRs: []string{}})
+	assert.NoError(t, err)
+	current, err := cli.WithServiceToken(sk.Token).ServiceKeyCurrent(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, current.AllowedCIDRs)
+}
+
+func TestAPIRatelimits(t *testing.T) {
+	ctx := context.Background()
+	admin := cli.WithServiceToken("t0k3n")
+
+	ks, err := admin.KeyspaceCreate(ctx, driplimit.KeyspaceCreatePayload{Name: "api_ratelimits", KeysPrefix: "arl_"})
+	assert.NoError(t, err)
+	k, err := admin.KeyCreate(ctx, driplimit.KeyCreatePayload{KSID: ks.KSID, ExpiresIn: driplimit.Milliseconds{Duration: time.Hour}})
+	assert.NoError(t, err)
+	sk, err := admin.ServiceKeyCreate(ctx, driplimit.ServiceKeyCreatePayload{
+		Description:       "rate limited service key",
+		KeyspacesPolicies: driplimit.Policies{ks.KSID: {Check: true, Read: true}},
+		APIRatelimit:      driplimit.RatelimitPayload{Limit: 2, RefillRate: 1, RefillInterval: driplimit.Milliseconds{Duration: time.Hour}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), sk.APIRatelimit.Limit)
+	check := driplimit.KeysCheckPayload{KSID: ks.KSID, Token: k.Token}
+
+	// check calls share the API rate limit by default
+	limited := cli.WithServiceToken(sk.Token)
+	_, err = limited.ServiceKeyCurrent(ctx)
+	assert.NoError(t, err)
+	_, err = limited.KeyCheck(ctx, check)
+	assert.NoError(t, err)
+	_, err = limited.KeyList(ctx, driplimit.KeyListPayload{KSID: ks.KSID})
+	assert.ErrorIs(t, err, driplimit.ErrServiceKeyRateLimitExceeded)
+	_, err = limited.KeyCheck(ctx, check)
+	assert.ErrorIs(t, err, driplimit.ErrServiceKeyRateLimitExceeded)
+	// callers can tell their own rate limit from the rate limit of the checked key
+	assert.NotErrorIs(t, err, driplimit.ErrRateLimitExceeded)
+	rlks, err := admin.KeyspaceCreate(ctx, driplimit.KeyspaceCreatePayload{
+		Name:       "api_ratelimits_keys",
+		KeysPrefix: "arlk_",
+		Ratelimit:  driplimit.RatelimitPayload{Limit: 1, RefillRate: 1, RefillInterval: driplimit.Milliseconds{Duration: time.Hour}},
+	})
+	assert.NoError(t, err)
+	rlk, err := admin.KeyCreate(ctx, driplimit.KeyCreatePayload{KSID: rlks.KSID, ExpiresIn: driplimit.Milliseconds{Duration: time.Hour}})
+	assert.NoError(t, err)
+	_, err = admin.KeyCheck(ctx, driplimit.KeysCheckPayload{KSID: rlks.KSID, Token: rlk.Token})
+	assert.NoError(t, err)
+	_, err = admin.KeyCheck(ctx, driplimit.KeysCheckPayload{KSID: rlks.KSID, Token: rlk.Token})
+	assert.ErrorIs(t, err, driplimit.ErrRateLimitExceeded)
+	assert.NotErrorIs(t, err, driplimit.ErrServiceKeyRateLimitExceeded)
+
+	// check calls can be exempted, or have their own rate limit
+	cfg, err := config.FromEnv(ctx)
+	assert.NoError(t, err)
+	cfg.ChecksRatelimitExempt = true
+	exempting := api.New(cfg, service)
+	limited = cli.WithServiceToken(sk.Token).WithSendRequestFunc(exempting.Test)
+	_, err = limited.ServiceKeyCurrent(ctx)
+	assert.NoError(t, err)
+	_, err = limited.ServiceKeyCurrent(ctx)
+	assert.NoError(t, err)
+	_, err = limited.ServiceKeyCurrent(ctx)
+	assert.ErrorIs(t, err, driplimit.ErrServiceKeyRateLimitExceeded)
+	for i := 0; i < 3; i++ {
+		_, err = limited.KeyCheck(ctx, check)
+		assert.NoError(t, err)
+	}
+
+	_, err = admin.ServiceKeyUpdate(ctx, driplimit.ServiceKeyUpdatePayload{
+		SKID:            sk.SKID,
+		ChecksRatelimit: &driplimit.RatelimitPayload{Limit: 1, RefillRate: 1, RefillInterval: driplimit.Milliseconds{Duration: time.Hour}},
+	})
+	assert.NoError(t, err)
+	_, err = limited.KeyCheck(ctx, check)
+	assert.NoError(t, err)
+	_, err = limited.KeyCheck(ctx, check)
+	assert.ErrorIs(t, err, driplimit.ErrServiceKeyRateLimitExceeded)
+
+	// batch checks cost as many calls as they hold checks
+	batcher, err := admin.ServiceKeyCreate(ctx, driplimit.ServiceKeyCreatePayload{
+		Description:       "batch checks service key",
+		KeyspacesPolicies: driplimit.Policies{ks.KSID: {Check: true}},
+		ChecksRatelimit:   driplimit.RatelimitPayload{Limit: 3, RefillRate: 1, RefillInterval: driplimit.Milliseconds{Duration: time.Hour}},
+	})
+	assert.NoError(t, err)
+	batching := cli.WithServiceToken(batcher.Token).WithSendRequestFunc(exempting.Test)
+	batch := driplimit.KeysCheckBatchPayload{Checks: []driplimit.KeysCheckPayload{check, check}}
+	_, err = batching.KeyCheckBatch(ctx, batch)
+	assert.NoError(t, err)
+	_, err = batching.KeyCheckBatch(ctx, batch)
+	assert.ErrorIs(t, err, driplimit.ErrServiceKeyRateLimitExceeded)
+	_, err = batching.KeyCheck(ctx, check)
+	assert.NoError(t, err)
+
+	// a zero limit restores the server default, disabled here
+	_, err = admin.ServiceKeyUpdate(ctx, driplimit.ServiceKeyUpdatePayload{
+		SKID:         sk.SKID,
+		APIRatelimit: &driplimit.RatelimitPayload{},
+	})
+	assert.NoError(t, err)
+	current, err := limited.ServiceKeyCurrent(ctx)
+	assert.NoError(t, err)
+	assert.Nil(t, current.APIRatelimit)
+}
+
+func TestServiceKeysUsage(t *testing.T) {
+	ctx := context.Background()
+	admin := cli.WithServiceToken("t0k3n")
+
+	sk, err := admin.ServiceKeyCreate(ctx, driplimit.ServiceKeyCreatePayload{Description: "usage service key"})
+	assert.NoError(t, err)
+	assert.True(t, sk.LastUsedAt.IsZero())
+	unused := func(since time.Time) bool {
+		list, err := admin.ServiceKeyList(ctx, driplimit.ServiceKeyListPayload{List: driplimit.ListPayload{Limit: 100}, UnusedSince: since})
+		assert.NoError(t, err)
+		for _, listed := range list.ServiceKeys {
+			if listed.SKID == sk.SKID {
+				return true
+			}
+		}
+		return false
+	}
+
+	// never used service keys are unused since any time
+	assert.True(t, unused(time.Now()))
+
+	// usages are recorded in batch
+	usedAt := time.Now()
+	_, err = cli.WithServiceToken(sk.Token).ServiceKeyCurrent(ctx)
+	assert.NoError(t, err)
+	got, err := admin.ServiceKeyGet(ctx, driplimit.ServiceKeyGetPayload{SKID: sk.SKID})
+	assert.NoError(t, err)
+	assert.True(t, got.LastUsedAt.IsZero())
+
+	assert.NoError(t, usage.Flush(ctx))
+	got, err = admin.ServiceKeyGet(ctx, driplimit.ServiceKeyGetPayload{SKID: sk.SKID})
+	assert.NoError(t, err)
+	assert.False(t, got.LastUsedAt.Before(usedAt))
+	assert.Equal(t, "0.0.0.0", got.LastUsedAddr)
+	assert.False(t, unused(usedAt))
+	assert.True(t, unused(time.Now().Add(time.Minute)))
+}
+
+func TestServiceKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	admin := cli.WithServiceToken("t0k3n")
+
+	sk, err := admin.ServiceKeyCreate(ctx, driplimit.ServiceKeyCreatePayload{Description: "rotated service key"})
+	assert.NoError(t, err)
+	assert.False(t, sk.TokenCreatedAt.IsZero())
+	assert.True(t, sk.SecondaryTokenCreatedAt.IsZero())
+	other, err := admin.ServiceKeyCreate(ctx, driplimit.ServiceKeyCreatePayload{Description: "other service key"})
+	assert.NoError(t, err)
+
+	// service keys rotate their own token only
+	_, err = cli.WithServiceToken(other.Token).ServiceKeyRotate(ctx, driplimit.ServiceKeyRotatePayload{SKID: sk.SKID})
+	assert.ErrorIs(t, err, driplimit.ErrUnauthorized)
+
+	// both tokens authenticate during the rotation
+	rotated, err := cli.WithServiceToken(sk.Token).ServiceKeyRotate(ctx, driplimit.ServiceKeyRotatePayload{SKID: sk.SKID})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, rotated.SecondaryToken)
+	assert.False(t, rotated.SecondaryTokenCreatedAt.IsZero())
+	for _, token := range []string{sk.Token, rotated.SecondaryToken} {
+		current, err := cli.WithServiceToken(token).ServiceKeyCurrent(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, sk.SKID, current.SKID)
+		assert.Empty(t, current.SecondaryToken)
+	}
+	_, err = admin.ServiceKeyRotate(ctx, driplimit.ServiceKeyRotatePayload{SKID: sk.SKID})
+	assert.ErrorIs(t, err, driplimit.ErrAlreadyExists)
+
+	// the former token still authenticates once the secondary token is promoted
+	promoted, err := cli.WithServiceToken(rotated.SecondaryToken).ServiceKeyPromote(ctx, driplimit.ServiceKeyPromotePayload{SKID: sk.SKID})
+	assert.NoError(t, err)
+	assert.True(t, rotated.SecondaryTokenCreatedAt.Equal(promoted.TokenCreatedAt))
+	assert.True(t, rotated.TokenCreatedAt.Equal(promoted.SecondaryTokenCreatedAt))
+	_, err = cli.WithServiceToken(sk.Token).ServiceKeyCurrent(ctx)
+	assert.NoError(t, err)
+
+	// until it is revoked
+	revoked, err := cli.WithServiceToken(rotated.SecondaryToken).ServiceKeyRevokePrevious(ctx, driplimit.ServiceKeyRevokePreviousPayload{SKID: sk.SKID})
+	assert.NoError(t, err)
+	assert.True(t, revoked.SecondaryTokenCreatedAt.IsZero())
+	_, err = cli.WithServiceToken(sk.Token).ServiceKeyCurrent(ctx)
+	assert.ErrorIs(t, err, driplimit.ErrUnauthorized)
+	_, err = cli.WithServiceToken(rotated.SecondaryToken).ServiceKeyCurrent(ctx)
+	assert.NoError(t, err)
+	_, err = admin.ServiceKeyRevokePrevious(ctx, driplimit.ServiceKeyRevokePreviousPayload{SKID: sk.SKID})
+	assert.ErrorIs(t, err, driplimit.ErrNotFound)
+}
+
+// newProxy returns a driplimit server in proxy mode in front of upstream, configured with
+// the additional env.
+func newProxy(t *testing.T, upstream *api.Server, env string) *api.Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	cfg, err := config.FromEnvFile(ctx, strings.NewReader("MODE=proxy\nUPSTREAM_URL=http://upstream.test\n"+env))
+	assert.NoError(t, err)
+	return api.New(cfg, driplimit.NewServiceValidator(
+		proxycache.New(ctx, cfg, client.New(cfg.UpstreamURL).WithSendRequestFunc(upstream.Test)),
+	))
+}
+
+func TestProxyMode(t *testing.T) {
+	ctx := context.Background()
+	admin := cli.WithServiceToken("t0k3n")
+	proxy := client.New("http://proxy.test").WithSendRequestFunc(newProxy(t, server, "").Test)
+
+	ks, err := admin.KeyspaceCreate(ctx, driplimit.KeyspaceCreatePayload{Name: "proxied", KeysPrefix: "prx_"})
+	assert.NoError(t, err)
+	k, err := admin.KeyCreate(ctx, driplimit.KeyCreatePayload{KSID: ks.KSID, ExpiresIn: driplimit.Milliseconds{Duration: time.Hour}})
+	assert.NoError(t, err)
+	sk, err := admin.ServiceKeyCreate(ctx, driplimit.ServiceKeyCreatePayload{
+		Description:       "proxied service key",
+		KeyspacesPolicies: driplimit.Policies{ks.KSID: {Check: true}},
+	})
+	assert.NoError(t, err)
+
+	// service key tokens are resolved upstream
+	current, err := proxy.WithServiceToken(sk.Token).ServiceKeyCurrent(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, sk.SKID, current.SKID)
+	_, err = proxy.WithServiceToken(sk.Token).KeyCheck(ctx, driplimit.KeysCheckPayload{KSID: ks.KSID, Token: k.Token})
+	assert.NoError(t, err)
+
+	// signatures cannot be verified upstream, signed requests are rejected by proxies
+	_, err = proxy.WithSigningSecret(sk.SKID, sk.SigningSecret).ServiceKeyCurrent(ctx)
+	assert.ErrorIs(t, err, driplimit.ErrUnauthorized)
+	_, err = cli.WithSigningSecret(sk.SKID, sk.SigningSecret).ServiceKeyCurrent(ctx)
+	assert.NoError(t, err)
+
+	// keys deleted through the proxy are no longer checked from its cache
+	_, err = proxy.WithServiceToken("t0k3n").KeyDeleteMany(ctx, driplimit.KeyDeleteManyPayload{KSID: ks.KSID, KIDs: []string{k.KID}})
+	assert.NoError(t, err)
+	_, err = proxy.WithServiceToken(sk.Token).KeyCheck(ctx, driplimit.KeysCheckPayload{KSID: ks.KSID, Token: k.Token})
+	assert.ErrorIs(t, err, driplimit.ErrNotFound)
+}
+
+func TestProxyForwardedAddr(t *testing.T) {
+	ctx := context.Background()
+	admin := cli.WithServiceToken("t0k3n")
+	ks, err := admin.KeyspaceCreate(ctx, driplimit.KeyspaceCreatePayload{Name: "proxied_addrs", KeysPrefix: "prxa_"})
+	assert.NoError(t, err)
+	k, err := admin.KeyCreate(ctx, driplimit.KeyCreatePayload{KSID: ks.KSID, ExpiresIn: driplimit.Milliseconds{Duration: time.Hour}})
+	assert.NoError(t, err)
+	sk, err := admin.ServiceKeyCreate(ctx, driplimit.ServiceKeyCreatePayload{
+		Description:       "proxied internal network service key",
+		KeyspacesPolicies: driplimit.Policies{ks.KSID: {Check: true}},
+		AllowedCIDRs:      []string{"10.0.0.0/8"},
+	})
+	assert.NoError(t, err)
+
+	// test requests come from 0.0.0.0, trusted as a proxy by the upstream server and the proxy
+	cfg, err := config.FromEnv(ctx)
+	assert.NoError(t, err)
+	cfg.TrustedProxies = []string{"0.0.0.0"}
+	proxy := newProxy(t, api.New(cfg, service), "TRUSTED_PROXIES=0.0.0.0\n")
+	forwardedFor := func(addr string) *client.HTTP {
+		return client.New("http://proxy.test").WithServiceToken(sk.Token).WithSendRequestFunc(func(req *http.Request) (*http.Response, error) {
+			req.Header.Set("X-Forwarded-For", addr)
+			return proxy.Test(req)
+		})
+	}
+
+	// the address of the client is checked upstream rather than the address of the proxy
+	_, err = forwardedFor("10.1.2.3").KeyCheck(ctx, driplimit.KeysCheckPayload{KSID: ks.KSID, Token: k.Token})
+	assert.NoError(t, err)
+	_, err = forwardedFor("203.0.113.7").KeyCheck(ctx, driplimit.KeysCheckPayload{KSID: ks.KSID, Token: k.Token})
+	assert.ErrorIs(t, err, driplimit.ErrUnauthorized)
+
+	// and recorded as the last used address
+	assert.NoError(t, usage.Flush(ctx))
+	got, err := admin.ServiceKeyGet(ctx, driplimit.ServiceKeyGetPayload{SKID: sk.SKID})
+	assert.NoError(t, err)
+	assert.Equal(t, "10.1.2.3", got.LastUsedAddr)
 }
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/i4n-co/driplimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (api *Server) auditList() *rpc {
+	return &rpc{
+		Namespace: "audit",
+		Action:    "list",
+		Documentation: RPCDocumentation{
+			Description: "List the audit log of the mutating calls, most recent first (requires an admin service key)",
+			Parameters: driplimit.AuditListPayload{
+				List: driplimit.ListPayload{
+					Page:  1,
+					Limit: 10,
+				},
+				Action: "keyspaces.delete",
+				Result: driplimit.AuditSuccess,
+			},
+			Response: driplimit.AuditList{
+				List: driplimit.ListMetadata{
+					Page:     1,
+					Limit:    10,
+					LastPage: 1,
+				},
+				Entries: []*driplimit.AuditEntry{
+					{
+						AEID:      "ae_xyz",
+						SKID:      "sk_root",
+						Action:    "keyspaces.delete",
+						TargetIDs: []string{"ks_xyz"},
+						Payload:   json.RawMessage(`{"ksid":"ks_xyz"}`),
+						Result:    driplimit.AuditSuccess,
+						CreatedAt: time.Now(),
+					},
+				},
+			},
+		},
+		Handler: func(c *fiber.Ctx) (err error) {
+			payload := new(driplimit.AuditListPayload)
+			if err := c.BodyParser(payload); err != nil {
+				return err
+			}
+			alist, err := api.service.AuditList(c.Context(), *payload.WithServiceToken(token(c)))
+			if err != nil {
+				return err
+			}
+			return c.JSON(alist)
+		},
+	}
+}
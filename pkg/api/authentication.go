@@ -1,25 +1,207 @@
 package api
 
 import (
+	"errors"
+	"log/slog"
+	"net"
 	"strings"
+	"sync"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/hashicorp/golang-lru/v2/expirable"
 	"github.com/i4n-co/driplimit"
 )
 
-// authenticate is a middleware that checks the presence of an Bearer token
-func authenticate() func(c *fiber.Ctx) error {
+// errSignedRequestsUnsupported is returned by proxies to signed requests: the upstream server
+// only accepts signatures of the requests it receives, not of the requests the proxy received.
+var errSignedRequestsUnsupported = fiber.NewError(fiber.StatusUnauthorized, "signed requests are only supported by authoritative servers")
+
+// authenticate is a middleware that checks the presence of an Bearer token, of an HMAC
+// request signature (see driplimit.SignatureScheme) or of a verified TLS client certificate.
+// Bearer tokens are either service key tokens or JWTs, both resolved to their service key by
+// the authoritative service. Signatures are verified with the service key signing secret by the
+// authoritative service, then checked against replays (see preventReplays), so proxies reject
+// them. Client
+// certificates are only used when the request has no Authorization header, and never by
+// proxies for the same reason (see config.Config validation).
+func authenticate(proxy bool) func(c *fiber.Ctx) error {
 	return func(c *fiber.Ctx) error {
 		auth := c.Get("Authorization")
 		if auth == "" {
+			if proxy {
+				return driplimit.ErrUnauthorized
+			}
+			identities := clientCertificateIdentities(c)
+			if len(identities) == 0 {
+				return driplimit.ErrUnauthorized
+			}
+			c.Locals("token", driplimit.NewClientCertificateCredential(identities))
+			return c.Next()
+		}
+
+		if driplimit.IsSignatureCredential(auth) {
+			if proxy {
+				return errSignedRequestsUnsupported
+			}
+			credential, err := signatureCredential(c, auth)
+			if err != nil {
+				return err
+			}
+			c.Locals("token", credential)
+			return c.Next()
+		}
+
+		token := strings.TrimPrefix(auth, "Bearer ")
+		// credentials are bound to the request they were derived from
+		if driplimit.IsSignatureCredential(token) || driplimit.IsClientCertificateCredential(token) {
+			return driplimit.ErrUnauthorized
+		}
+		c.Locals("token", token)
+		return c.Next()
+	}
+}
+
+// signatureCredential returns the credential of the signed request. Signatures out of the
+// allowed window are rejected.
+func signatureCredential(c *fiber.Ctx, auth string) (string, error) {
+	parsed, err := driplimit.ParseRequestSignature(auth)
+	if err != nil {
+		return "", err
+	}
+	// the signed attributes always come from the request itself
+	signature := driplimit.NewRequestSignature(parsed.SKID, c.Method(), c.Path(), c.Body(), parsed.Timestamp, parsed.Nonce)
+	signature.Signature = parsed.Signature
+	if signature.Stale() {
+		return "", driplimit.ErrUnauthorized
+	}
+	return signature.Credential(), nil
+}
+
+// preventReplays is a middleware that rejects the signed requests whose nonce was already seen.
+// It runs once the signature was verified (see restrictAddrs), so that forged signatures cannot
+// fill the nonces cache and evict the nonces of genuine requests.
+func preventReplays(nonces *nonceCache) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		if !driplimit.IsSignatureCredential(token(c)) {
+			return c.Next()
+		}
+		signature, err := driplimit.ParseRequestSignature(token(c))
+		if err != nil {
+			return err
+		}
+		if nonces.seen(signature.SKID + ":" + signature.Nonce) {
+			return driplimit.ErrUnauthorized
+		}
+		return c.Next()
+	}
+}
+
+// clientCertificateIdentities returns the subject common name and the SANs of the verified
+// client certificate, if any.
+func clientCertificateIdentities(c *fiber.Ctx) []string {
+	state := c.Context().TLSConnectionState()
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	cert := state.VerifiedChains[0][0]
+	identities := make([]string, 0, 1+len(cert.DNSNames)+len(cert.URIs)+len(cert.EmailAddresses))
+	if cert.Subject.CommonName != "" {
+		identities = append(identities, cert.Subject.CommonName)
+	}
+	identities = append(identities, cert.DNSNames...)
+	for _, uri := range cert.URIs {
+		identities = append(identities, uri.String())
+	}
+	identities = append(identities, cert.EmailAddresses...)
+	return identities
+}
+
+// restrictAddrs is a middleware that rejects the requests of service keys used from an address
+// out of their allowed CIDRs. The rejection is logged apart from invalid tokens but the client
+// only gets an unauthorized error. The resolved service key is kept in the service_key local,
+// and by the service in the caller of the request context so that it is resolved only once.
+func restrictAddrs(service driplimit.Service, trustedProxies []*net.IPNet, logger *slog.Logger) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		addr := remoteAddr(c, trustedProxies)
+		c.Context().SetUserValue(driplimit.RemoteAddrKey, addr.String())
+		c.Context().SetUserValue(driplimit.CallerKey, new(driplimit.Caller))
+		current := driplimit.ServiceKeyGetPayload{Token: token(c)}
+		sk, err := service.ServiceKeyGet(c.Context(), *current.WithServiceToken(token(c)))
+		if err != nil {
+			if errors.Is(err, driplimit.ErrNotFound) || errors.Is(err, driplimit.ErrUnauthorized) {
+				logger.Warn("authentication failed", "reason", "invalid service token", "addr", addr.String())
+				return driplimit.ErrUnauthorized
+			}
+			return err
+		}
+		if !sk.AllowsAddr(addr) {
+			logger.Warn("authentication failed", "reason", "address not allowed", "skid", sk.SKID, "addr", addr.String())
 			return driplimit.ErrUnauthorized
 		}
-	
-		c.Locals("token", strings.TrimPrefix(auth, "Bearer "))
+		c.Locals("service_key", sk)
 		return c.Next()
 	}
 }
 
+// remoteAddr returns the address of the client. The X-Forwarded-For header is only used when
+// the request comes from a trusted proxy: its addresses are read from the right and the first
+// one that is not a trusted proxy is the client address.
+func remoteAddr(c *fiber.Ctx, trustedProxies []*net.IPNet) net.IP {
+	addr := c.Context().RemoteIP()
+	if !trusted(addr, trustedProxies) {
+		return addr
+	}
+	forwarded := make([]string, 0, 1)
+	for _, header := range c.Request().Header.PeekAll(fiber.HeaderXForwardedFor) {
+		forwarded = append(forwarded, strings.Split(string(header), ",")...)
+	}
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if ip == nil {
+			// the header was not set by a trusted proxy from here
+			return addr
+		}
+		addr = ip
+		if !trusted(addr, trustedProxies) {
+			return addr
+		}
+	}
+	return addr
+}
+
+func trusted(addr net.IP, trustedProxies []*net.IPNet) bool {
+	for _, network := range trustedProxies {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// nonceCache remembers the nonces of the verified signed requests as long as their signatures
+// are valid.
+type nonceCache struct {
+	mu     sync.Mutex
+	nonces *expirable.LRU[string, struct{}]
+}
+
+func newNonceCache(size int) *nonceCache {
+	return &nonceCache{
+		nonces: expirable.NewLRU[string, struct{}](size, nil, 2*driplimit.SignatureMaxSkew),
+	}
+}
+
+// seen records the nonce and returns true if it was already recorded.
+func (n *nonceCache) seen(nonce string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.nonces.Contains(nonce) {
+		return true
+	}
+	n.nonces.Add(nonce, struct{}{})
+	return false
+}
+
 // token returns the Bearer token from the context
 func token(c *fiber.Ctx) string {
 	return c.Locals("token").(string)
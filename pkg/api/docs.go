@@ -72,6 +72,9 @@ func documentStruct(strct any, docfields *[]RPCDocumentationField) {
 	if t == nil {
 		return
 	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
 	for i := 0; i < t.NumField(); i++ {
 		docfield := documentField(t.Field(i))
 		if docfield.Name == "-" {
@@ -144,6 +147,9 @@ func documentField(field reflect.StructField) RPCDocumentationField {
 }
 
 func docFieldType(field reflect.Type) (fieldType string) {
+	if field.Kind() == reflect.Pointer {
+		return docFieldType(field.Elem())
+	}
 	if field.String() == "time.Time" {
 		return "timestamp"
 	}
@@ -162,5 +168,8 @@ func docFieldType(field reflect.Type) (fieldType string) {
 	if field.Kind() == reflect.Map {
 		return "map"
 	}
+	if field.Kind() == reflect.String {
+		return "string"
+	}
 	return fmt.Sprintf("%v", field)
 }
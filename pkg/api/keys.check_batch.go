@@ -0,0 +1,71 @@
+package api
+
+import (
+	"time"
+
+	"github.com/i4n-co/driplimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (api *Server) keysCheckBatch() *rpc {
+	return &rpc{
+		Namespace: "keys",
+		Action:    "check_batch",
+		Documentation: RPCDocumentation{
+			Description: "Check multiple keys at once. Each check gets its own result and error code, and counts as a call against the service key rate limits",
+			Parameters: driplimit.KeysCheckBatchPayload{
+				Checks: []driplimit.KeysCheckPayload{
+					{
+						KSID:  "ks_abc",
+						Token: "demo_xxxxxxxxxxxxxxxxxxxxxxxxxxxxxx",
+					},
+					{
+						KSID:  "ks_abc",
+						Token: "demo_yyyyyyyyyyyyyyyyyyyyyyyyyyyyyy",
+					},
+				},
+			},
+			Response: driplimit.KeysCheckBatch{
+				Results: []driplimit.KeysCheckBatchResult{
+					{
+						Key: &driplimit.Key{
+							KID:       "k_xyz",
+							KSID:      "ks_abc",
+							CreatedAt: time.Now(),
+							ExpiresAt: time.Now().Add(time.Minute * 5),
+							Ratelimit: &driplimit.Ratelimit{
+								State: &driplimit.RatelimitState{
+									LastRefilled: time.Now(),
+									Remaining:    4,
+								},
+								Limit:          5,
+								RefillRate:     1,
+								RefillInterval: driplimit.Milliseconds{Duration: time.Second},
+							},
+						},
+						Code: 200,
+					},
+					driplimit.NewKeysCheckBatchResult(nil, driplimit.ErrRateLimitExceeded),
+				},
+			},
+		},
+		Handler: func(c *fiber.Ctx) (err error) {
+			payload := new(driplimit.KeysCheckBatchPayload)
+			if err := c.BodyParser(payload); err != nil {
+				return err
+			}
+
+			batch, err := api.service.KeyCheckBatch(c.Context(), *payload.WithServiceToken(token(c)))
+			if err != nil {
+				return err
+			}
+			for _, result := range batch.Results {
+				if result.Code == fiber.StatusInternalServerError {
+					api.logger.Error("internal server error", "err", result.Err())
+				}
+			}
+			return c.JSON(batch)
+		},
+	}
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"time"
+
+	"github.com/i4n-co/driplimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (api *Server) keysDeleteMany() *rpc {
+	return &rpc{
+		Namespace: "keys",
+		Action:    "delete_many",
+		Documentation: RPCDocumentation{
+			Description: "Delete multiple keys from a keyspace by ids and/or expiration. Filters are combined",
+			Parameters: driplimit.KeyDeleteManyPayload{
+				KSID:          "ks_abc",
+				KIDs:          []string{"k_xyz", "k_uvw"},
+				ExpiredBefore: time.Now().Add(-time.Hour * 24 * 30),
+				DryRun:        true,
+			},
+			Response: driplimit.KeyDeleteManyResult{
+				Deleted: 2,
+				DryRun:  true,
+			},
+		},
+		Handler: func(c *fiber.Ctx) (err error) {
+			payload := new(driplimit.KeyDeleteManyPayload)
+			if err := c.BodyParser(payload); err != nil {
+				return err
+			}
+
+			result, err := api.service.KeyDeleteMany(c.Context(), *payload.WithServiceToken(token(c)))
+			if err != nil {
+				return err
+			}
+			return c.JSON(result)
+		},
+	}
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"time"
+
+	"github.com/i4n-co/driplimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (api *Server) keysMove() *rpc {
+	return &rpc{
+		Namespace: "keys",
+		Action:    "move",
+		Documentation: RPCDocumentation{
+			Description: "Move a key to another keyspace. The token is returned only if a new one has been generated",
+			Parameters: driplimit.KeyMovePayload{
+				KSID:       "ks_abc",
+				KID:        "k_xyz",
+				TargetKSID: "ks_def",
+				KeepToken:  false,
+			},
+			Response: driplimit.Key{
+				KID:       "k_xyz",
+				KSID:      "ks_def",
+				Token:     "premium_xxxxxxxxxxxxxxxxxxxxxxxxxxxxxx",
+				CreatedAt: time.Now(),
+				ExpiresAt: time.Now().Add(time.Minute * 5),
+				Ratelimit: &driplimit.Ratelimit{
+					State: &driplimit.RatelimitState{
+						LastRefilled: time.Now(),
+						Remaining:    1000,
+					},
+					Limit:          1000,
+					RefillRate:     10,
+					RefillInterval: driplimit.Milliseconds{Duration: time.Second},
+				},
+			},
+		},
+		Handler: func(c *fiber.Ctx) (err error) {
+			payload := new(driplimit.KeyMovePayload)
+			if err := c.BodyParser(payload); err != nil {
+				return err
+			}
+
+			key, err := api.service.KeyMove(c.Context(), *payload.WithServiceToken(token(c)))
+			if err != nil {
+				return err
+			}
+			return c.JSON(key)
+		},
+	}
+}
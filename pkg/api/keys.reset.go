@@ -0,0 +1,52 @@
+package api
+
+import (
+	"time"
+
+	"github.com/i4n-co/driplimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (api *Server) keysReset() *rpc {
+	remaining := int64(5)
+	return &rpc{
+		Namespace: "keys",
+		Action:    "reset",
+		Documentation: RPCDocumentation{
+			Description: "Reset the rate limit state of a key. Remaining is set to the rate limit if not provided",
+			Parameters: driplimit.KeyResetPayload{
+				KSID:      "ks_abc",
+				KID:       "k_xyz",
+				Remaining: &remaining,
+			},
+			Response: driplimit.Key{
+				KID:       "k_xyz",
+				KSID:      "ks_abc",
+				CreatedAt: time.Now(),
+				ExpiresAt: time.Now().Add(time.Minute * 5),
+				Ratelimit: &driplimit.Ratelimit{
+					State: &driplimit.RatelimitState{
+						LastRefilled: time.Now(),
+						Remaining:    5,
+					},
+					Limit:          5,
+					RefillRate:     1,
+					RefillInterval: driplimit.Milliseconds{Duration: time.Second},
+				},
+			},
+		},
+		Handler: func(c *fiber.Ctx) (err error) {
+			payload := new(driplimit.KeyResetPayload)
+			if err := c.BodyParser(payload); err != nil {
+				return err
+			}
+
+			key, err := api.service.KeyReset(c.Context(), *payload.WithServiceToken(token(c)))
+			if err != nil {
+				return err
+			}
+			return c.JSON(key)
+		},
+	}
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"time"
+
+	"github.com/i4n-co/driplimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (api *Server) keyspacesClone() *rpc {
+	return &rpc{
+		Namespace: "keyspaces",
+		Action:    "clone",
+		Documentation: RPCDocumentation{
+			Description: "Clone a keyspace with its configuration, plans and service keys policies, and optionally its keys (admin only)",
+			Parameters: driplimit.KeyspaceClonePayload{
+				KSID:       "ks_abc",
+				NameSuffix: " - staging",
+				KeysPrefix: "staging_",
+			},
+			Response: driplimit.Keyspace{
+				KSID:       "ks_def",
+				Name:       "demo.yourapi.com - staging",
+				KeysPrefix: "staging_",
+				Ratelimit: &driplimit.Ratelimit{
+					Limit:          100,
+					RefillRate:     1,
+					RefillInterval: driplimit.Milliseconds{Duration: time.Second},
+				},
+			},
+		},
+		Handler: func(c *fiber.Ctx) (err error) {
+			payload := new(driplimit.KeyspaceClonePayload)
+			if err := c.BodyParser(payload); err != nil {
+				return err
+			}
+			keyspace, err := api.service.KeyspaceClone(c.Context(), *payload.WithServiceToken(token(c)))
+			if err != nil {
+				return err
+			}
+			return c.JSON(keyspace)
+		},
+	}
+}
@@ -15,8 +15,9 @@ func (api *Server) keyspacesCreate() *rpc {
 		Documentation: RPCDocumentation{
 			Description: "Create a new keyspace",
 			Parameters: driplimit.KeyspaceCreatePayload{
-				Name:       "demo.yourapi.com (env: production)",
-				KeysPrefix: "demo_",
+				Name:        "demo.yourapi.com (env: production)",
+				KeysPrefix:  "demo_",
+				GracePeriod: driplimit.Milliseconds{Duration: time.Hour * 24},
 				Ratelimit: driplimit.RatelimitPayload{
 					Limit:          100,
 					RefillRate:     1,
@@ -24,9 +25,10 @@ func (api *Server) keyspacesCreate() *rpc {
 				},
 			},
 			Response: driplimit.Keyspace{
-				KSID:       "ks_abc",
-				Name:       "demo.yourapi.com (env: production)",
-				KeysPrefix: "demo_",
+				KSID:        "ks_abc",
+				Name:        "demo.yourapi.com (env: production)",
+				KeysPrefix:  "demo_",
+				GracePeriod: driplimit.Milliseconds{Duration: time.Hour * 24},
 				Ratelimit: &driplimit.Ratelimit{
 					Limit:          100,
 					RefillRate:     1,
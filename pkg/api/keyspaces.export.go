@@ -0,0 +1,63 @@
+package api
+
+import (
+	"time"
+
+	"github.com/i4n-co/driplimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (api *Server) keyspacesExport() *rpc {
+	return &rpc{
+		Namespace: "keyspaces",
+		Action:    "export",
+		Documentation: RPCDocumentation{
+			Description: "Export a keyspace with its plans, keys and policies as a versioned bundle (admin only)",
+			Parameters: driplimit.KeyspaceExportPayload{
+				KSID: "ks_abc",
+			},
+			Response: driplimit.KeyspaceBundle{
+				Version:    driplimit.KeyspaceBundleVersion,
+				ExportedAt: time.Now(),
+				Keyspace: &driplimit.Keyspace{
+					KSID:       "ks_abc",
+					Name:       "demo.yourapi.com (env: production)",
+					KeysPrefix: "demo_",
+				},
+				Plans: []*driplimit.Plan{},
+				Keys: []*driplimit.KeyspaceBundleKey{
+					{
+						KID:       "k_xyz",
+						TokenHash: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+						CreatedAt: time.Now(),
+						ExpiresAt: time.Now().Add(time.Hour),
+						Ratelimit: driplimit.RatelimitPayload{
+							Limit:          5,
+							RefillRate:     1,
+							RefillInterval: driplimit.Milliseconds{Duration: time.Second},
+						},
+						State: driplimit.RatelimitState{
+							LastRefilled: time.Now(),
+							Remaining:    5,
+						},
+					},
+				},
+				Policies: driplimit.Policies{
+					"sk_xyz": {Check: true, Read: true},
+				},
+			},
+		},
+		Handler: func(c *fiber.Ctx) (err error) {
+			payload := new(driplimit.KeyspaceExportPayload)
+			if err := c.BodyParser(payload); err != nil {
+				return err
+			}
+			bundle, err := api.service.KeyspaceExport(c.Context(), *payload.WithServiceToken(token(c)))
+			if err != nil {
+				return err
+			}
+			return c.JSON(bundle)
+		},
+	}
+}
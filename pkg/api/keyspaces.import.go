@@ -0,0 +1,47 @@
+package api
+
+import (
+	"github.com/i4n-co/driplimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (api *Server) keyspacesImport() *rpc {
+	return &rpc{
+		Namespace: "keyspaces",
+		Action:    "import",
+		Documentation: RPCDocumentation{
+			Description: "Import a keyspace bundle produced by keyspaces.export (admin only). Existing items are skipped, overwritten or make the import fail depending on the strategy, plans and keys of other keyspaces always make it fail",
+			Parameters: driplimit.KeyspaceImportPayload{
+				Bundle: driplimit.KeyspaceBundle{
+					Version: driplimit.KeyspaceBundleVersion,
+					Keyspace: &driplimit.Keyspace{
+						KSID:       "ks_abc",
+						Name:       "demo.yourapi.com (env: production)",
+						KeysPrefix: "demo_",
+					},
+				},
+				Strategy: driplimit.ImportSkip,
+			},
+			Response: driplimit.KeyspaceImportResult{
+				Keyspace: &driplimit.Keyspace{
+					KSID:       "ks_abc",
+					Name:       "demo.yourapi.com (env: production)",
+					KeysPrefix: "demo_",
+				},
+				Created: 1,
+			},
+		},
+		Handler: func(c *fiber.Ctx) (err error) {
+			payload := new(driplimit.KeyspaceImportPayload)
+			if err := c.BodyParser(payload); err != nil {
+				return err
+			}
+			result, err := api.service.KeyspaceImport(c.Context(), *payload.WithServiceToken(token(c)))
+			if err != nil {
+				return err
+			}
+			return c.JSON(result)
+		},
+	}
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"time"
+
+	"github.com/i4n-co/driplimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (api *Server) keyspacesStats() *rpc {
+	return &rpc{
+		Namespace: "keyspaces",
+		Action:    "stats",
+		Documentation: RPCDocumentation{
+			Description: "Get the key statistics of a keyspace",
+			Parameters: driplimit.KeyspaceStatsPayload{
+				KSID: "ks_abc",
+			},
+			Response: driplimit.KeyspaceStats{
+				KSID:                 "ks_abc",
+				ActiveKeys:           120,
+				ExpiredKeys:          8,
+				DeletedKeys:          14,
+				CustomRatelimitKeys:  30,
+				DefaultRatelimitKeys: 98,
+				UsedLast24h:          42,
+				UsedLast7d:           87,
+				ExhaustedKeys:        3,
+				ComputedAt:           time.Now(),
+			},
+		},
+		Handler: func(c *fiber.Ctx) (err error) {
+			payload := new(driplimit.KeyspaceStatsPayload)
+			if err := c.BodyParser(payload); err != nil {
+				return err
+			}
+			stats, err := api.service.KeyspaceStats(c.Context(), *payload.WithServiceToken(token(c)))
+			if err != nil {
+				return err
+			}
+			return c.JSON(stats)
+		},
+	}
+}
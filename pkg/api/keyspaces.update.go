@@ -0,0 +1,50 @@
+package api
+
+import (
+	"time"
+
+	"github.com/i4n-co/driplimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (api *Server) keyspacesUpdate() *rpc {
+	name := "demo.yourapi.com (env: production)"
+	return &rpc{
+		Namespace: "keyspaces",
+		Action:    "update",
+		Documentation: RPCDocumentation{
+			Description: "Update a keyspace. Only the provided fields are updated",
+			Parameters: driplimit.KeyspaceUpdatePayload{
+				KSID: "ks_abc",
+				Name: &name,
+				Ratelimit: &driplimit.RatelimitPayload{
+					Limit:          200,
+					RefillRate:     2,
+					RefillInterval: driplimit.Milliseconds{Duration: time.Second},
+				},
+			},
+			Response: driplimit.Keyspace{
+				KSID:       "ks_abc",
+				Name:       "demo.yourapi.com (env: production)",
+				KeysPrefix: "demo_",
+				Ratelimit: &driplimit.Ratelimit{
+					Limit:          200,
+					RefillRate:     2,
+					RefillInterval: driplimit.Milliseconds{Duration: time.Second},
+				},
+			},
+		},
+		Handler: func(c *fiber.Ctx) (err error) {
+			payload := new(driplimit.KeyspaceUpdatePayload)
+			if err := c.BodyParser(payload); err != nil {
+				return err
+			}
+			keyspace, err := api.service.KeyspaceUpdate(c.Context(), *payload.WithServiceToken(token(c)))
+			if err != nil {
+				return err
+			}
+			return c.JSON(keyspace)
+		},
+	}
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"time"
+
+	"github.com/i4n-co/driplimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (api *Server) plansCreate() *rpc {
+	return &rpc{
+		Namespace: "plans",
+		Action:    "create",
+		Documentation: RPCDocumentation{
+			Description: "Create a plan, a reusable rate limit configuration for the keys of a keyspace",
+			Parameters: driplimit.PlanCreatePayload{
+				KSID: "ks_abc",
+				Name: "premium",
+				Ratelimit: driplimit.RatelimitPayload{
+					Limit:          1000,
+					RefillRate:     10,
+					RefillInterval: driplimit.Milliseconds{Duration: time.Second},
+				},
+			},
+			Response: driplimit.Plan{
+				PLID: "pl_xyz",
+				KSID: "ks_abc",
+				Name: "premium",
+				Ratelimit: &driplimit.Ratelimit{
+					Limit:          1000,
+					RefillRate:     10,
+					RefillInterval: driplimit.Milliseconds{Duration: time.Second},
+				},
+			},
+		},
+		Handler: func(c *fiber.Ctx) (err error) {
+			payload := new(driplimit.PlanCreatePayload)
+			if err := c.BodyParser(payload); err != nil {
+				return err
+			}
+			plan, err := api.service.PlanCreate(c.Context(), *payload.WithServiceToken(token(c)))
+			if err != nil {
+				return err
+			}
+			return c.JSON(plan)
+		},
+	}
+}
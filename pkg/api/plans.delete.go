@@ -0,0 +1,33 @@
+package api
+
+import (
+	"github.com/i4n-co/driplimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (api *Server) plansDelete() *rpc {
+	return &rpc{
+		Namespace: "plans",
+		Action:    "delete",
+		Documentation: RPCDocumentation{
+			Description: "Delete a plan. Its keys fall back to the keyspace default rate limit",
+			Parameters: driplimit.PlanDeletePayload{
+				KSID: "ks_abc",
+				PLID: "pl_xyz",
+			},
+			Response: nil,
+		},
+		Handler: func(c *fiber.Ctx) (err error) {
+			payload := new(driplimit.PlanDeletePayload)
+			if err := c.BodyParser(payload); err != nil {
+				return err
+			}
+			err = api.service.PlanDelete(c.Context(), *payload.WithServiceToken(token(c)))
+			if err != nil {
+				return err
+			}
+			return c.SendStatus(fiber.StatusNoContent)
+		},
+	}
+}
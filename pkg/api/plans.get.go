@@ -0,0 +1,44 @@
+package api
+
+import (
+	"time"
+
+	"github.com/i4n-co/driplimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (api *Server) plansGet() *rpc {
+	return &rpc{
+		Namespace: "plans",
+		Action:    "get",
+		Documentation: RPCDocumentation{
+			Description: "Get plan by ID",
+			Parameters: driplimit.PlanGetPayload{
+				KSID: "ks_abc",
+				PLID: "pl_xyz",
+			},
+			Response: driplimit.Plan{
+				PLID: "pl_xyz",
+				KSID: "ks_abc",
+				Name: "premium",
+				Ratelimit: &driplimit.Ratelimit{
+					Limit:          1000,
+					RefillRate:     10,
+					RefillInterval: driplimit.Milliseconds{Duration: time.Second},
+				},
+			},
+		},
+		Handler: func(c *fiber.Ctx) (err error) {
+			payload := new(driplimit.PlanGetPayload)
+			if err := c.BodyParser(payload); err != nil {
+				return err
+			}
+			plan, err := api.service.PlanGet(c.Context(), *payload.WithServiceToken(token(c)))
+			if err != nil {
+				return err
+			}
+			return c.JSON(plan)
+		},
+	}
+}
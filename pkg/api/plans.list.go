@@ -0,0 +1,56 @@
+package api
+
+import (
+	"time"
+
+	"github.com/i4n-co/driplimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (api *Server) plansList() *rpc {
+	return &rpc{
+		Namespace: "plans",
+		Action:    "list",
+		Documentation: RPCDocumentation{
+			Description: "List the plans of a keyspace",
+			Parameters: driplimit.PlanListPayload{
+				KSID: "ks_abc",
+				List: driplimit.ListPayload{
+					Page:  1,
+					Limit: 10,
+				},
+			},
+			Response: driplimit.PlanList{
+				List: driplimit.ListMetadata{
+					Page:     1,
+					Limit:    10,
+					LastPage: 1,
+				},
+				Plans: []*driplimit.Plan{
+					{
+						PLID: "pl_xyz",
+						KSID: "ks_abc",
+						Name: "premium",
+						Ratelimit: &driplimit.Ratelimit{
+							Limit:          1000,
+							RefillRate:     10,
+							RefillInterval: driplimit.Milliseconds{Duration: time.Second},
+						},
+					},
+				},
+			},
+		},
+		Handler: func(c *fiber.Ctx) (err error) {
+			payload := new(driplimit.PlanListPayload)
+			if err := c.BodyParser(payload); err != nil {
+				return err
+			}
+			plist, err := api.service.PlanList(c.Context(), *payload.WithServiceToken(token(c)))
+			if err != nil {
+				return err
+			}
+			return c.JSON(plist)
+		},
+	}
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"time"
+
+	"github.com/i4n-co/driplimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (api *Server) plansUpdate() *rpc {
+	return &rpc{
+		Namespace: "plans",
+		Action:    "update",
+		Documentation: RPCDocumentation{
+			Description: "Update a plan. Only the provided fields are updated and all the keys of the plan are affected at once",
+			Parameters: driplimit.PlanUpdatePayload{
+				KSID: "ks_abc",
+				PLID: "pl_xyz",
+				Ratelimit: &driplimit.RatelimitPayload{
+					Limit:          2000,
+					RefillRate:     20,
+					RefillInterval: driplimit.Milliseconds{Duration: time.Second},
+				},
+			},
+			Response: driplimit.Plan{
+				PLID: "pl_xyz",
+				KSID: "ks_abc",
+				Name: "premium",
+				Ratelimit: &driplimit.Ratelimit{
+					Limit:          2000,
+					RefillRate:     20,
+					RefillInterval: driplimit.Milliseconds{Duration: time.Second},
+				},
+			},
+		},
+		Handler: func(c *fiber.Ctx) (err error) {
+			payload := new(driplimit.PlanUpdatePayload)
+			if err := c.BodyParser(payload); err != nil {
+				return err
+			}
+			plan, err := api.service.PlanUpdate(c.Context(), *payload.WithServiceToken(token(c)))
+			if err != nil {
+				return err
+			}
+			return c.JSON(plan)
+		},
+	}
+}
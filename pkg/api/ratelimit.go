@@ -0,0 +1,129 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/i4n-co/driplimit"
+	"github.com/i4n-co/driplimit/pkg/config"
+)
+
+// ratelimits holds the remaining API calls of the service keys. The state is local to the
+// server and starts over on restart.
+type ratelimits struct {
+	mu           sync.Mutex
+	remaining    *lru.Cache[string, *driplimit.Ratelimit]
+	api          *driplimit.Ratelimit
+	checks       *driplimit.Ratelimit
+	exemptChecks bool
+}
+
+func newRatelimits(cfg *config.Config) *ratelimits {
+	remaining, _ := lru.New[string, *driplimit.Ratelimit](max(cfg.ServiceKeysCacheSize, 1))
+	return &ratelimits{
+		remaining: remaining,
+		api: &driplimit.Ratelimit{
+			Limit:          cfg.APIRatelimitLimit,
+			RefillRate:     cfg.APIRatelimitRefillRate,
+			RefillInterval: driplimit.Milliseconds{Duration: cfg.APIRatelimitRefillInterval},
+		},
+		checks: &driplimit.Ratelimit{
+			Limit:          cfg.ChecksRatelimitLimit,
+			RefillRate:     cfg.ChecksRatelimitRefillRate,
+			RefillInterval: driplimit.Milliseconds{Duration: cfg.ChecksRatelimitRefillInterval},
+		},
+		exemptChecks: cfg.ChecksRatelimitExempt,
+	}
+}
+
+// allow consumes cost calls of the service key and returns false if its rate limit is exceeded.
+// Check calls consume the checks rate limit of the service key if it has one (or the server
+// default), otherwise they share the API rate limit unless they are exempted.
+func (r *ratelimits) allow(sk *driplimit.ServiceKey, check bool, cost int64) bool {
+	if check {
+		checks := sk.ChecksRatelimit
+		if !checks.Configured() {
+			checks = r.checks
+		}
+		if checks.Configured() {
+			return r.consume(sk.SKID+":checks", checks, cost)
+		}
+		if r.exemptChecks {
+			return true
+		}
+	}
+	api := sk.APIRatelimit
+	if !api.Configured() {
+		api = r.api
+	}
+	if !api.Configured() {
+		return true
+	}
+	return r.consume(sk.SKID+":api", api, cost)
+}
+
+// consume takes cost calls from the remaining calls of id, which follow the given rate limit.
+// Nothing is taken when fewer calls remain.
+func (r *ratelimits) consume(id string, ratelimit *driplimit.Ratelimit, cost int64) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, found := r.remaining.Get(id)
+	if !found {
+		// a fresh rate limit starts full
+		current = &driplimit.Ratelimit{State: &driplimit.RatelimitState{Remaining: ratelimit.Limit, LastRefilled: time.Now()}}
+		r.remaining.Add(id, current)
+	}
+	// the rate limit may have been updated since the last call
+	current.Limit = ratelimit.Limit
+	current.RefillRate = ratelimit.RefillRate
+	current.RefillInterval = ratelimit.RefillInterval
+	current.UpdateRemaining()
+	if current.State.Remaining > current.Limit {
+		current.State.Remaining = current.Limit
+	}
+
+	if current.State.Remaining < cost {
+		return false
+	}
+	current.State.Remaining -= cost
+	return true
+}
+
+// ratelimit is a middleware that enforces the rate limits of the service keys calls to the API.
+// Batch checks cost as many calls as they hold checks. It must run after restrictAddrs, which
+// resolves the service key.
+func ratelimit(limits *ratelimits) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		sk, ok := c.Locals("service_key").(*driplimit.ServiceKey)
+		if !ok {
+			return driplimit.ErrUnauthorized
+		}
+		batch := strings.HasSuffix(c.Path(), "/keys.check_batch")
+		check := batch || strings.HasSuffix(c.Path(), "/keys.check")
+		cost := int64(1)
+		if batch {
+			cost = batchCost(c.Body())
+		}
+		if !limits.allow(sk, check, cost) {
+			return driplimit.ErrServiceKeyRateLimitExceeded
+		}
+		return c.Next()
+	}
+}
+
+// batchCost returns the number of checks of a batch check body, at least 1. Invalid bodies
+// cost a single call, they are rejected by the RPC.
+func batchCost(body []byte) int64 {
+	batch := struct {
+		Checks []json.RawMessage `json:"checks"`
+	}{}
+	if json.Unmarshal(body, &batch) != nil || len(batch.Checks) == 0 {
+		return 1
+	}
+	return int64(len(batch.Checks))
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"time"
+
+	"github.com/i4n-co/driplimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (api *Server) rolesCreate() *rpc {
+	return &rpc{
+		Namespace: "roles",
+		Action:    "create",
+		Documentation: RPCDocumentation{
+			Description: "Create a role, a named set of keyspaces policies that can be assigned to service keys",
+			Parameters: driplimit.RoleCreatePayload{
+				Name: "gateway",
+				KeyspacesPolicies: driplimit.Policies{
+					"prod-*": {
+						Check: true,
+					},
+				},
+			},
+			Response: driplimit.Role{
+				RLID: "rl_xyz",
+				Name: "gateway",
+				KeyspacesPolicies: driplimit.Policies{
+					"prod-*": {
+						Check: true,
+					},
+				},
+				CreatedAt: time.Now(),
+			},
+		},
+		Handler: func(c *fiber.Ctx) (err error) {
+			payload := new(driplimit.RoleCreatePayload)
+			if err := c.BodyParser(payload); err != nil {
+				return err
+			}
+			role, err := api.service.RoleCreate(c.Context(), *payload.WithServiceToken(token(c)))
+			if err != nil {
+				return err
+			}
+			return c.JSON(role)
+		},
+	}
+}
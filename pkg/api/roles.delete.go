@@ -0,0 +1,32 @@
+package api
+
+import (
+	"github.com/i4n-co/driplimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (api *Server) rolesDelete() *rpc {
+	return &rpc{
+		Namespace: "roles",
+		Action:    "delete",
+		Documentation: RPCDocumentation{
+			Description: "Delete a role. It is removed from the service keys holding it",
+			Parameters: driplimit.RoleDeletePayload{
+				RLID: "rl_xyz",
+			},
+			Response: nil,
+		},
+		Handler: func(c *fiber.Ctx) (err error) {
+			payload := new(driplimit.RoleDeletePayload)
+			if err := c.BodyParser(payload); err != nil {
+				return err
+			}
+			err = api.service.RoleDelete(c.Context(), *payload.WithServiceToken(token(c)))
+			if err != nil {
+				return err
+			}
+			return c.SendStatus(fiber.StatusNoContent)
+		},
+	}
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"time"
+
+	"github.com/i4n-co/driplimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (api *Server) rolesGet() *rpc {
+	return &rpc{
+		Namespace: "roles",
+		Action:    "get",
+		Documentation: RPCDocumentation{
+			Description: "Get a role with its keyspaces policies",
+			Parameters: driplimit.RoleGetPayload{
+				RLID: "rl_xyz",
+			},
+			Response: driplimit.Role{
+				RLID: "rl_xyz",
+				Name: "gateway",
+				KeyspacesPolicies: driplimit.Policies{
+					"prod-*": {
+						Check: true,
+					},
+				},
+				CreatedAt: time.Now(),
+			},
+		},
+		Handler: func(c *fiber.Ctx) (err error) {
+			payload := new(driplimit.RoleGetPayload)
+			if err := c.BodyParser(payload); err != nil {
+				return err
+			}
+			role, err := api.service.RoleGet(c.Context(), *payload.WithServiceToken(token(c)))
+			if err != nil {
+				return err
+			}
+			return c.JSON(role)
+		},
+	}
+}
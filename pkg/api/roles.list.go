@@ -0,0 +1,55 @@
+package api
+
+import (
+	"time"
+
+	"github.com/i4n-co/driplimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (api *Server) rolesList() *rpc {
+	return &rpc{
+		Namespace: "roles",
+		Action:    "list",
+		Documentation: RPCDocumentation{
+			Description: "List the roles",
+			Parameters: driplimit.RoleListPayload{
+				List: driplimit.ListPayload{
+					Page:  1,
+					Limit: 10,
+				},
+			},
+			Response: driplimit.RoleList{
+				List: driplimit.ListMetadata{
+					Page:     1,
+					Limit:    10,
+					LastPage: 1,
+				},
+				Roles: []*driplimit.Role{
+					{
+						RLID: "rl_xyz",
+						Name: "gateway",
+						KeyspacesPolicies: driplimit.Policies{
+							"prod-*": {
+								Check: true,
+							},
+						},
+						CreatedAt: time.Now(),
+					},
+				},
+			},
+		},
+		Handler: func(c *fiber.Ctx) (err error) {
+			payload := new(driplimit.RoleListPayload)
+			if err := c.BodyParser(payload); err != nil {
+				return err
+			}
+			rlist, err := api.service.RoleList(c.Context(), *payload.WithServiceToken(token(c)))
+			if err != nil {
+				return err
+			}
+			return c.JSON(rlist)
+		},
+	}
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"time"
+
+	"github.com/i4n-co/driplimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (api *Server) rolesUpdate() *rpc {
+	return &rpc{
+		Namespace: "roles",
+		Action:    "update",
+		Documentation: RPCDocumentation{
+			Description: "Update a role. The service keys holding the role are affected at once",
+			Parameters: driplimit.RoleUpdatePayload{
+				RLID: "rl_xyz",
+				KeyspacesPolicies: driplimit.Policies{
+					"prod-*": {
+						Check: true,
+						Read:  true,
+					},
+				},
+			},
+			Response: driplimit.Role{
+				RLID: "rl_xyz",
+				Name: "gateway",
+				KeyspacesPolicies: driplimit.Policies{
+					"prod-*": {
+						Check: true,
+						Read:  true,
+					},
+				},
+				CreatedAt: time.Now(),
+			},
+		},
+		Handler: func(c *fiber.Ctx) (err error) {
+			payload := new(driplimit.RoleUpdatePayload)
+			if err := c.BodyParser(payload); err != nil {
+				return err
+			}
+			role, err := api.service.RoleUpdate(c.Context(), *payload.WithServiceToken(token(c)))
+			if err != nil {
+				return err
+			}
+			return c.JSON(role)
+		},
+	}
+}
@@ -2,10 +2,15 @@ package api
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
+	"os"
 	"sync"
 
 	"github.com/i4n-co/driplimit"
@@ -26,6 +31,9 @@ type Server struct {
 	router  *fiber.App
 	logger  *slog.Logger
 	cfg     *config.Config
+	nonces  *nonceCache
+	proxies []*net.IPNet
+	limits  *ratelimits
 }
 
 // New creates an API server
@@ -34,6 +42,10 @@ func New(cfg *config.Config, service driplimit.Service) *Server {
 	server.mu = new(sync.Mutex)
 	server.cfg = cfg
 	server.service = service
+	server.nonces = newNonceCache(cfg.SignatureNoncesSize)
+	// trusted proxies are validated with the configuration
+	server.proxies, _ = cfg.TrustedProxyNetworks()
+	server.limits = newRatelimits(cfg)
 	server.logger = cfg.Logger().With("component", "api")
 	network := fiber.NetworkTCP4
 	if cfg.UseIPv6Addr() {
@@ -51,20 +63,49 @@ func New(cfg *config.Config, service driplimit.Service) *Server {
 	server.router.Get("/healthz", healthz)
 
 	v1 := server.router.Group("/v1")
-	v1.Use(authenticate())
+	v1.Use(authenticate(cfg.IsProxy()))
+	v1.Use(restrictAddrs(service, server.proxies, server.logger))
+	v1.Use(preventReplays(server.nonces))
+	v1.Use(ratelimit(server.limits))
 
 	// Keys namespace
 	server.registerRPC(v1, server.keysCreate())
 	server.registerRPC(v1, server.keysCheck())
+	server.registerRPC(v1, server.keysCheckBatch())
 	server.registerRPC(v1, server.keysList())
 	server.registerRPC(v1, server.keysGet())
 	server.registerRPC(v1, server.keysDelete())
+	server.registerRPC(v1, server.keysDeleteMany())
+	server.registerRPC(v1, server.keysReset())
+	server.registerRPC(v1, server.keysMove())
 
 	// Keyspaces namespace
 	server.registerRPC(v1, server.keyspacesGet())
 	server.registerRPC(v1, server.keyspacesList())
 	server.registerRPC(v1, server.keyspacesCreate())
+	server.registerRPC(v1, server.keyspacesUpdate())
 	server.registerRPC(v1, server.keyspacesDelete())
+	server.registerRPC(v1, server.keyspacesClone())
+	server.registerRPC(v1, server.keyspacesStats())
+	server.registerRPC(v1, server.keyspacesExport())
+	server.registerRPC(v1, server.keyspacesImport())
+
+	// Plans namespace
+	server.registerRPC(v1, server.plansGet())
+	server.registerRPC(v1, server.plansList())
+	server.registerRPC(v1, server.plansCreate())
+	server.registerRPC(v1, server.plansUpdate())
+	server.registerRPC(v1, server.plansDelete())
+
+	// Roles namespace
+	server.registerRPC(v1, server.rolesGet())
+	server.registerRPC(v1, server.rolesList())
+	server.registerRPC(v1, server.rolesCreate())
+	server.registerRPC(v1, server.rolesUpdate())
+	server.registerRPC(v1, server.rolesDelete())
+
+	// Audit namespace
+	server.registerRPC(v1, server.auditList())
 
 	// ServiceKeys namespace
 	server.registerRPC(v1, server.serviceKeysCurrent())
@@ -73,13 +114,67 @@ func New(cfg *config.Config, service driplimit.Service) *Server {
 	server.registerRPC(v1, server.serviceKeysDelete())
 	server.registerRPC(v1, server.serviceKeysCreate())
 	server.registerRPC(v1, server.serviceKeysSetToken())
+	server.registerRPC(v1, server.serviceKeysRotate())
+	server.registerRPC(v1, server.serviceKeysPromote())
+	server.registerRPC(v1, server.serviceKeysRevokePrevious())
+	server.registerRPC(v1, server.serviceKeysUpdate())
+	server.registerRPC(v1, server.serviceKeysCan())
 	return server
 }
 
 // Listen starts the API server on the given address
 func (api *Server) Listen(addr string) error {
-	api.logger.Info("starting driplimit...", "addr", addr)
-	return api.router.Listen(addr)
+	network := "tcp4"
+	if api.cfg.UseIPv6Addr() {
+		network = "tcp6"
+	}
+	ln, err := net.Listen(network, addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	return api.Listener(ln)
+}
+
+// Listener starts the API server on the given listener. The listener is wrapped with TLS
+// when a TLS certificate is configured, client certificates are then verified against the
+// configured client CAs and authenticate the requests without Authorization header.
+func (api *Server) Listener(ln net.Listener) error {
+	if api.cfg.UseTLS() {
+		tlsConfig, err := api.tlsConfig()
+		if err != nil {
+			ln.Close()
+			return err
+		}
+		ln = tls.NewListener(ln, tlsConfig)
+	}
+	api.logger.Info("starting driplimit...", "addr", ln.Addr().String(), "tls", api.cfg.UseTLS())
+	return api.router.Listener(ln)
+}
+
+// tlsConfig loads the TLS configuration of the server.
+func (api *Server) tlsConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(api.cfg.TLSCertFile, api.cfg.TLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if api.cfg.TLSClientCAFile != "" {
+		pem, err := os.ReadFile(api.cfg.TLSClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read TLS client CA: %w", err)
+		}
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in TLS client CA %s", api.cfg.TLSClientCAFile)
+		}
+		tlsConfig.ClientCAs = clientCAs
+		// clients without certificate still authenticate with an Authorization header
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tlsConfig, nil
 }
 
 // ShutdownWithContext shuts down the API server gracefully
@@ -117,6 +212,8 @@ func (api *Server) errorHandler(ctx *fiber.Ctx, err error) error {
 		return ctx.Status(driplimit.HTTPCodeFromErr(driplimit.ErrInvalidExpiration)).JSON(Err{Message: err.Error()})
 	case errors.Is(err, driplimit.ErrRateLimitExceeded):
 		return ctx.Status(driplimit.HTTPCodeFromErr(driplimit.ErrRateLimitExceeded)).JSON(Err{Message: err.Error()})
+	case errors.Is(err, driplimit.ErrServiceKeyRateLimitExceeded):
+		return ctx.Status(driplimit.HTTPCodeFromErr(driplimit.ErrServiceKeyRateLimitExceeded)).JSON(Err{Message: err.Error()})
 	case errors.Is(err, driplimit.ErrKeyExpired):
 		return ctx.Status(driplimit.HTTPCodeFromErr(driplimit.ErrKeyExpired)).JSON(Err{Message: err.Error()})
 	case errors.Is(err, driplimit.ErrCannotDeleteItself):
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"github.com/i4n-co/driplimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (api *Server) serviceKeysCan() *rpc {
+	return &rpc{
+		Namespace: "serviceKeys",
+		Action:    "can",
+		Documentation: RPCDocumentation{
+			Description: "Simulate an action of a service key on a keyspace and explain whether it is allowed and which rule decides it (admin, expired, policy, pattern, wildcard or none). Admins can simulate any service key, other service keys only themselves",
+			Parameters: driplimit.ServiceKeyCanPayload{
+				SKID:   "sk_uvw",
+				Action: driplimit.Check,
+				KSID:   "ks_abc",
+			},
+			Response: driplimit.Permission{
+				SKID:          "sk_uvw",
+				Action:        driplimit.Check,
+				KSID:          "ks_abc",
+				KeyspaceFound: true,
+				PolicyDecision: driplimit.PolicyDecision{
+					Allowed: true,
+					Rule:    driplimit.RulePattern,
+					Policy:  "prod-*",
+					RLID:    "rl_xyz",
+				},
+			},
+		},
+		Handler: func(c *fiber.Ctx) (err error) {
+			payload := new(driplimit.ServiceKeyCanPayload)
+			if err := c.BodyParser(payload); err != nil {
+				return err
+			}
+			permission, err := api.service.ServiceKeyCan(c.Context(), *payload.WithServiceToken(token(c)))
+			if err != nil {
+				return err
+			}
+			return c.JSON(permission)
+		},
+	}
+}
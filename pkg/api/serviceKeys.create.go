@@ -19,19 +19,21 @@ func (api *Server) serviceKeysCreate() *rpc {
 				Admin:       false,
 				KeyspacesPolicies: map[string]driplimit.Policy{
 					"ks_abc": {
+						Check: true,
 						Read:  true,
-						Write: false,
 					},
 				},
 			},
 			Response: driplimit.ServiceKey{
-				SKID:        "sk_uvw",
-				Description: "api generated non admin service key",
-				Admin:       true,
+				SKID:          "sk_uvw",
+				Description:   "api generated non admin service key",
+				Admin:         true,
+				Token:         "sk_xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx",
+				SigningSecret: "sks_xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx",
 				KeyspacesPolicies: map[string]driplimit.Policy{
 					"ks_abc": {
+						Check: true,
 						Read:  true,
-						Write: false,
 					},
 				},
 				CreatedAt: time.Now(),
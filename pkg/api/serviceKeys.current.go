@@ -22,8 +22,11 @@ func (api *Server) serviceKeysCurrent() *rpc {
 				Admin:       true,
 				KeyspacesPolicies: map[string]driplimit.Policy{
 					"ks_abc": {
-						Read:  true,
-						Write: true,
+						Check:  true,
+						Read:   true,
+						Create: true,
+						Update: true,
+						Delete: true,
 					},
 				},
 				CreatedAt: time.Now(),
@@ -25,8 +25,11 @@ func (api *Server) serviceKeysGet() *rpc {
 				Admin:       true,
 				KeyspacesPolicies: map[string]driplimit.Policy{
 					"ks_abc": {
-						Read:  true,
-						Write: true,
+						Check:  true,
+						Read:   true,
+						Create: true,
+						Update: true,
+						Delete: true,
 					},
 				},
 				CreatedAt: time.Now(),
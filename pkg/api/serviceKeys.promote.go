@@ -0,0 +1,39 @@
+package api
+
+import (
+	"time"
+
+	"github.com/i4n-co/driplimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (api *Server) serviceKeysPromote() *rpc {
+	return &rpc{
+		Namespace: "serviceKeys",
+		Action:    "promote",
+		Documentation: RPCDocumentation{
+			Description: "Promote the secondary token of a service key. The former token becomes the secondary token and still authenticates the service key until it is revoked",
+			Parameters: driplimit.ServiceKeyPromotePayload{
+				SKID: "sk_uvw",
+			},
+			Response: driplimit.ServiceKey{
+				SKID:           "sk_uvw",
+				Description:    "ci service key",
+				TokenCreatedAt: time.Now().Add(-30 * 24 * time.Hour),
+				CreatedAt:      time.Now().Add(-30 * 24 * time.Hour),
+			},
+		},
+		Handler: func(c *fiber.Ctx) (err error) {
+			payload := new(driplimit.ServiceKeyPromotePayload)
+			if err := c.BodyParser(payload); err != nil {
+				return err
+			}
+			sk, err := api.service.ServiceKeyPromote(c.Context(), *payload.WithServiceToken(token(c)))
+			if err != nil {
+				return err
+			}
+			return c.JSON(sk)
+		},
+	}
+}
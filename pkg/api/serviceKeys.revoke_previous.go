@@ -0,0 +1,39 @@
+package api
+
+import (
+	"time"
+
+	"github.com/i4n-co/driplimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (api *Server) serviceKeysRevokePrevious() *rpc {
+	return &rpc{
+		Namespace: "serviceKeys",
+		Action:    "revoke_previous",
+		Documentation: RPCDocumentation{
+			Description: "Revoke the secondary token of a service key, finishing or cancelling its rotation",
+			Parameters: driplimit.ServiceKeyRevokePreviousPayload{
+				SKID: "sk_uvw",
+			},
+			Response: driplimit.ServiceKey{
+				SKID:           "sk_uvw",
+				Description:    "ci service key",
+				TokenCreatedAt: time.Now().Add(-30 * 24 * time.Hour),
+				CreatedAt:      time.Now().Add(-30 * 24 * time.Hour),
+			},
+		},
+		Handler: func(c *fiber.Ctx) (err error) {
+			payload := new(driplimit.ServiceKeyRevokePreviousPayload)
+			if err := c.BodyParser(payload); err != nil {
+				return err
+			}
+			sk, err := api.service.ServiceKeyRevokePrevious(c.Context(), *payload.WithServiceToken(token(c)))
+			if err != nil {
+				return err
+			}
+			return c.JSON(sk)
+		},
+	}
+}
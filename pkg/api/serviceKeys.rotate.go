@@ -0,0 +1,41 @@
+package api
+
+import (
+	"time"
+
+	"github.com/i4n-co/driplimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (api *Server) serviceKeysRotate() *rpc {
+	return &rpc{
+		Namespace: "serviceKeys",
+		Action:    "rotate",
+		Documentation: RPCDocumentation{
+			Description: "Generate a secondary token for a service key, returned once. Both tokens authenticate the service key until the secondary token is promoted or revoked",
+			Parameters: driplimit.ServiceKeyRotatePayload{
+				SKID: "sk_uvw",
+			},
+			Response: driplimit.ServiceKey{
+				SKID:                    "sk_uvw",
+				Description:             "ci service key",
+				SecondaryToken:          "sk_xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx",
+				SecondaryTokenCreatedAt: time.Now(),
+				TokenCreatedAt:          time.Now().Add(-30 * 24 * time.Hour),
+				CreatedAt:               time.Now().Add(-30 * 24 * time.Hour),
+			},
+		},
+		Handler: func(c *fiber.Ctx) (err error) {
+			payload := new(driplimit.ServiceKeyRotatePayload)
+			if err := c.BodyParser(payload); err != nil {
+				return err
+			}
+			sk, err := api.service.ServiceKeyRotate(c.Context(), *payload.WithServiceToken(token(c)))
+			if err != nil {
+				return err
+			}
+			return c.JSON(sk)
+		},
+	}
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"time"
+
+	"github.com/i4n-co/driplimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (api *Server) serviceKeysUpdate() *rpc {
+	description := "ci service key"
+	return &rpc{
+		Namespace: "serviceKeys",
+		Action:    "update",
+		Documentation: RPCDocumentation{
+			Description: "Update a service key (admin only). Only the provided fields are updated, the token is kept. Service keys allowed to manage the policies of keyspaces can also update the policies of other non admin service keys on these keyspaces",
+			Parameters: driplimit.ServiceKeyUpdatePayload{
+				SKID:        "sk_uvw",
+				Description: &description,
+				KeyspacesPolicies: map[string]driplimit.Policy{
+					"ks_abc": {
+						Check:  true,
+						Read:   true,
+						Create: true,
+						Update: true,
+						Delete: true,
+					},
+				},
+			},
+			Response: driplimit.ServiceKey{
+				SKID:        "sk_uvw",
+				Description: "ci service key",
+				Admin:       false,
+				KeyspacesPolicies: map[string]driplimit.Policy{
+					"ks_abc": {
+						Check:  true,
+						Read:   true,
+						Create: true,
+						Update: true,
+						Delete: true,
+					},
+				},
+				CreatedAt: time.Now(),
+			},
+		},
+		Handler: func(c *fiber.Ctx) (err error) {
+			payload := new(driplimit.ServiceKeyUpdatePayload)
+			if err := c.BodyParser(payload); err != nil {
+				return err
+			}
+			sk, err := api.service.ServiceKeyUpdate(c.Context(), *payload.WithServiceToken(token(c)))
+			if err != nil {
+				return err
+			}
+			return c.JSON(sk)
+		},
+	}
+}
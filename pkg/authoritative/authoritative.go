@@ -2,10 +2,12 @@ package authoritative
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/i4n-co/driplimit"
+	"github.com/i4n-co/driplimit/pkg/jwt"
 	"github.com/i4n-co/driplimit/pkg/store"
 )
 
@@ -13,6 +15,7 @@ import (
 // It is the source of truth for the rate limits and uses the store directly.
 type Authoritative struct {
 	store *store.Store
+	jwt   *JWTAuthentication
 }
 
 // NewService returns a new authoritative driplimit service.
@@ -23,10 +26,48 @@ func NewService(store *store.Store) *Authoritative {
 	return app
 }
 
+// JWTAuthentication maps verified JWT bearer tokens to service keys. A token either names an
+// existing service key in its SKID claim, or carries keyspaces policies in its policies claim
+// and then acts as a non admin service key of its own, identified by its subject.
+type JWTAuthentication struct {
+	Verifier      *jwt.Verifier
+	SKIDClaim     string
+	PoliciesClaim string
+}
+
+// WithJWTAuthentication enables the authentication of service keys with JWT bearer tokens.
+func (service *Authoritative) WithJWTAuthentication(auth JWTAuthentication) *Authoritative {
+	service.jwt = &auth
+	return service
+}
+
 // KeyCheck checks if the key can be used (not expired, rate limit not exceeded) and returns an error if not.
 // In case of success, it decrements the remaining count of the key if the rate limit is set.
+// A key used during its grace period is flagged as in grace.
 func (service *Authoritative) KeyCheck(ctx context.Context, payload driplimit.KeysCheckPayload) (key *driplimit.Key, err error) {
-	key, err = service.KeyGet(ctx, driplimit.KeyGetPayload{KSID: payload.KSID, Token: payload.Token})
+	return service.checkKey(ctx, service.store, payload)
+}
+
+// KeyCheckBatch checks multiple keys within a single transaction. Each check gets its own result
+// so that a failing check does not fail the whole batch.
+func (service *Authoritative) KeyCheckBatch(ctx context.Context, payload driplimit.KeysCheckBatchPayload) (batch *driplimit.KeysCheckBatch, err error) {
+	batch = &driplimit.KeysCheckBatch{Results: make([]driplimit.KeysCheckBatchResult, 0, len(payload.Checks))}
+	err = service.store.WithTx(ctx, func(tx *store.Store) error {
+		for _, check := range payload.Checks {
+			key, err := service.checkKey(ctx, tx, check)
+			batch.Results = append(batch.Results, driplimit.NewKeysCheckBatchResult(key, err))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check keys: %w", err)
+	}
+	return batch, nil
+}
+
+// checkKey checks the key using the given store. See KeyCheck.
+func (service *Authoritative) checkKey(ctx context.Context, store *store.Store, payload driplimit.KeysCheckPayload) (key *driplimit.Key, err error) {
+	key, err = service.getKey(ctx, store, driplimit.KeyGetPayload{KSID: payload.KSID, Token: payload.Token})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get key: %w", err)
 	}
@@ -34,10 +75,11 @@ func (service *Authoritative) KeyCheck(ctx context.Context, payload driplimit.Ke
 	if key.Expired() {
 		return nil, driplimit.ErrKeyExpired
 	}
+	key.InGrace = key.InGracePeriod()
 
 	key.LastUsed = time.Now()
 	if !key.ConfiguredRatelimit() {
-		if err := service.store.UpdateLastUsed(ctx, key); err != nil {
+		if err := store.UpdateLastUsed(ctx, key); err != nil {
 			return nil, fmt.Errorf("failed to update key last used: %w", err)
 		}
 		return key, nil
@@ -47,7 +89,7 @@ func (service *Authoritative) KeyCheck(ctx context.Context, payload driplimit.Ke
 		return nil, driplimit.ErrRateLimitExceeded
 	}
 
-	if err := service.store.DecrementKeyRemaining(ctx, key); err != nil {
+	if err := store.DecrementKeyRemaining(ctx, key); err != nil {
 		return nil, fmt.Errorf("failed to decrement key remaining: %w", err)
 	}
 
@@ -65,7 +107,12 @@ func (service *Authoritative) KeyCreate(ctx context.Context, payload driplimit.K
 
 // KeyGet returns key based on the given payload. It ensures that the remaining count is up to date if necessary.
 func (service *Authoritative) KeyGet(ctx context.Context, payload driplimit.KeyGetPayload) (key *driplimit.Key, err error) {
-	key, err = service.store.GetKey(ctx, payload)
+	return service.getKey(ctx, service.store, payload)
+}
+
+// getKey returns the key using the given store. See KeyGet.
+func (service *Authoritative) getKey(ctx context.Context, store *store.Store, payload driplimit.KeyGetPayload) (key *driplimit.Key, err error) {
+	key, err = store.GetKey(ctx, payload)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get key: %w", err)
 	}
@@ -75,7 +122,7 @@ func (service *Authoritative) KeyGet(ctx context.Context, payload driplimit.KeyG
 		return key, nil
 	}
 
-	if err := service.store.SetKeyRemaining(ctx, key); err != nil {
+	if err := store.SetKeyRemaining(ctx, key); err != nil {
 		return nil, fmt.Errorf("failed to set key remaining: %w", err)
 	}
 
@@ -99,6 +146,53 @@ func (service *Authoritative) KeyDelete(ctx context.Context, payload driplimit.K
 	return nil
 }
 
+// KeyDeleteMany deletes the keys matching the payload filters and returns how many were deleted.
+// In dry run mode, keys are only counted.
+func (service *Authoritative) KeyDeleteMany(ctx context.Context, payload driplimit.KeyDeleteManyPayload) (result *driplimit.KeyDeleteManyResult, err error) {
+	result, err = service.store.DeleteKeys(ctx, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete keys: %w", err)
+	}
+	return result, nil
+}
+
+// KeyReset resets the rate limit state of a key. The remaining count is set to the rate limit
+// (or to the given value) and the refill clock restarts. Keys without rate limit are left untouched.
+func (service *Authoritative) KeyReset(ctx context.Context, payload driplimit.KeyResetPayload) (key *driplimit.Key, err error) {
+	key, err = service.store.GetKey(ctx, driplimit.KeyGetPayload{KSID: payload.KSID, KID: payload.KID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get key: %w", err)
+	}
+	if !key.ConfiguredRatelimit() {
+		return key, nil
+	}
+
+	remaining := key.Ratelimit.Limit
+	if payload.Remaining != nil {
+		if *payload.Remaining > key.Ratelimit.Limit {
+			return nil, fmt.Errorf("remaining exceeds the rate limit: %w", driplimit.ErrInvalidPayload)
+		}
+		remaining = *payload.Remaining
+	}
+	key.Ratelimit.State.Remaining = remaining
+	key.Ratelimit.State.LastRefilled = time.Now()
+
+	if err := service.store.SetKeyRemaining(ctx, key); err != nil {
+		return nil, fmt.Errorf("failed to set key remaining: %w", err)
+	}
+	return key, nil
+}
+
+// KeyMove moves a key to another keyspace and returns it. The token is returned only if a new one
+// has been generated.
+func (service *Authoritative) KeyMove(ctx context.Context, payload driplimit.KeyMovePayload) (key *driplimit.Key, err error) {
+	key, err = service.store.MoveKey(ctx, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to move key: %w", err)
+	}
+	return key, nil
+}
+
 // KeyspaceGet returns a keyspace based on the given payload.
 func (service *Authoritative) KeyspaceGet(ctx context.Context, payload driplimit.KeyspaceGetPayload) (keyspace *driplimit.Keyspace, err error) {
 	ks, err := service.store.GetKeyspaceByID(ctx, payload.KSID)
@@ -126,6 +220,15 @@ func (service *Authoritative) KeyspaceList(ctx context.Context, payload driplimi
 	return kslist, nil
 }
 
+// KeyspaceUpdate updates a keyspace with the given payload and returns it.
+func (service *Authoritative) KeyspaceUpdate(ctx context.Context, payload driplimit.KeyspaceUpdatePayload) (keyspace *driplimit.Keyspace, err error) {
+	ks, err := service.store.UpdateKeyspace(ctx, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update keyspace: %w", err)
+	}
+	return ks, nil
+}
+
 // KeyspaceDelete deletes a keyspace based on the given payload.
 func (service *Authoritative) KeyspaceDelete(ctx context.Context, payload driplimit.KeyspaceDeletePayload) (err error) {
 	if err := service.store.DeleteKeyspace(ctx, payload); err != nil {
@@ -134,8 +237,146 @@ func (service *Authoritative) KeyspaceDelete(ctx context.Context, payload dripli
 	return nil
 }
 
-// ServiceKeyGet returns a service key based on the given payload.
+// KeyspaceClone clones a keyspace with its configuration, plans, policies and optionally its keys.
+func (service *Authoritative) KeyspaceClone(ctx context.Context, payload driplimit.KeyspaceClonePayload) (keyspace *driplimit.Keyspace, err error) {
+	keyspace, err = service.store.CloneKeyspace(ctx, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone keyspace: %w", err)
+	}
+	return keyspace, nil
+}
+
+// KeyspaceStats returns the key statistics of a keyspace.
+func (service *Authoritative) KeyspaceStats(ctx context.Context, payload driplimit.KeyspaceStatsPayload) (stats *driplimit.KeyspaceStats, err error) {
+	stats, err = service.store.KeyspaceStats(ctx, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get keyspace stats: %w", err)
+	}
+	return stats, nil
+}
+
+// KeyspaceExport exports a keyspace with its plans, keys and policies as a bundle.
+func (service *Authoritative) KeyspaceExport(ctx context.Context, payload driplimit.KeyspaceExportPayload) (bundle *driplimit.KeyspaceBundle, err error) {
+	bundle, err = service.store.ExportKeyspace(ctx, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export keyspace: %w", err)
+	}
+	return bundle, nil
+}
+
+// KeyspaceImport imports a keyspace bundle according to the payload strategy.
+func (service *Authoritative) KeyspaceImport(ctx context.Context, payload driplimit.KeyspaceImportPayload) (result *driplimit.KeyspaceImportResult, err error) {
+	result, err = service.store.ImportKeyspace(ctx, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import keyspace: %w", err)
+	}
+	return result, nil
+}
+
+// PlanGet returns the plan matching the given payload.
+func (service *Authoritative) PlanGet(ctx context.Context, payload driplimit.PlanGetPayload) (plan *driplimit.Plan, err error) {
+	plan, err = service.store.GetPlan(ctx, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get plan: %w", err)
+	}
+	return plan, nil
+}
+
+// PlanCreate creates a new plan in a keyspace.
+func (service *Authoritative) PlanCreate(ctx context.Context, payload driplimit.PlanCreatePayload) (plan *driplimit.Plan, err error) {
+	plan, err = service.store.CreatePlan(ctx, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create plan: %w", err)
+	}
+	return plan, nil
+}
+
+// PlanList returns the plans of a keyspace.
+func (service *Authoritative) PlanList(ctx context.Context, payload driplimit.PlanListPayload) (plist *driplimit.PlanList, err error) {
+	plist, err = service.store.ListPlans(ctx, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list plans: %w", err)
+	}
+	return plist, nil
+}
+
+// PlanUpdate updates a plan, its keys pick up the changes at once.
+func (service *Authoritative) PlanUpdate(ctx context.Context, payload driplimit.PlanUpdatePayload) (plan *driplimit.Plan, err error) {
+	plan, err = service.store.UpdatePlan(ctx, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update plan: %w", err)
+	}
+	return plan, nil
+}
+
+// PlanDelete deletes a plan, its keys fall back to the keyspace defaults.
+func (service *Authoritative) PlanDelete(ctx context.Context, payload driplimit.PlanDeletePayload) (err error) {
+	err = service.store.DeletePlan(ctx, payload)
+	if err != nil {
+		return fmt.Errorf("failed to delete plan: %w", err)
+	}
+	return nil
+}
+
+// RoleGet returns the role matching the given payload.
+func (service *Authoritative) RoleGet(ctx context.Context, payload driplimit.RoleGetPayload) (role *driplimit.Role, err error) {
+	role, err = service.store.GetRole(ctx, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role: %w", err)
+	}
+	return role, nil
+}
+
+// RoleCreate creates a new role.
+func (service *Authoritative) RoleCreate(ctx context.Context, payload driplimit.RoleCreatePayload) (role *driplimit.Role, err error) {
+	role, err = service.store.CreateRole(ctx, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create role: %w", err)
+	}
+	return role, nil
+}
+
+// RoleList returns the roles.
+func (service *Authoritative) RoleList(ctx context.Context, payload driplimit.RoleListPayload) (rlist *driplimit.RoleList, err error) {
+	rlist, err = service.store.ListRoles(ctx, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+	return rlist, nil
+}
+
+// RoleUpdate updates a role, the service keys holding it pick up the changes at once.
+func (service *Authoritative) RoleUpdate(ctx context.Context, payload driplimit.RoleUpdatePayload) (role *driplimit.Role, err error) {
+	role, err = service.store.UpdateRole(ctx, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update role: %w", err)
+	}
+	return role, nil
+}
+
+// RoleDelete deletes a role and removes it from the service keys holding it.
+func (service *Authoritative) RoleDelete(ctx context.Context, payload driplimit.RoleDeletePayload) (err error) {
+	err = service.store.DeleteRole(ctx, payload)
+	if err != nil {
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+	return nil
+}
+
+// ServiceKeyGet returns the service key by id or by token. Request signature credentials are
+// verified against the service key signing secret, JWT bearer tokens against the JWKS when
+// JWT authentication is enabled, and client certificate credentials are matched against the
+// service keys client identity.
 func (service *Authoritative) ServiceKeyGet(ctx context.Context, payload driplimit.ServiceKeyGetPayload) (sk *driplimit.ServiceKey, err error) {
+	if payload.SKID == "" && driplimit.IsSignatureCredential(payload.Token) {
+		return service.serviceKeyBySignature(ctx, payload.Token)
+	}
+	if payload.SKID == "" && service.jwt != nil && jwt.IsJWT(payload.Token) {
+		return service.serviceKeyByJWT(ctx, payload.Token)
+	}
+	if payload.SKID == "" && driplimit.IsClientCertificateCredential(payload.Token) {
+		return service.serviceKeyByClientCertificate(ctx, payload.Token)
+	}
 	sk, err = service.store.GetServiceKey(ctx, payload)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get service key: %w", err)
@@ -143,6 +384,73 @@ func (service *Authoritative) ServiceKeyGet(ctx context.Context, payload driplim
 	return sk, nil
 }
 
+// serviceKeyBySignature returns the service key that signed the request of the credential.
+func (service *Authoritative) serviceKeyBySignature(ctx context.Context, credential string) (sk *driplimit.ServiceKey, err error) {
+	signature, err := driplimit.ParseRequestSignature(credential)
+	if err != nil {
+		return nil, driplimit.ErrItemNotFound("service key")
+	}
+	secret, err := service.store.GetServiceKeySigningSecret(ctx, signature.SKID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service key: %w", err)
+	}
+	if signature.Verify(secret) != nil {
+		return nil, driplimit.ErrItemNotFound("service key")
+	}
+	return service.ServiceKeyGet(ctx, driplimit.ServiceKeyGetPayload{SKID: signature.SKID})
+}
+
+// serviceKeyByClientCertificate returns the service key whose client identity matches the
+// first of the certificate identities. The certificate is verified by the TLS listener.
+func (service *Authoritative) serviceKeyByClientCertificate(ctx context.Context, credential string) (sk *driplimit.ServiceKey, err error) {
+	identities, err := driplimit.ParseClientCertificateCredential(credential)
+	if err != nil {
+		return nil, driplimit.ErrItemNotFound("service key")
+	}
+	sk, err = service.store.GetServiceKeyByClientIdentity(ctx, identities)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service key: %w", err)
+	}
+	return sk, nil
+}
+
+// serviceKeyByJWT returns the service key of the JWT bearer token. The service key never
+// outlives the token.
+func (service *Authoritative) serviceKeyByJWT(ctx context.Context, token string) (sk *driplimit.ServiceKey, err error) {
+	claims, err := service.jwt.Verifier.Verify(ctx, token)
+	if err != nil {
+		return nil, driplimit.ErrItemNotFound("service key")
+	}
+
+	skid := ""
+	found, err := claims.Decode(service.jwt.SKIDClaim, &skid)
+	if err != nil {
+		return nil, driplimit.ErrItemNotFound("service key")
+	}
+	if found && skid != "" {
+		sk, err = service.ServiceKeyGet(ctx, driplimit.ServiceKeyGetPayload{SKID: skid})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		policies := make(driplimit.Policies)
+		found, err = claims.Decode(service.jwt.PoliciesClaim, &policies)
+		if err != nil || !found || claims.Subject == "" || policies.Validate() != nil {
+			return nil, driplimit.ErrItemNotFound("service key")
+		}
+		sk = &driplimit.ServiceKey{
+			SKID:              "jwt:" + claims.Subject,
+			Description:       "JWT bearer token of " + claims.Subject,
+			KeyspacesPolicies: policies,
+		}
+	}
+
+	if sk.ExpiresAt.IsZero() || claims.ExpiresAt.Before(sk.ExpiresAt) {
+		sk.ExpiresAt = claims.ExpiresAt
+	}
+	return sk, nil
+}
+
 // ServiceKeyCreate creates a new service key with the given payload and returns the service key information.
 func (service *Authoritative) ServiceKeyCreate(ctx context.Context, payload driplimit.ServiceKeyCreatePayload) (sk *driplimit.ServiceKey, err error) {
 	var token *string
@@ -175,3 +483,75 @@ func (service *Authoritative) ServiceKeySetToken(ctx context.Context, payload dr
 	}
 	return nil
 }
+
+// ServiceKeyRotate generates the secondary token of the service key, returned once. Both
+// tokens authenticate the service key until the rotation is finished.
+func (service *Authoritative) ServiceKeyRotate(ctx context.Context, payload driplimit.ServiceKeyRotatePayload) (sk *driplimit.ServiceKey, err error) {
+	sk, err = service.store.RotateServiceKeyToken(ctx, payload.SKID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate service key: %w", err)
+	}
+	return sk, nil
+}
+
+// ServiceKeyPromote makes the secondary token of the service key its token. The former token
+// becomes the secondary token until it is revoked.
+func (service *Authoritative) ServiceKeyPromote(ctx context.Context, payload driplimit.ServiceKeyPromotePayload) (sk *driplimit.ServiceKey, err error) {
+	sk, err = service.store.PromoteServiceKeyToken(ctx, payload.SKID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to promote service key token: %w", err)
+	}
+	return sk, nil
+}
+
+// ServiceKeyRevokePrevious revokes the secondary token of the service key.
+func (service *Authoritative) ServiceKeyRevokePrevious(ctx context.Context, payload driplimit.ServiceKeyRevokePreviousPayload) (sk *driplimit.ServiceKey, err error) {
+	sk, err = service.store.RevokeServiceKeySecondaryToken(ctx, payload.SKID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke service key secondary token: %w", err)
+	}
+	return sk, nil
+}
+
+func (service *Authoritative) ServiceKeyUpdate(ctx context.Context, payload driplimit.ServiceKeyUpdatePayload) (sk *driplimit.ServiceKey, err error) {
+	sk, err = service.store.UpdateServiceKey(ctx, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update service key: %w", err)
+	}
+	return sk, nil
+}
+
+// ServiceKeyCan simulates the action of the service key on the keyspace and returns the
+// decision the authorizer makes along with the rule it is based on.
+func (service *Authoritative) ServiceKeyCan(ctx context.Context, payload driplimit.ServiceKeyCanPayload) (permission *driplimit.Permission, err error) {
+	sk, err := service.store.GetServiceKey(ctx, driplimit.ServiceKeyGetPayload{SKID: payload.SKID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service key: %w", err)
+	}
+
+	permission = &driplimit.Permission{
+		SKID:   sk.SKID,
+		Action: payload.Action,
+		KSID:   payload.KSID,
+	}
+	ids := []string{payload.KSID}
+	ks, err := service.store.GetKeyspaceByID(ctx, payload.KSID)
+	switch {
+	case err == nil:
+		permission.KeyspaceFound = true
+		ids = append(ids, ks.Name)
+	case !errors.Is(err, driplimit.ErrNotFound):
+		return nil, fmt.Errorf("failed to get keyspace: %w", err)
+	}
+	permission.PolicyDecision = sk.Explain(payload.Action, ids...)
+	return permission, nil
+}
+
+// AuditList returns the audit entries matching the given payload.
+func (service *Authoritative) AuditList(ctx context.Context, payload driplimit.AuditListPayload) (alist *driplimit.AuditList, err error) {
+	alist, err = service.store.ListAuditEntries(ctx, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	return alist, nil
+}
@@ -2,6 +2,7 @@ package authoritative_test
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -119,3 +120,540 @@ func TestUnconfiguredRateLimit(t *testing.T) {
 	_, err = app.KeyCheck(ctx, driplimit.KeysCheckPayload{KSID: key.KSID, Token: key.Token})
 	assert.NoError(t, err)
 }
+
+func TestKeyGracePeriod(t *testing.T) {
+	ctx := context.Background()
+	dbHandler, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlite, err := store.New(ctx, dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := authoritative.NewService(sqlite)
+
+	ks, err := app.KeyspaceCreate(ctx, driplimit.KeyspaceCreatePayload{
+		Name:        "test key space",
+		GracePeriod: driplimit.Milliseconds{Duration: time.Hour},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the key inherits the keyspace grace period
+	key, err := app.KeyCreate(ctx, driplimit.KeyCreatePayload{
+		KSID:      ks.KSID,
+		ExpiresAt: time.Now().Add(-time.Minute),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checked, err := app.KeyCheck(ctx, driplimit.KeysCheckPayload{KSID: ks.KSID, Token: key.Token})
+	assert.NoError(t, err)
+	assert.True(t, checked.InGrace)
+
+	// the key overrides the keyspace grace period
+	key, err = app.KeyCreate(ctx, driplimit.KeyCreatePayload{
+		KSID:        ks.KSID,
+		ExpiresAt:   time.Now().Add(-time.Minute),
+		GracePeriod: &driplimit.Milliseconds{Duration: time.Second},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = app.KeyCheck(ctx, driplimit.KeysCheckPayload{KSID: ks.KSID, Token: key.Token})
+	assert.ErrorIs(t, err, driplimit.ErrKeyExpired)
+
+	// the key disables the keyspace grace period
+	key, err = app.KeyCreate(ctx, driplimit.KeyCreatePayload{
+		KSID:        ks.KSID,
+		ExpiresAt:   time.Now().Add(-time.Minute),
+		GracePeriod: &driplimit.Milliseconds{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = app.KeyCheck(ctx, driplimit.KeysCheckPayload{KSID: ks.KSID, Token: key.Token})
+	assert.ErrorIs(t, err, driplimit.ErrKeyExpired)
+	stats, err := app.KeyspaceStats(ctx, driplimit.KeyspaceStatsPayload{KSID: ks.KSID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(2), stats.ExpiredKeys)
+}
+
+func TestKeyMove(t *testing.T) {
+	ctx := context.Background()
+	dbHandler, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlite, err := store.New(ctx, dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := authoritative.NewService(sqlite)
+
+	free, err := app.KeyspaceCreate(ctx, driplimit.KeyspaceCreatePayload{
+		Name:       "free",
+		KeysPrefix: "free_",
+		Ratelimit:  driplimit.RatelimitPayload{Limit: 10},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	premium, err := app.KeyspaceCreate(ctx, driplimit.KeyspaceCreatePayload{
+		Name:       "premium",
+		KeysPrefix: "premium_",
+		Ratelimit:  driplimit.RatelimitPayload{Limit: 1000},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := app.KeyCreate(ctx, driplimit.KeyCreatePayload{
+		KSID:      free.KSID,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken := key.Token
+
+	// the key adopts the target keyspace rate limit and gets a new token
+	moved, err := app.KeyMove(ctx, driplimit.KeyMovePayload{KSID: free.KSID, KID: key.KID, TargetKSID: premium.KSID})
+	assert.NoError(t, err)
+	assert.Equal(t, premium.KSID, moved.KSID)
+	assert.Equal(t, int64(1000), moved.Ratelimit.Limit)
+	assert.Equal(t, int64(1000), moved.Ratelimit.State.Remaining)
+	assert.True(t, strings.HasPrefix(moved.Token, "premium_"))
+
+	_, err = app.KeyCheck(ctx, driplimit.KeysCheckPayload{KSID: free.KSID, Token: oldToken})
+	assert.ErrorIs(t, err, driplimit.ErrNotFound)
+	_, err = app.KeyCheck(ctx, driplimit.KeysCheckPayload{KSID: premium.KSID, Token: moved.Token})
+	assert.NoError(t, err)
+
+	// the token is kept on demand
+	premiumToken := moved.Token
+	moved, err = app.KeyMove(ctx, driplimit.KeyMovePayload{KSID: premium.KSID, KID: key.KID, TargetKSID: free.KSID, KeepToken: true})
+	assert.NoError(t, err)
+	assert.Empty(t, moved.Token)
+	_, err = app.KeyCheck(ctx, driplimit.KeysCheckPayload{KSID: free.KSID, Token: premiumToken})
+	assert.NoError(t, err)
+}
+
+func TestKeyDeleteMany(t *testing.T) {
+	ctx := context.Background()
+	dbHandler, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlite, err := store.New(ctx, dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := authoritative.NewService(sqlite)
+
+	ks, err := app.KeyspaceCreate(ctx, driplimit.KeyspaceCreatePayload{Name: "test key space", KeysPrefix: "test_"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kids := make([]string, 0)
+	for _, expiresAt := range []time.Time{time.Now().Add(-time.Hour), time.Now().Add(-time.Hour), time.Now().Add(time.Hour)} {
+		key, err := app.KeyCreate(ctx, driplimit.KeyCreatePayload{KSID: ks.KSID, ExpiresAt: expiresAt})
+		if err != nil {
+			t.Fatal(err)
+		}
+		kids = append(kids, key.KID)
+	}
+
+	// filters are combined
+	result, err := app.KeyDeleteMany(ctx, driplimit.KeyDeleteManyPayload{KSID: ks.KSID, KIDs: kids[1:], ExpiredBefore: time.Now()})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), result.Deleted)
+
+	result, err = app.KeyDeleteMany(ctx, driplimit.KeyDeleteManyPayload{KSID: ks.KSID, KIDs: kids})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), result.Deleted)
+
+	klist, err := app.KeyList(ctx, driplimit.KeyListPayload{KSID: ks.KSID, List: driplimit.ListPayload{Page: 1, Limit: 10}})
+	assert.NoError(t, err)
+	assert.Len(t, klist.Keys, 0)
+}
+
+func TestKeyspaceUpdate(t *testing.T) {
+	ctx := context.Background()
+	dbHandler, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlite, err := store.New(ctx, dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := authoritative.NewService(sqlite)
+
+	ks, err := app.KeyspaceCreate(ctx, driplimit.KeyspaceCreatePayload{
+		Name:       "free",
+		KeysPrefix: "free_",
+		Ratelimit:  driplimit.RatelimitPayload{Limit: 10},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = app.KeyspaceCreate(ctx, driplimit.KeyspaceCreatePayload{Name: "premium", KeysPrefix: "premium_"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := app.KeyCreate(ctx, driplimit.KeyCreatePayload{
+		KSID:      ks.KSID,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// renaming to an existing keyspace name is rejected
+	taken := "premium"
+	_, err = app.KeyspaceUpdate(ctx, driplimit.KeyspaceUpdatePayload{KSID: ks.KSID, Name: &taken})
+	assert.ErrorIs(t, err, driplimit.ErrAlreadyExists)
+
+	// only the rate limit is updated, keys without their own limit pick it up
+	updated, err := app.KeyspaceUpdate(ctx, driplimit.KeyspaceUpdatePayload{
+		KSID:      ks.KSID,
+		Ratelimit: &driplimit.RatelimitPayload{Limit: 100},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "free", updated.Name)
+	assert.Equal(t, int64(100), updated.Ratelimit.Limit)
+
+	got, err := app.KeyGet(ctx, driplimit.KeyGetPayload{KSID: ks.KSID, KID: key.KID})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), got.Ratelimit.Limit)
+
+	_, err = app.KeyspaceUpdate(ctx, driplimit.KeyspaceUpdatePayload{KSID: "ks_unknown"})
+	assert.ErrorIs(t, err, driplimit.ErrNotFound)
+}
+
+func TestPlans(t *testing.T) {
+	ctx := context.Background()
+	dbHandler, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlite, err := store.New(ctx, dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := authoritative.NewService(sqlite)
+
+	ks, err := app.KeyspaceCreate(ctx, driplimit.KeyspaceCreatePayload{
+		Name:       "api",
+		KeysPrefix: "api_",
+		Ratelimit:  driplimit.RatelimitPayload{Limit: 10},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	premium, err := app.PlanCreate(ctx, driplimit.PlanCreatePayload{
+		KSID:      ks.KSID,
+		Name:      "premium",
+		Ratelimit: driplimit.RatelimitPayload{Limit: 1000},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = app.PlanCreate(ctx, driplimit.PlanCreatePayload{
+		KSID:      ks.KSID,
+		Name:      "premium",
+		Ratelimit: driplimit.RatelimitPayload{Limit: 1000},
+	})
+	assert.ErrorIs(t, err, driplimit.ErrAlreadyExists)
+
+	expiresAt := time.Now().Add(time.Hour)
+	planKey, err := app.KeyCreate(ctx, driplimit.KeyCreatePayload{KSID: ks.KSID, PLID: premium.PLID, ExpiresAt: expiresAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	overrideKey, err := app.KeyCreate(ctx, driplimit.KeyCreatePayload{
+		KSID:      ks.KSID,
+		PLID:      premium.PLID,
+		ExpiresAt: expiresAt,
+		Ratelimit: driplimit.RatelimitPayload{Limit: 5},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = app.KeyCreate(ctx, driplimit.KeyCreatePayload{KSID: ks.KSID, PLID: "pl_unknown", ExpiresAt: expiresAt})
+	assert.ErrorIs(t, err, driplimit.ErrNotFound)
+
+	// key override -> plan -> keyspace default
+	limit := func(kid string) int64 {
+		key, err := app.KeyGet(ctx, driplimit.KeyGetPayload{KSID: ks.KSID, KID: kid})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key.Ratelimit.Limit
+	}
+	assert.Equal(t, int64(1000), limit(planKey.KID))
+	assert.Equal(t, int64(5), limit(overrideKey.KID))
+
+	// updating the plan affects all its keys at once
+	_, err = app.PlanUpdate(ctx, driplimit.PlanUpdatePayload{
+		KSID:      ks.KSID,
+		PLID:      premium.PLID,
+		Ratelimit: &driplimit.RatelimitPayload{Limit: 2000},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2000), limit(planKey.KID))
+	assert.Equal(t, int64(5), limit(overrideKey.KID))
+
+	plist, err := app.PlanList(ctx, driplimit.PlanListPayload{KSID: ks.KSID, List: driplimit.ListPayload{Page: 1, Limit: 10}})
+	assert.NoError(t, err)
+	assert.Len(t, plist.Plans, 1)
+
+	// deleting the plan falls back to the keyspace default
+	err = app.PlanDelete(ctx, driplimit.PlanDeletePayload{KSID: ks.KSID, PLID: premium.PLID})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), limit(planKey.KID))
+	_, err = app.PlanGet(ctx, driplimit.PlanGetPayload{KSID: ks.KSID, PLID: premium.PLID})
+	assert.ErrorIs(t, err, driplimit.ErrNotFound)
+}
+
+func TestKeyspaceExportImport(t *testing.T) {
+	ctx := context.Background()
+	newService := func() *authoritative.Authoritative {
+		dbHandler, err := sqlx.Open("sqlite3", ":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sqlite, err := store.New(ctx, dbHandler)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return authoritative.NewService(sqlite)
+	}
+	staging := newService()
+	production := newService()
+
+	ks, err := staging.KeyspaceCreate(ctx, driplimit.KeyspaceCreatePayload{
+		Name:       "api",
+		KeysPrefix: "api_",
+		Ratelimit:  driplimit.RatelimitPayload{Limit: 10},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := staging.PlanCreate(ctx, driplimit.PlanCreatePayload{
+		KSID:      ks.KSID,
+		Name:      "premium",
+		Ratelimit: driplimit.RatelimitPayload{Limit: 1000},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := staging.KeyCreate(ctx, driplimit.KeyCreatePayload{
+		KSID:      ks.KSID,
+		PLID:      plan.PLID,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bundle, err := staging.KeyspaceExport(ctx, driplimit.KeyspaceExportPayload{KSID: ks.KSID})
+	assert.NoError(t, err)
+	assert.Equal(t, driplimit.KeyspaceBundleVersion, bundle.Version)
+	assert.Len(t, bundle.Plans, 1)
+	assert.Len(t, bundle.Keys, 1)
+
+	// tokens remain valid once imported
+	result, err := production.KeyspaceImport(ctx, driplimit.KeyspaceImportPayload{Bundle: *bundle, Strategy: driplimit.ImportFail})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, result.Created)
+	checked, err := production.KeyCheck(ctx, driplimit.KeysCheckPayload{KSID: ks.KSID, Token: key.Token})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1000), checked.Ratelimit.Limit)
+
+	// existing items fail, are skipped or overwritten depending on the strategy
+	_, err = production.KeyspaceImport(ctx, driplimit.KeyspaceImportPayload{Bundle: *bundle, Strategy: driplimit.ImportFail})
+	assert.ErrorIs(t, err, driplimit.ErrAlreadyExists)
+
+	result, err = production.KeyspaceImport(ctx, driplimit.KeyspaceImportPayload{Bundle: *bundle, Strategy: driplimit.ImportSkip})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, result.Skipped)
+
+	bundle.Keyspace.Name = "api (imported)"
+	result, err = production.KeyspaceImport(ctx, driplimit.KeyspaceImportPayload{Bundle: *bundle, Strategy: driplimit.ImportOverwrite})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, result.Overwritten)
+	assert.Equal(t, "api (imported)", result.Keyspace.Name)
+
+	// plans and keys of other keyspaces are never taken over
+	other := *bundle
+	otherKS := *bundle.Keyspace
+	otherKS.KSID, otherKS.Name, otherKS.KeysPrefix = "ks_other", "other", "other_"
+	other.Keyspace = &otherKS
+	other.Policies = nil
+	for _, strategy := range []driplimit.ImportStrategy{driplimit.ImportSkip, driplimit.ImportOverwrite} {
+		_, err = production.KeyspaceImport(ctx, driplimit.KeyspaceImportPayload{Bundle: other, Strategy: strategy})
+		assert.ErrorIs(t, err, driplimit.ErrAlreadyExists, strategy)
+	}
+	otherKey := *bundle.Keys[0]
+	otherKey.PLID = ""
+	other.Plans, other.Keys = nil, []*driplimit.KeyspaceBundleKey{&otherKey}
+	for _, strategy := range []driplimit.ImportStrategy{driplimit.ImportSkip, driplimit.ImportOverwrite} {
+		_, err = production.KeyspaceImport(ctx, driplimit.KeyspaceImportPayload{Bundle: other, Strategy: strategy})
+		assert.ErrorIs(t, err, driplimit.ErrAlreadyExists, strategy)
+	}
+	checked, err = production.KeyCheck(ctx, driplimit.KeysCheckPayload{KSID: ks.KSID, Token: key.Token})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1000), checked.Ratelimit.Limit)
+	_, err = production.KeyspaceGet(ctx, driplimit.KeyspaceGetPayload{KSID: "ks_other"})
+	assert.ErrorIs(t, err, driplimit.ErrNotFound)
+}
+
+func TestKeyspaceStats(t *testing.T) {
+	ctx := context.Background()
+	dbHandler, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlite, err := store.New(ctx, dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := authoritative.NewService(sqlite)
+
+	ks, err := app.KeyspaceCreate(ctx, driplimit.KeyspaceCreatePayload{
+		Name:       "api",
+		KeysPrefix: "api_",
+		Ratelimit:  driplimit.RatelimitPayload{Limit: 1, RefillRate: 1, RefillInterval: driplimit.Milliseconds{Duration: time.Hour}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	create := func(payload driplimit.KeyCreatePayload) *driplimit.Key {
+		payload.KSID = ks.KSID
+		key, err := app.KeyCreate(ctx, payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key
+	}
+	create(driplimit.KeyCreatePayload{ExpiresAt: time.Now().Add(-time.Hour)})
+	create(driplimit.KeyCreatePayload{ExpiresAt: time.Now().Add(time.Hour), Ratelimit: driplimit.RatelimitPayload{Limit: 5}})
+	used := create(driplimit.KeyCreatePayload{ExpiresAt: time.Now().Add(time.Hour)})
+	deleted := create(driplimit.KeyCreatePayload{ExpiresAt: time.Now().Add(time.Hour)})
+
+	// consumes the only token of the keyspace default rate limit until the next refill
+	_, err = app.KeyCheck(ctx, driplimit.KeysCheckPayload{KSID: ks.KSID, Token: used.Token})
+	assert.NoError(t, err)
+	err = app.KeyDelete(ctx, driplimit.KeyDeletePayload{KSID: ks.KSID, KID: deleted.KID})
+	assert.NoError(t, err)
+
+	stats, err := app.KeyspaceStats(ctx, driplimit.KeyspaceStatsPayload{KSID: ks.KSID})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), stats.ActiveKeys)
+	assert.Equal(t, int64(1), stats.ExpiredKeys)
+	assert.Equal(t, int64(1), stats.DeletedKeys)
+	assert.Equal(t, int64(1), stats.CustomRatelimitKeys)
+	assert.Equal(t, int64(2), stats.DefaultRatelimitKeys)
+	assert.Equal(t, int64(1), stats.UsedLast24h)
+	assert.Equal(t, int64(1), stats.UsedLast7d)
+	assert.Equal(t, int64(1), stats.ExhaustedKeys)
+}
+
+func TestKeyspaceClone(t *testing.T) {
+	ctx := context.Background()
+	dbHandler, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlite, err := store.New(ctx, dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := authoritative.NewService(sqlite)
+
+	ks, err := app.KeyspaceCreate(ctx, driplimit.KeyspaceCreatePayload{
+		Name:        "acme",
+		KeysPrefix:  "acme_",
+		GracePeriod: driplimit.Milliseconds{Duration: time.Minute},
+		Ratelimit:   driplimit.RatelimitPayload{Limit: 10},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := app.PlanCreate(ctx, driplimit.PlanCreatePayload{
+		KSID:      ks.KSID,
+		Name:      "premium",
+		Ratelimit: driplimit.RatelimitPayload{Limit: 1000},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := app.KeyCreate(ctx, driplimit.KeyCreatePayload{KSID: ks.KSID, PLID: plan.PLID, ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = app.KeyspaceClone(ctx, driplimit.KeyspaceClonePayload{KSID: ks.KSID, NameSuffix: " - staging", KeysPrefix: "acme_"})
+	assert.ErrorIs(t, err, driplimit.ErrAlreadyExists)
+
+	clone, err := app.KeyspaceClone(ctx, driplimit.KeyspaceClonePayload{KSID: ks.KSID, NameSuffix: " - staging", KeysPrefix: "staging_", CopyKeys: true})
+	assert.NoError(t, err)
+	assert.NotEqual(t, ks.KSID, clone.KSID)
+	assert.Equal(t, "acme - staging", clone.Name)
+	assert.Equal(t, "staging_", clone.KeysPrefix)
+	assert.Equal(t, ks.GracePeriod, clone.GracePeriod)
+	assert.Equal(t, int64(10), clone.Ratelimit.Limit)
+
+	// copied keys keep their token and reference the cloned plan
+	copied, err := app.KeyCheck(ctx, driplimit.KeysCheckPayload{KSID: clone.KSID, Token: key.Token})
+	assert.NoError(t, err)
+	assert.NotEqual(t, key.KID, copied.KID)
+	assert.NotEqual(t, plan.PLID, copied.PLID)
+	assert.Equal(t, int64(1000), copied.Ratelimit.Limit)
+}
+
+func TestServiceKeyCreateWithUnknownRole(t *testing.T) {
+	ctx := context.Background()
+	dbHandler, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlite, err := store.New(ctx, dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := authoritative.NewService(sqlite)
+
+	ks, err := app.KeyspaceCreate(ctx, driplimit.KeyspaceCreatePayload{Name: "acme", KeysPrefix: "acme_"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the service key is only created along with its policies and roles
+	_, err = app.ServiceKeyCreate(ctx, driplimit.ServiceKeyCreatePayload{
+		SKID:              "sk_orphan",
+		Description:       "service key with an unknown role",
+		KeyspacesPolicies: driplimit.Policies{ks.KSID: {Check: true}},
+		Roles:             []string{"rl_unknown"},
+	})
+	assert.ErrorIs(t, err, driplimit.ErrNotFound)
+	_, err = app.ServiceKeyGet(ctx, driplimit.ServiceKeyGetPayload{SKID: "sk_orphan"})
+	assert.ErrorIs(t, err, driplimit.ErrNotFound)
+	policies, err := sqlite.GetKeyspacesPolicies(ctx, "sk_orphan")
+	assert.NoError(t, err)
+	assert.Empty(t, policies)
+
+	sk, err := app.ServiceKeyCreate(ctx, driplimit.ServiceKeyCreatePayload{
+		SKID:              "sk_orphan",
+		Description:       "service key without role",
+		KeyspacesPolicies: driplimit.Policies{ks.KSID: {Check: true}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "sk_orphan", sk.SKID)
+}
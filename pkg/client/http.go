@@ -3,6 +3,7 @@ package client
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,20 +12,27 @@ import (
 	"time"
 
 	"github.com/i4n-co/driplimit"
+	"github.com/i4n-co/driplimit/pkg/generate"
 )
 
 // HTTP is a driplimit http client that implements the driplimit service
 type HTTP struct {
 	upstreamURL     string
+	timeout         time.Duration
 	sendRequestFunc func(req *http.Request) (*http.Response, error)
 	serviceToken    string
+	signingSKID     string
+	signingSecret   string
 }
 
 func (http *HTTP) clone() *HTTP {
 	return &HTTP{
 		upstreamURL:     http.upstreamURL,
+		timeout:         http.timeout,
 		sendRequestFunc: http.sendRequestFunc,
 		serviceToken:    http.serviceToken,
+		signingSKID:     http.signingSKID,
+		signingSecret:   http.signingSecret,
 	}
 }
 
@@ -34,20 +42,34 @@ func New(upstreamURL string, timeout ...time.Duration) *HTTP {
 	if len(timeout) > 0 && timeout[0] > 0 {
 		timeoutDuration = timeout[0]
 	}
+	return &HTTP{
+		upstreamURL:     upstreamURL,
+		timeout:         timeoutDuration,
+		sendRequestFunc: newHTTPClient(timeoutDuration, nil).Do,
+	}
+}
+
+// newHTTPClient returns an http client with the given timeout and TLS configuration
+func newHTTPClient(timeout time.Duration, tlsConfig *tls.Config) *http.Client {
 	transport := &http.Transport{
 		DialContext: (&net.Dialer{
-			Timeout: timeoutDuration,
+			Timeout: timeout,
 		}).DialContext,
-		TLSHandshakeTimeout: timeoutDuration,
+		TLSHandshakeTimeout: timeout,
+		TLSClientConfig:     tlsConfig,
 	}
-	client := &http.Client{
-		Timeout:   timeoutDuration,
+	return &http.Client{
+		Timeout:   timeout,
 		Transport: transport,
 	}
-	return &HTTP{
-		upstreamURL:     upstreamURL,
-		sendRequestFunc: client.Do,
-	}
+}
+
+// WithTLSConfig sends the requests with the given TLS configuration, e.g. to trust a private
+// CA or to present a client certificate authenticating the requests without service token.
+func (h *HTTP) WithTLSConfig(tlsConfig *tls.Config) *HTTP {
+	nh := h.clone()
+	nh.sendRequestFunc = newHTTPClient(h.timeout, tlsConfig).Do
+	return nh
 }
 
 // WithSendRequestFunc replace the default client http Do func by a custom one.
@@ -65,6 +87,17 @@ func (h *HTTP) WithServiceToken(token string) *HTTP {
 	return nh
 }
 
+// WithSigningSecret signs all requests on behalf of the service key skid with its signing
+// secret instead of sending a bearer token. Payloads with their own service token are
+// still sent with a bearer token. Signed requests are only supported by authoritative
+// servers (including async authoritative ones): proxies reject them.
+func (h *HTTP) WithSigningSecret(skid, secret string) *HTTP {
+	nh := h.clone()
+	nh.signingSKID = skid
+	nh.signingSecret = secret
+	return nh
+}
+
 // do sends a request to the upstream server
 func do[K any](ctx context.Context, c *HTTP, action string, payload driplimit.Payload, target ...K) (err error) {
 	buf := new(bytes.Buffer)
@@ -79,12 +112,19 @@ func do[K any](ctx context.Context, c *HTTP, action string, payload driplimit.Pa
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", "driplimit")
+	// proxies forward the address of their own client, upstream must trust them to use it
+	if addr, ok := ctx.Value(driplimit.RemoteAddrKey).(string); ok && addr != "" {
+		req.Header.Set("X-Forwarded-For", addr)
+	}
 	if c.serviceToken != "" {
 		req.Header.Set("Authorization", "Bearer "+c.serviceToken)
 	}
 
 	if payload != nil && payload.ServiceToken() != "" {
 		req.Header.Set("Authorization", "Bearer "+payload.ServiceToken())
+	} else if c.signingSecret != "" {
+		signature := driplimit.NewRequestSignature(c.signingSKID, req.Method, req.URL.Path, buf.Bytes(), time.Now(), generate.ID())
+		req.Header.Set("Authorization", signature.Sign(c.signingSecret).Header())
 	}
 
 	resp, err := c.sendRequestFunc(req)
@@ -108,7 +148,8 @@ func do[K any](ctx context.Context, c *HTTP, action string, payload driplimit.Pa
 	fmt.Println(string(b))
 
 	r.Seek(0, 0)
-	if len(target) > 0 {
+	// no content responses have no body to decode
+	if len(target) > 0 && resp.StatusCode != http.StatusNoContent {
 		err = json.NewDecoder(r).Decode(target[0])
 		if err != nil {
 			return fmt.Errorf("failed to decode response: %w", err)
@@ -127,6 +168,15 @@ func (c *HTTP) KeyCheck(ctx context.Context, payload driplimit.KeysCheckPayload)
 	return key, nil
 }
 
+func (c *HTTP) KeyCheckBatch(ctx context.Context, payload driplimit.KeysCheckBatchPayload) (batch *driplimit.KeysCheckBatch, err error) {
+	batch = new(driplimit.KeysCheckBatch)
+	err = do(ctx, c, "/v1/keys.check_batch", payload, batch)
+	if err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
 func (c *HTTP) KeyCreate(ctx context.Context, payload driplimit.KeyCreatePayload) (key *driplimit.Key, err error) {
 	key = new(driplimit.Key)
 	err = do(ctx, c, "/v1/keys.create", payload, key)
@@ -162,6 +212,33 @@ func (c *HTTP) KeyDelete(ctx context.Context, payload driplimit.KeyDeletePayload
 	return nil
 }
 
+func (c *HTTP) KeyDeleteMany(ctx context.Context, payload driplimit.KeyDeleteManyPayload) (result *driplimit.KeyDeleteManyResult, err error) {
+	result = new(driplimit.KeyDeleteManyResult)
+	err = do(ctx, c, "/v1/keys.delete_many", payload, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *HTTP) KeyReset(ctx context.Context, payload driplimit.KeyResetPayload) (key *driplimit.Key, err error) {
+	key = new(driplimit.Key)
+	err = do(ctx, c, "/v1/keys.reset", payload, key)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (c *HTTP) KeyMove(ctx context.Context, payload driplimit.KeyMovePayload) (key *driplimit.Key, err error) {
+	key = new(driplimit.Key)
+	err = do(ctx, c, "/v1/keys.move", payload, key)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
 func (c *HTTP) KeyspaceGet(ctx context.Context, payload driplimit.KeyspaceGetPayload) (keyspace *driplimit.Keyspace, err error) {
 	keyspace = new(driplimit.Keyspace)
 	err = do(ctx, c, "/v1/keyspaces.get", payload, keyspace)
@@ -189,6 +266,15 @@ func (c *HTTP) KeyspaceList(ctx context.Context, payload driplimit.KeyspaceListP
 	return kslist, nil
 }
 
+func (c *HTTP) KeyspaceUpdate(ctx context.Context, payload driplimit.KeyspaceUpdatePayload) (keyspace *driplimit.Keyspace, err error) {
+	keyspace = new(driplimit.Keyspace)
+	err = do(ctx, c, "/v1/keyspaces.update", payload, keyspace)
+	if err != nil {
+		return nil, err
+	}
+	return keyspace, nil
+}
+
 func (c *HTTP) KeyspaceDelete(ctx context.Context, payload driplimit.KeyspaceDeletePayload) (err error) {
 	err = do(ctx, c, "/v1/keyspaces.delete", payload, make(map[any]any))
 	if err != nil {
@@ -197,6 +283,130 @@ func (c *HTTP) KeyspaceDelete(ctx context.Context, payload driplimit.KeyspaceDel
 	return nil
 }
 
+func (c *HTTP) KeyspaceClone(ctx context.Context, payload driplimit.KeyspaceClonePayload) (keyspace *driplimit.Keyspace, err error) {
+	keyspace = new(driplimit.Keyspace)
+	err = do(ctx, c, "/v1/keyspaces.clone", payload, keyspace)
+	if err != nil {
+		return nil, err
+	}
+	return keyspace, nil
+}
+
+func (c *HTTP) KeyspaceStats(ctx context.Context, payload driplimit.KeyspaceStatsPayload) (stats *driplimit.KeyspaceStats, err error) {
+	stats = new(driplimit.KeyspaceStats)
+	err = do(ctx, c, "/v1/keyspaces.stats", payload, stats)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func (c *HTTP) KeyspaceExport(ctx context.Context, payload driplimit.KeyspaceExportPayload) (bundle *driplimit.KeyspaceBundle, err error) {
+	bundle = new(driplimit.KeyspaceBundle)
+	err = do(ctx, c, "/v1/keyspaces.export", payload, bundle)
+	if err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+func (c *HTTP) KeyspaceImport(ctx context.Context, payload driplimit.KeyspaceImportPayload) (result *driplimit.KeyspaceImportResult, err error) {
+	result = new(driplimit.KeyspaceImportResult)
+	err = do(ctx, c, "/v1/keyspaces.import", payload, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *HTTP) PlanGet(ctx context.Context, payload driplimit.PlanGetPayload) (plan *driplimit.Plan, err error) {
+	plan = new(driplimit.Plan)
+	err = do(ctx, c, "/v1/plans.get", payload, plan)
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func (c *HTTP) PlanCreate(ctx context.Context, payload driplimit.PlanCreatePayload) (plan *driplimit.Plan, err error) {
+	plan = new(driplimit.Plan)
+	err = do(ctx, c, "/v1/plans.create", payload, plan)
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func (c *HTTP) PlanList(ctx context.Context, payload driplimit.PlanListPayload) (plist *driplimit.PlanList, err error) {
+	plist = new(driplimit.PlanList)
+	err = do(ctx, c, "/v1/plans.list", payload, plist)
+	if err != nil {
+		return nil, err
+	}
+	return plist, nil
+}
+
+func (c *HTTP) PlanUpdate(ctx context.Context, payload driplimit.PlanUpdatePayload) (plan *driplimit.Plan, err error) {
+	plan = new(driplimit.Plan)
+	err = do(ctx, c, "/v1/plans.update", payload, plan)
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func (c *HTTP) PlanDelete(ctx context.Context, payload driplimit.PlanDeletePayload) (err error) {
+	err = do(ctx, c, "/v1/plans.delete", payload, make(map[any]any))
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *HTTP) RoleGet(ctx context.Context, payload driplimit.RoleGetPayload) (role *driplimit.Role, err error) {
+	role = new(driplimit.Role)
+	err = do(ctx, c, "/v1/roles.get", payload, role)
+	if err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+func (c *HTTP) RoleCreate(ctx context.Context, payload driplimit.RoleCreatePayload) (role *driplimit.Role, err error) {
+	role = new(driplimit.Role)
+	err = do(ctx, c, "/v1/roles.create", payload, role)
+	if err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+func (c *HTTP) RoleList(ctx context.Context, payload driplimit.RoleListPayload) (rlist *driplimit.RoleList, err error) {
+	rlist = new(driplimit.RoleList)
+	err = do(ctx, c, "/v1/roles.list", payload, rlist)
+	if err != nil {
+		return nil, err
+	}
+	return rlist, nil
+}
+
+func (c *HTTP) RoleUpdate(ctx context.Context, payload driplimit.RoleUpdatePayload) (role *driplimit.Role, err error) {
+	role = new(driplimit.Role)
+	err = do(ctx, c, "/v1/roles.update", payload, role)
+	if err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+func (c *HTTP) RoleDelete(ctx context.Context, payload driplimit.RoleDeletePayload) (err error) {
+	err = do(ctx, c, "/v1/roles.delete", payload, make(map[any]any))
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
 // ServiceKeyGet returns a service key based on the given payload
 func (c *HTTP) ServiceKeyCurrent(ctx context.Context) (sk *driplimit.ServiceKey, err error) {
 	sk = new(driplimit.ServiceKey)
@@ -250,3 +460,57 @@ func (c *HTTP) ServiceKeySetToken(ctx context.Context, payload driplimit.Service
 	}
 	return nil
 }
+
+func (c *HTTP) ServiceKeyRotate(ctx context.Context, payload driplimit.ServiceKeyRotatePayload) (sk *driplimit.ServiceKey, err error) {
+	sk = new(driplimit.ServiceKey)
+	err = do(ctx, c, "/v1/serviceKeys.rotate", payload, sk)
+	if err != nil {
+		return nil, err
+	}
+	return sk, nil
+}
+
+func (c *HTTP) ServiceKeyPromote(ctx context.Context, payload driplimit.ServiceKeyPromotePayload) (sk *driplimit.ServiceKey, err error) {
+	sk = new(driplimit.ServiceKey)
+	err = do(ctx, c, "/v1/serviceKeys.promote", payload, sk)
+	if err != nil {
+		return nil, err
+	}
+	return sk, nil
+}
+
+func (c *HTTP) ServiceKeyRevokePrevious(ctx context.Context, payload driplimit.ServiceKeyRevokePreviousPayload) (sk *driplimit.ServiceKey, err error) {
+	sk = new(driplimit.ServiceKey)
+	err = do(ctx, c, "/v1/serviceKeys.revoke_previous", payload, sk)
+	if err != nil {
+		return nil, err
+	}
+	return sk, nil
+}
+
+func (c *HTTP) ServiceKeyUpdate(ctx context.Context, payload driplimit.ServiceKeyUpdatePayload) (sk *driplimit.ServiceKey, err error) {
+	sk = new(driplimit.ServiceKey)
+	err = do(ctx, c, "/v1/serviceKeys.update", payload, sk)
+	if err != nil {
+		return nil, err
+	}
+	return sk, nil
+}
+
+func (c *HTTP) ServiceKeyCan(ctx context.Context, payload driplimit.ServiceKeyCanPayload) (permission *driplimit.Permission, err error) {
+	permission = new(driplimit.Permission)
+	err = do(ctx, c, "/v1/serviceKeys.can", payload, permission)
+	if err != nil {
+		return nil, err
+	}
+	return permission, nil
+}
+
+func (c *HTTP) AuditList(ctx context.Context, payload driplimit.AuditListPayload) (alist *driplimit.AuditList, err error) {
+	alist = new(driplimit.AuditList)
+	err = do(ctx, c, "/v1/audit.list", payload, alist)
+	if err != nil {
+		return nil, err
+	}
+	return alist, nil
+}
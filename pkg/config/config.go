@@ -42,20 +42,38 @@ func (m Mode) isValid() bool {
 
 // Config represents the configuration of the service
 type Config struct {
-	Addr                 string        `env:"ADDR, default=127.0.0.1" description:"address to listen on"`
-	CacheDuration        time.Duration `env:"CACHE_DURATION, default=30s" description:"cache entries time-to-live"`
-	DatabaseName         string        `env:"DATABASE_NAME, default=driplimit.db" description:"database file name"`
-	DataDir              string        `env:"DATA_DIR" description:"directory where the database file is stored"`
-	GzipCompression      bool          `env:"GZIP_COMPRESSION, default=false" description:"enable gzip compression"`
-	KeysCacheSize        int           `env:"KEYS_CACHE_SIZE, default=65536" description:"maximum number of keys in the cache"`
-	LogFormat            string        `env:"LOG_FORMAT, default=text" description:"log format (text or json)"`
-	LogSeverity          string        `env:"LOG_SEVERITY, default=info" description:"log severity level (debug, info, warn, error)"`
-	Mode                 Mode          `env:"MODE, default=authoritative" description:"service mode (authoritative, async_authoritative, proxy)"`
-	Port                 int           `env:"PORT, default=7131" description:"port to listen on"`
-	ServiceKeysCacheSize int           `env:"SERVICE_KEYS_CACHE_SIZE, default=2048" description:"maximum number of service keys in the cache"`
-	UpstreamTimeout      time.Duration `env:"UPSTREAM_TIMEOUT, default=5s" description:"timeout for upstream requests"`
-	UpstreamURL          string        `env:"UPSTREAM_URL" description:"upstream URL for proxy mode or SDK client"`
-	RootServiceKeyToken  string        `env:"ROOT_SERVICE_KEY_TOKEN" description:"create a root service key at startup with this token"`
+	Addr                          string        `env:"ADDR, default=127.0.0.1" description:"address to listen on"`
+	APIRatelimitLimit             int64         `env:"API_RATELIMIT_LIMIT, default=0" description:"default rate limit of the service keys calls to the driplimit API (disabled if 0)"`
+	APIRatelimitRefillInterval    time.Duration `env:"API_RATELIMIT_REFILL_INTERVAL, default=1s" description:"default refill interval of the service keys API rate limit"`
+	APIRatelimitRefillRate        int64         `env:"API_RATELIMIT_REFILL_RATE, default=0" description:"default refill rate of the service keys API rate limit"`
+	CacheDuration                 time.Duration `env:"CACHE_DURATION, default=30s" description:"cache entries time-to-live"`
+	ChecksRatelimitExempt         bool          `env:"CHECKS_RATELIMIT_EXEMPT, default=false" description:"exempt keys.check and keys.check_batch calls from the API rate limit when they have no rate limit of their own"`
+	ChecksRatelimitLimit          int64         `env:"CHECKS_RATELIMIT_LIMIT, default=0" description:"default rate limit of the service keys keys.check and keys.check_batch calls, apart from the API rate limit (disabled if 0)"`
+	ChecksRatelimitRefillInterval time.Duration `env:"CHECKS_RATELIMIT_REFILL_INTERVAL, default=1s" description:"default refill interval of the service keys checks rate limit"`
+	ChecksRatelimitRefillRate     int64         `env:"CHECKS_RATELIMIT_REFILL_RATE, default=0" description:"default refill rate of the service keys checks rate limit"`
+	DatabaseName                  string        `env:"DATABASE_NAME, default=driplimit.db" description:"database file name"`
+	DataDir                       string        `env:"DATA_DIR" description:"directory where the database file is stored"`
+	GzipCompression               bool          `env:"GZIP_COMPRESSION, default=false" description:"enable gzip compression"`
+	JWTAudience                   string        `env:"JWT_AUDIENCE" description:"expected audience of the JWT bearer tokens"`
+	JWTIssuer                     string        `env:"JWT_ISSUER" description:"expected issuer of the JWT bearer tokens"`
+	JWTJWKS                       string        `env:"JWT_JWKS" description:"path or URL of the JWKS verifying the JWT bearer tokens (JWT authentication is disabled if empty)"`
+	JWTPoliciesClaim              string        `env:"JWT_POLICIES_CLAIM, default=keyspaces_policies" description:"claim holding the keyspaces policies of the JWT bearer tokens without service key id"`
+	JWTSKIDClaim                  string        `env:"JWT_SKID_CLAIM, default=skid" description:"claim holding the service key id of the JWT bearer tokens"`
+	KeysCacheSize                 int           `env:"KEYS_CACHE_SIZE, default=65536" description:"maximum number of keys in the cache"`
+	LogFormat                     string        `env:"LOG_FORMAT, default=text" description:"log format (text or json)"`
+	LogSeverity                   string        `env:"LOG_SEVERITY, default=info" description:"log severity level (debug, info, warn, error)"`
+	Mode                          Mode          `env:"MODE, default=authoritative" description:"service mode (authoritative, async_authoritative, proxy)"`
+	Port                          int           `env:"PORT, default=7131" description:"port to listen on"`
+	ServiceKeysCacheSize          int           `env:"SERVICE_KEYS_CACHE_SIZE, default=2048" description:"maximum number of service keys in the cache"`
+	ServiceKeysUsageInterval      time.Duration `env:"SERVICE_KEYS_USAGE_INTERVAL, default=10s" description:"interval between two batched recordings of the service keys last use"`
+	SignatureNoncesSize           int           `env:"SIGNATURE_NONCES_SIZE, default=65536" description:"maximum number of signed requests nonces remembered to prevent replays"`
+	TLSCertFile                   string        `env:"TLS_CERT_FILE" description:"path of the PEM encoded TLS certificate (TLS is disabled if empty)"`
+	TLSClientCAFile               string        `env:"TLS_CLIENT_CA_FILE" description:"path of the PEM encoded CA certificates verifying the TLS client certificates (client certificate authentication is disabled if empty)"`
+	TLSKeyFile                    string        `env:"TLS_KEY_FILE" description:"path of the PEM encoded TLS private key"`
+	TrustedProxies                []string      `env:"TRUSTED_PROXIES" description:"comma separated addresses or CIDRs of the proxies trusted to set the X-Forwarded-For header, driplimit proxies included"`
+	UpstreamTimeout               time.Duration `env:"UPSTREAM_TIMEOUT, default=5s" description:"timeout for upstream requests"`
+	UpstreamURL                   string        `env:"UPSTREAM_URL" description:"upstream URL for proxy mode or SDK client"`
+	RootServiceKeyToken           string        `env:"ROOT_SERVICE_KEY_TOKEN" description:"create a root service key at startup with this token"`
 
 	logger *slog.Logger
 }
@@ -154,9 +172,63 @@ func (c *Config) validate() error {
 	if c.Mode == Proxy && c.UpstreamURL == "" {
 		return fmt.Errorf("upstream URL is required for proxy mode")
 	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return fmt.Errorf("TLS certificate and key files are both required")
+	}
+	if c.TLSClientCAFile != "" && c.TLSCertFile == "" {
+		return fmt.Errorf("TLS certificate is required for client certificate authentication")
+	}
+	if c.TLSClientCAFile != "" && c.Mode == Proxy {
+		// the certificate identity cannot be proven to the upstream server
+		return fmt.Errorf("client certificate authentication is not supported in proxy mode")
+	}
+	if c.ServiceKeysUsageInterval <= 0 {
+		return fmt.Errorf("invalid service keys usage interval: %s", c.ServiceKeysUsageInterval)
+	}
+	if c.APIRatelimitLimit < 0 || c.APIRatelimitRefillRate < 0 || c.ChecksRatelimitLimit < 0 || c.ChecksRatelimitRefillRate < 0 {
+		return fmt.Errorf("rate limits cannot be negative")
+	}
+	if _, err := c.TrustedProxyNetworks(); err != nil {
+		return err
+	}
 	return nil
 }
 
+// UseTLS returns true if the API server is served over TLS
+func (c *Config) UseTLS() bool {
+	return c.TLSCertFile != ""
+}
+
+// TrustedProxyNetworks returns the networks of the trusted proxies. Addresses are single
+// host networks.
+func (c *Config) TrustedProxyNetworks() ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(c.TrustedProxies))
+	for _, proxy := range c.TrustedProxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy: %s", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy: %s", proxy)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
 // InMemoryDatabase returns true if the service is using an in-memory database
 func (c *Config) InMemoryDatabase() bool {
 	return c.DataDir == ""
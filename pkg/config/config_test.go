@@ -27,3 +27,31 @@ func TestFromEnvFile(t *testing.T) {
 	assert.Equal(t, "debug", cfg.LogSeverity)
 	assert.Equal(t, 7131, cfg.Port)
 }
+
+func TestTrustedProxies(t *testing.T) {
+	file := "TRUSTED_PROXIES=10.0.0.1,192.168.0.0/16,::1\n"
+
+	cfg, err := config.FromEnvFile(context.Background(), strings.NewReader(file))
+	assert.NoError(t, err)
+	networks, err := cfg.TrustedProxyNetworks()
+	assert.NoError(t, err)
+	assert.Len(t, networks, 3)
+	assert.Equal(t, "10.0.0.1/32", networks[0].String())
+	assert.Equal(t, "192.168.0.0/16", networks[1].String())
+	assert.Equal(t, "::1/128", networks[2].String())
+
+	_, err = config.FromEnvFile(context.Background(), strings.NewReader("TRUSTED_PROXIES=proxy.internal\n"))
+	assert.Error(t, err)
+}
+
+func TestClientCertificatesInProxyMode(t *testing.T) {
+	file := "MODE=proxy\n"
+	file += "UPSTREAM_URL=http://upstream.test\n"
+	file += "TLS_CERT_FILE=cert.pem\n"
+	file += "TLS_KEY_FILE=key.pem\n"
+
+	_, err := config.FromEnvFile(context.Background(), strings.NewReader(file))
+	assert.NoError(t, err)
+	_, err = config.FromEnvFile(context.Background(), strings.NewReader(file+"TLS_CLIENT_CA_FILE=ca.pem\n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,269 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// reloadInterval is the minimum interval between two JWKS reloads triggered by unknown key ids.
+const reloadInterval = time.Minute
+
+// jwk is a JSON Web Key. Only the public key members are used.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey is a verification key of the JWKS, restricted to the alg algorithm if not empty.
+type publicKey struct {
+	kid string
+	alg string
+	key crypto.PublicKey
+}
+
+// Verifier verifies tokens against the keys of a JWKS loaded from a local file or an URL.
+// The JWKS is reloaded when a token is signed with an unknown key id, at most once per
+// reloadInterval whether the reload succeeds or not.
+type Verifier struct {
+	source   string
+	issuer   string
+	audience string
+	client   *http.Client
+
+	mu   sync.RWMutex
+	keys []publicKey
+
+	reloading  sync.Mutex
+	reloadedAt time.Time
+}
+
+// NewVerifier returns a verifier of the tokens issued by issuer for audience, signed with
+// one of the keys of the JWKS found at source (a file path or an http(s) URL). Empty issuer
+// or audience are not checked.
+func NewVerifier(ctx context.Context, source, issuer, audience string) (*Verifier, error) {
+	verifier := &Verifier{
+		source:   source,
+		issuer:   issuer,
+		audience: audience,
+		client:   &http.Client{Timeout: 5 * time.Second},
+	}
+	err := verifier.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	verifier.reloadedAt = time.Now()
+	return verifier, nil
+}
+
+// Verify verifies the token signature and claims and returns its claims.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	h, claims, signed, signature, err := parse(token)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := v.candidates(h.Kid)
+	if len(keys) == 0 && h.Kid != "" {
+		err = v.reload(ctx)
+		if err != nil {
+			return nil, err
+		}
+		keys = v.candidates(h.Kid)
+	}
+
+	verified := false
+	for _, key := range keys {
+		if key.alg != "" && key.alg != h.Alg {
+			continue
+		}
+		if verifySignature(h.Alg, key.key, signed, signature) == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, ErrInvalidToken
+	}
+
+	err = claims.validate(v.issuer, v.audience)
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// candidates returns the keys matching the key id, or all keys if kid is empty.
+func (v *Verifier) candidates(kid string) []publicKey {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	keys := make([]publicKey, 0, 1)
+	for _, key := range v.keys {
+		if kid == "" || key.kid == kid {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// reload loads the JWKS unless it was attempted less than reloadInterval ago. Reloads are
+// serialized and the attempt is recorded before loading, so that tokens with unknown key ids
+// cannot make every request fetch the JWKS, even while its source is unavailable.
+func (v *Verifier) reload(ctx context.Context) error {
+	v.reloading.Lock()
+	defer v.reloading.Unlock()
+	if time.Since(v.reloadedAt) < reloadInterval {
+		return nil
+	}
+	v.reloadedAt = time.Now()
+	return v.load(ctx)
+}
+
+// load loads the JWKS from the verifier source.
+func (v *Verifier) load(ctx context.Context) error {
+	raw, err := v.read(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read jwks: %w", err)
+	}
+	keys, err := parseJWKS(raw)
+	if err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.keys = keys
+	return nil
+}
+
+func (v *Verifier) read(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(v.source, "http://") && !strings.HasPrefix(v.source, "https://") {
+		return os.ReadFile(v.source)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// parseJWKS parses the signature keys of the JWKS. Keys of unsupported types are skipped.
+func parseJWKS(raw []byte) ([]publicKey, error) {
+	jwks := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+	err := json.Unmarshal(raw, &jwks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode jwks: %w", err)
+	}
+
+	keys := make([]publicKey, 0, len(jwks.Keys))
+	for _, key := range jwks.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		parsed, err := key.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse jwk %q: %w", key.Kid, err)
+		}
+		if parsed != nil {
+			keys = append(keys, publicKey{kid: key.Kid, alg: key.Alg, key: parsed})
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no signature key found in jwks")
+	}
+	return keys, nil
+}
+
+// publicKey returns the public key of the JWK, or nil if its type is not supported.
+func (key jwk) publicKey() (crypto.PublicKey, error) {
+	switch key.Kty {
+	case "RSA":
+		n, err := decodeBigInt(key.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(key.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid rsa exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch key.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, nil
+		}
+		x, err := decodeBigInt(key.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(key.Y)
+		if err != nil {
+			return nil, err
+		}
+		ecKey := &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		// ECDH fails on points that are not on the curve
+		if _, err := ecKey.ECDH(); err != nil {
+			return nil, fmt.Errorf("invalid ec point: %w", err)
+		}
+		return ecKey, nil
+	case "OKP":
+		if key.Crv != "Ed25519" {
+			return nil, nil
+		}
+		x, err := base64.RawURLEncoding.DecodeString(key.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, nil
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(raw) == 0 {
+		return nil, fmt.Errorf("invalid base64url integer")
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
@@ -0,0 +1,56 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/i4n-co/driplimit/pkg/jwt/jwttest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifierReload(t *testing.T) {
+	ctx := context.Background()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	jwks, err := jwttest.NewJWKS(map[string]crypto.PublicKey{"ec": key.Public()})
+	assert.NoError(t, err)
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fetches.Add(1) > 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(jwks)
+	}))
+	defer server.Close()
+
+	verifier, err := NewVerifier(ctx, server.URL, "", "")
+	assert.NoError(t, err)
+	token, err := jwttest.Sign(key, "unknown", map[string]any{"exp": time.Now().Add(time.Minute).Unix()})
+	assert.NoError(t, err)
+
+	// unknown key ids reload the JWKS once per interval, even when the reload fails
+	verifier.reloadedAt = time.Time{}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := verifier.Verify(ctx, token)
+			assert.Error(t, err)
+		}()
+	}
+	wg.Wait()
+	_, err = verifier.Verify(ctx, token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	assert.Equal(t, int32(2), fetches.Load())
+}
@@ -0,0 +1,229 @@
+// Package jwt verifies JWT bearer tokens against a JSON Web Key Set (JWKS).
+// Only asymmetric signatures are supported (RS*, PS*, ES* and EdDSA).
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// ErrInvalidToken is returned when a token is malformed, badly signed or its claims are not valid.
+var ErrInvalidToken = errors.New("invalid token")
+
+// leeway is the clock skew tolerated on the exp and nbf claims.
+const leeway = 30 * time.Second
+
+// now is overridden by tests.
+var now = time.Now
+
+// header is the JOSE header of a token.
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Claims holds the registered claims of a verified token along with its other claims.
+type Claims struct {
+	Issuer    string
+	Subject   string
+	Audience  []string
+	ExpiresAt time.Time
+	NotBefore time.Time
+	raw       map[string]json.RawMessage
+}
+
+// Decode decodes the claim name into target. found is false if the token has no such claim.
+func (c *Claims) Decode(name string, target any) (found bool, err error) {
+	raw, found := c.raw[name]
+	if !found {
+		return false, nil
+	}
+	err = json.Unmarshal(raw, target)
+	if err != nil {
+		return true, fmt.Errorf("failed to decode claim %s: %w", name, err)
+	}
+	return true, nil
+}
+
+// IsJWT returns true if the token looks like a compact serialized JWT. The signature is not verified.
+func IsJWT(token string) bool {
+	segments := strings.Split(token, ".")
+	if len(segments) != 3 {
+		return false
+	}
+	_, err := decodeHeader(segments[0])
+	return err == nil
+}
+
+// parse splits the token and decodes its header, claims and signature.
+func parse(token string) (h *header, claims *Claims, signed string, signature []byte, err error) {
+	segments := strings.Split(token, ".")
+	if len(segments) != 3 {
+		return nil, nil, "", nil, ErrInvalidToken
+	}
+	h, err = decodeHeader(segments[0])
+	if err != nil {
+		return nil, nil, "", nil, err
+	}
+	claims, err = decodeClaims(segments[1])
+	if err != nil {
+		return nil, nil, "", nil, err
+	}
+	signature, err = base64.RawURLEncoding.DecodeString(segments[2])
+	if err != nil {
+		return nil, nil, "", nil, ErrInvalidToken
+	}
+	return h, claims, segments[0] + "." + segments[1], signature, nil
+}
+
+func decodeHeader(segment string) (*header, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	h := new(header)
+	err = json.Unmarshal(raw, h)
+	if err != nil || h.Alg == "" {
+		return nil, ErrInvalidToken
+	}
+	return h, nil
+}
+
+func decodeClaims(segment string) (*Claims, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	claims := &Claims{raw: make(map[string]json.RawMessage)}
+	err = json.Unmarshal(raw, &claims.raw)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	registered := struct {
+		Issuer    string          `json:"iss"`
+		Subject   string          `json:"sub"`
+		Audience  json.RawMessage `json:"aud"`
+		ExpiresAt *float64        `json:"exp"`
+		NotBefore *float64        `json:"nbf"`
+	}{}
+	err = json.Unmarshal(raw, &registered)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	claims.Issuer = registered.Issuer
+	claims.Subject = registered.Subject
+	if registered.ExpiresAt != nil {
+		claims.ExpiresAt = numericDate(*registered.ExpiresAt)
+	}
+	if registered.NotBefore != nil {
+		claims.NotBefore = numericDate(*registered.NotBefore)
+	}
+	// the audience is either a single string or an array of strings
+	if len(registered.Audience) > 0 {
+		var audience string
+		if json.Unmarshal(registered.Audience, &audience) == nil {
+			claims.Audience = []string{audience}
+		} else if json.Unmarshal(registered.Audience, &claims.Audience) != nil {
+			return nil, ErrInvalidToken
+		}
+	}
+	return claims, nil
+}
+
+func numericDate(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
+
+// validate checks the time, issuer and audience claims. Tokens without expiration are rejected.
+func (c *Claims) validate(issuer, audience string) error {
+	current := now()
+	if c.ExpiresAt.IsZero() || !current.Before(c.ExpiresAt.Add(leeway)) {
+		return fmt.Errorf("%w: expired", ErrInvalidToken)
+	}
+	if !c.NotBefore.IsZero() && current.Add(leeway).Before(c.NotBefore) {
+		return fmt.Errorf("%w: not valid yet", ErrInvalidToken)
+	}
+	if issuer != "" && c.Issuer != issuer {
+		return fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+	}
+	if audience != "" {
+		for _, aud := range c.Audience {
+			if aud == audience {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
+	}
+	return nil
+}
+
+// curves are the curves of the ECDSA algorithms.
+var curves = map[string]string{
+	"ES256": "P-256",
+	"ES384": "P-384",
+	"ES512": "P-521",
+}
+
+// verifySignature verifies the signature of signed with the key according to alg.
+func verifySignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	var hash crypto.Hash
+	switch alg {
+	case "RS256", "PS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "PS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512", "PS512", "ES512":
+		hash = crypto.SHA512
+	case "EdDSA":
+		edKey, ok := key.(ed25519.PublicKey)
+		if !ok || !ed25519.Verify(edKey, []byte(signed), signature) {
+			return ErrInvalidToken
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unsupported algorithm %s", ErrInvalidToken, alg)
+	}
+	hasher := hash.New()
+	hasher.Write([]byte(signed))
+	digest := hasher.Sum(nil)
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		var err error
+		switch alg[:2] {
+		case "RS":
+			err = rsa.VerifyPKCS1v15(key, hash, digest, signature)
+		case "PS":
+			err = rsa.VerifyPSS(key, hash, digest, signature, nil)
+		default:
+			return ErrInvalidToken
+		}
+		if err != nil {
+			return ErrInvalidToken
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if curves[alg] != key.Curve.Params().Name || len(signature) != 2*size {
+			return ErrInvalidToken
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(key, digest, r, s) {
+			return ErrInvalidToken
+		}
+		return nil
+	default:
+		return ErrInvalidToken
+	}
+}
@@ -0,0 +1,167 @@
+package jwt_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/i4n-co/driplimit/pkg/jwt"
+	"github.com/i4n-co/driplimit/pkg/jwt/jwttest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifier(t *testing.T) {
+	ctx := context.Background()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	jwks, err := jwttest.NewJWKS(map[string]crypto.PublicKey{
+		"rsa": rsaKey.Public(),
+		"ec":  ecKey.Public(),
+		"ed":  edKey.Public(),
+	})
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(path, jwks, 0o600))
+
+	verifier, err := jwt.NewVerifier(ctx, path, "https://issuer.test", "driplimit")
+	assert.NoError(t, err)
+
+	claims := map[string]any{
+		"iss":  "https://issuer.test",
+		"aud":  []string{"other", "driplimit"},
+		"sub":  "billing",
+		"skid": "sk_abc",
+		"exp":  time.Now().Add(time.Minute).Unix(),
+	}
+	signers := map[string]crypto.Signer{"rsa": rsaKey, "ec": ecKey, "ed": edKey}
+	for kid, signer := range signers {
+		token, err := jwttest.Sign(signer, kid, claims)
+		assert.NoError(t, err)
+		assert.True(t, jwt.IsJWT(token))
+
+		verified, err := verifier.Verify(ctx, token)
+		if assert.NoError(t, err, kid) {
+			assert.Equal(t, "billing", verified.Subject)
+			skid := ""
+			found, err := verified.Decode("skid", &skid)
+			assert.NoError(t, err)
+			assert.True(t, found)
+			assert.Equal(t, "sk_abc", skid)
+		}
+	}
+
+	// tokens signed with another key are rejected
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	token, err := jwttest.Sign(otherKey, "rsa", claims)
+	assert.NoError(t, err)
+	_, err = verifier.Verify(ctx, token)
+	assert.ErrorIs(t, err, jwt.ErrInvalidToken)
+
+	// claims are checked
+	invalidClaims := []map[string]any{
+		{"iss": "https://issuer.test", "aud": "driplimit"},
+		{"iss": "https://issuer.test", "aud": "driplimit", "exp": time.Now().Add(-time.Hour).Unix()},
+		{"iss": "https://issuer.test", "aud": "driplimit", "exp": time.Now().Add(time.Hour).Unix(), "nbf": time.Now().Add(time.Hour).Unix()},
+		{"iss": "https://other.test", "aud": "driplimit", "exp": time.Now().Add(time.Hour).Unix()},
+		{"iss": "https://issuer.test", "aud": "other", "exp": time.Now().Add(time.Hour).Unix()},
+	}
+	for _, invalid := range invalidClaims {
+		token, err := jwttest.Sign(ecKey, "ec", invalid)
+		assert.NoError(t, err)
+		_, err = verifier.Verify(ctx, token)
+		assert.ErrorIs(t, err, jwt.ErrInvalidToken, invalid)
+	}
+
+	assert.False(t, jwt.IsJWT("sk_t0k3n"))
+	assert.False(t, jwt.IsJWT("a.b.c"))
+}
+
+func TestVerifierJWKSURL(t *testing.T) {
+	ctx := context.Background()
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.NoError(t, err)
+	jwks, err := jwttest.NewJWKS(map[string]crypto.PublicKey{"ec": key.Public()})
+	assert.NoError(t, err)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(jwks)
+	}))
+	defer server.Close()
+
+	verifier, err := jwt.NewVerifier(ctx, server.URL, "", "")
+	assert.NoError(t, err)
+	token, err := jwttest.Sign(key, "ec", map[string]any{"exp": time.Now().Add(time.Minute).Unix()})
+	assert.NoError(t, err)
+	_, err = verifier.Verify(ctx, token)
+	assert.NoError(t, err)
+}
+
+func TestVerifierAlgorithms(t *testing.T) {
+	ctx := context.Background()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	// the alg member of the keys restricts their algorithm
+	jwks, err := jwttest.NewJWKS(map[string]crypto.PublicKey{"rsa": rsaKey.Public(), "ec": ecKey.Public()})
+	assert.NoError(t, err)
+	set := struct {
+		Keys []map[string]any `json:"keys"`
+	}{}
+	assert.NoError(t, json.Unmarshal(jwks, &set))
+	for _, key := range set.Keys {
+		if key["kid"] == "rsa" {
+			key["alg"] = "PS256"
+		}
+	}
+	jwks, err = json.Marshal(set)
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(path, jwks, 0o600))
+	verifier, err := jwt.NewVerifier(ctx, path, "", "")
+	assert.NoError(t, err)
+
+	claims := map[string]any{"exp": time.Now().Add(time.Minute).Unix()}
+	token, err := jwttest.Sign(rsaKey, "rsa", claims)
+	assert.NoError(t, err)
+	_, err = verifier.Verify(ctx, token)
+	assert.ErrorIs(t, err, jwt.ErrInvalidToken)
+
+	// ECDSA algorithms are bound to their curve
+	token, err = jwttest.Sign(ecKey, "ec", claims)
+	assert.NoError(t, err)
+	_, err = verifier.Verify(ctx, token)
+	assert.NoError(t, err)
+	header, err := json.Marshal(map[string]string{"alg": "ES384", "kid": "ec"})
+	assert.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	assert.NoError(t, err)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha512.Sum384([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+	assert.NoError(t, err)
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+	_, err = verifier.Verify(ctx, signed+"."+base64.RawURLEncoding.EncodeToString(signature))
+	assert.ErrorIs(t, err, jwt.ErrInvalidToken)
+}
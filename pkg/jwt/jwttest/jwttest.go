@@ -0,0 +1,130 @@
+// Package jwttest issues tokens and JWKS for tests of the jwt package verifier, tokens
+// being usually issued by an identity provider.
+package jwttest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
+// header is the JOSE header of issued tokens.
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// jwk is a JSON Web Key of a JWKS.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// Sign issues a token with the claims, signed with the private key (RS256 for RSA keys,
+// ES256/ES384/ES512 for ECDSA keys depending on the curve and EdDSA for Ed25519 keys).
+func Sign(key crypto.Signer, kid string, claims any) (string, error) {
+	alg, hash, err := signatureAlgorithm(key)
+	if err != nil {
+		return "", err
+	}
+	rawHeader, err := json.Marshal(header{Alg: alg, Kid: kid})
+	if err != nil {
+		return "", err
+	}
+	rawClaims, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode claims: %w", err)
+	}
+	signed := base64.RawURLEncoding.EncodeToString(rawHeader) + "." + base64.RawURLEncoding.EncodeToString(rawClaims)
+
+	digest := []byte(signed)
+	if hash != 0 {
+		hasher := hash.New()
+		hasher.Write(digest)
+		digest = hasher.Sum(nil)
+	}
+	var signature []byte
+	// JWS expects ECDSA signatures as r||s instead of the ASN.1 encoding of crypto.Signer
+	if ecKey, ok := key.(*ecdsa.PrivateKey); ok {
+		r, s, err := ecdsa.Sign(rand.Reader, ecKey, digest)
+		if err != nil {
+			return "", fmt.Errorf("failed to sign token: %w", err)
+		}
+		size := (ecKey.Curve.Params().BitSize + 7) / 8
+		signature = make([]byte, 2*size)
+		r.FillBytes(signature[:size])
+		s.FillBytes(signature[size:])
+	} else {
+		signature, err = key.Sign(rand.Reader, digest, hash)
+		if err != nil {
+			return "", fmt.Errorf("failed to sign token: %w", err)
+		}
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func signatureAlgorithm(key crypto.Signer) (alg string, hash crypto.Hash, err error) {
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		return "RS256", crypto.SHA256, nil
+	case *ecdsa.PrivateKey:
+		switch key.Curve.Params().BitSize {
+		case 256:
+			return "ES256", crypto.SHA256, nil
+		case 384:
+			return "ES384", crypto.SHA384, nil
+		case 521:
+			return "ES512", crypto.SHA512, nil
+		}
+	case ed25519.PrivateKey:
+		return "EdDSA", 0, nil
+	}
+	return "", 0, fmt.Errorf("unsupported signing key %T", key)
+}
+
+// NewJWKS returns the JWKS of the public keys, indexed by key id.
+func NewJWKS(keys map[string]crypto.PublicKey) ([]byte, error) {
+	jwks := struct {
+		Keys []jwk `json:"keys"`
+	}{Keys: make([]jwk, 0, len(keys))}
+	encode := func(i *big.Int) string {
+		return base64.RawURLEncoding.EncodeToString(i.Bytes())
+	}
+	for kid, key := range keys {
+		switch key := key.(type) {
+		case *rsa.PublicKey:
+			jwks.Keys = append(jwks.Keys, jwk{Kty: "RSA", Kid: kid, Use: "sig", N: encode(key.N), E: encode(big.NewInt(int64(key.E)))})
+		case *ecdsa.PublicKey:
+			// coordinates are padded to the curve size
+			size := (key.Curve.Params().BitSize + 7) / 8
+			x, y := make([]byte, size), make([]byte, size)
+			key.X.FillBytes(x)
+			key.Y.FillBytes(y)
+			jwks.Keys = append(jwks.Keys, jwk{
+				Kty: "EC",
+				Kid: kid,
+				Use: "sig",
+				Crv: key.Curve.Params().Name,
+				X:   base64.RawURLEncoding.EncodeToString(x),
+				Y:   base64.RawURLEncoding.EncodeToString(y),
+			})
+		case ed25519.PublicKey:
+			jwks.Keys = append(jwks.Keys, jwk{Kty: "OKP", Kid: kid, Use: "sig", Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(key)})
+		default:
+			return nil, fmt.Errorf("unsupported public key %T", key)
+		}
+	}
+	return json.Marshal(jwks)
+}
@@ -2,7 +2,9 @@ package proxycache
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/hashicorp/golang-lru/v2/expirable"
 	"github.com/i4n-co/driplimit"
@@ -10,11 +12,14 @@ import (
 	"github.com/i4n-co/driplimit/pkg/generate"
 )
 
-// cache can store service keys, keys, and errors.
+// cache can store service keys, keys, errors and the upstream check authorizations.
 type cache struct {
 	ServiceKeys *expirable.LRU[string, *driplimit.ServiceKey]
 	Keys        *expirable.LRU[string, *driplimit.Key]
 	Errors      *expirable.LRU[string, error]
+	// Grants stores whether upstream authorized a service token to check the keys of a
+	// keyspace. It is used when the service key policies cannot be evaluated locally.
+	Grants *expirable.LRU[string, bool]
 }
 
 func newCache(cfg *config.Config) *cache {
@@ -22,6 +27,67 @@ func newCache(cfg *config.Config) *cache {
 		ServiceKeys: expirable.NewLRU[string, *driplimit.ServiceKey](cfg.ServiceKeysCacheSize, nil, cfg.CacheDuration),
 		Keys:        expirable.NewLRU[string, *driplimit.Key](cfg.KeysCacheSize, nil, cfg.CacheDuration),
 		Errors:      expirable.NewLRU[string, error](cfg.KeysCacheSize, nil, cfg.CacheDuration),
+		Grants:      expirable.NewLRU[string, bool](cfg.KeysCacheSize, nil, cfg.CacheDuration),
+	}
+}
+
+// invalidateKeys removes the cached keys matching the given predicate along with their cached errors.
+func (c *cache) invalidateKeys(match func(key *driplimit.Key) bool) {
+	for _, cacheKey := range c.Keys.Keys() {
+		key, found := c.Keys.Peek(cacheKey)
+		if found && match(key) {
+			c.Keys.Remove(cacheKey)
+			c.Errors.Remove(cacheKey)
+		}
+	}
+}
+
+// invalidateServiceKey removes the cached entries of the service key skid along with its grants.
+func (c *cache) invalidateServiceKey(skid string) {
+	for _, cacheKey := range c.ServiceKeys.Keys() {
+		sk, found := c.ServiceKeys.Peek(cacheKey)
+		if found && sk.SKID == skid {
+			c.ServiceKeys.Remove(cacheKey)
+			for _, grantKey := range c.Grants.Keys() {
+				if strings.HasPrefix(grantKey, cacheKey) {
+					c.Grants.Remove(grantKey)
+				}
+			}
+		}
+	}
+}
+
+// invalidateRole removes the cached service keys holding the role rlid along with their grants.
+func (c *cache) invalidateRole(rlid string) {
+	for _, cacheKey := range c.ServiceKeys.Keys() {
+		sk, found := c.ServiceKeys.Peek(cacheKey)
+		if !found {
+			continue
+		}
+		for _, role := range sk.Roles {
+			if role.RLID == rlid {
+				c.invalidateServiceKey(sk.SKID)
+				break
+			}
+		}
+	}
+}
+
+// grantKey returns the grants cache key of the service token on the keyspace ksid.
+func grantKey(serviceToken, ksid string) string {
+	return generate.Hash(serviceToken) + ksid
+}
+
+// recordGrant caches the authorization decision of an upstream check.
+func (c *cache) recordGrant(serviceToken, ksid string, err error) {
+	switch {
+	case driplimit.IsSignatureCredential(serviceToken):
+		// signature credentials are unique to a request (proxies reject signed requests, they
+		// only reach the cache in async authoritative mode)
+	case err == nil:
+		c.Grants.Add(grantKey(serviceToken, ksid), true)
+	case errors.Is(err, driplimit.ErrUnauthorized):
+		c.Grants.Add(grantKey(serviceToken, ksid), false)
 	}
 }
 
@@ -45,6 +111,14 @@ func (proxy *proxyCache) cacheRefresher(ctx context.Context) {
 
 type refreshOrder struct {
 	driplimit.KeysCheckPayload
+	// Addr is the address of the caller, forwarded upstream by asynchronous refreshes
+	Addr string
+}
+
+// newRefreshOrder returns the refresh order of the check made by the caller of ctx.
+func newRefreshOrder(ctx context.Context, payload driplimit.KeysCheckPayload) refreshOrder {
+	addr, _ := ctx.Value(driplimit.RemoteAddrKey).(string)
+	return refreshOrder{KeysCheckPayload: payload, Addr: addr}
 }
 
 func (order refreshOrder) CacheKey() string {
@@ -53,7 +127,15 @@ func (order refreshOrder) CacheKey() string {
 
 // refreshCache refreshes the cache with the upstream synchronously.
 func (proxy *proxyCache) refreshCache(ctx context.Context, order refreshOrder) error {
+	if order.Addr != "" {
+		ctx = context.WithValue(ctx, driplimit.RemoteAddrKey, order.Addr)
+	}
 	key, err := proxy.upstream.KeyCheck(ctx, order.KeysCheckPayload)
+	if errors.Is(err, driplimit.ErrServiceKeyRateLimitExceeded) {
+		// the caller was throttled upstream, the key itself is unknown
+		return fmt.Errorf("failed to check key: %w", err)
+	}
+	proxy.cache.recordGrant(order.ServiceToken(), order.KSID, err)
 	if err != nil {
 		proxy.cache.Errors.Add(order.CacheKey(), err)
 		return fmt.Errorf("failed to check key: %w", err)
@@ -0,0 +1,40 @@
+package proxycache
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/i4n-co/driplimit"
+	"github.com/i4n-co/driplimit/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// upstreamStub is an upstream resolving every service token to the same service key.
+type upstreamStub struct {
+	driplimit.Service
+}
+
+func (u *upstreamStub) ServiceKeyGet(ctx context.Context, payload driplimit.ServiceKeyGetPayload) (*driplimit.ServiceKey, error) {
+	return &driplimit.ServiceKey{SKID: "sk_abc"}, nil
+}
+
+func TestSignatureCredentialsNotCached(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg, err := config.FromEnvFile(ctx, strings.NewReader("MODE=async_authoritative\n"))
+	assert.NoError(t, err)
+	proxy := New(ctx, cfg, new(upstreamStub))
+
+	// signed requests reach the cache in async authoritative mode
+	signed := driplimit.NewRequestSignature("sk_abc", "POST", "/v1/keys.check", nil, time.Now(), "n0nc3").Sign("s3cr3t").Credential()
+	for _, token := range []string{signed, "t0k3n"} {
+		check := driplimit.KeysCheckPayload{KSID: "ks_abc"}
+		_, err := proxy.caller(ctx, check.WithServiceToken(token))
+		assert.NoError(t, err)
+		proxy.cache.recordGrant(token, "ks_abc", nil)
+	}
+	assert.Equal(t, 1, proxy.cache.ServiceKeys.Len())
+	assert.Equal(t, 1, proxy.cache.Grants.Len())
+}
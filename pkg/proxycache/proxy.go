@@ -39,42 +39,155 @@ func New(ctx context.Context, cfg *config.Config, upstream driplimit.Service) *p
 // KeyCheck checks if a key is valid and predicts the next check.
 // this method tries to be as asynchronous as possible.
 func (proxy *proxyCache) KeyCheck(ctx context.Context, payload driplimit.KeysCheckPayload) (key *driplimit.Key, err error) {
-	sk, err := proxy.ServiceKeyGet(ctx, driplimit.ServiceKeyGetPayload{
-		Token: generate.Hash(payload.ServiceToken()),
-	})
+	sk, err := proxy.caller(ctx, payload)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get service key: %w", err)
 	}
 
 	// cache can be populated by multiple service keys. Therfore, we need to check if the
 	// service key is allowed to check the key in the local cache.
-	if !sk.Admin && !sk.KeyspacesPolicies.Can(driplimit.Read, payload.KSID) {
+	allowed, known := proxy.canCheck(sk, payload.ServiceToken(), payload.KSID)
+	if known && !allowed {
 		return nil, driplimit.ErrUnauthorized
 	}
 
-	refreshOrder := refreshOrder{payload}
-	refreshErr, _ := proxy.cache.Errors.Get(refreshOrder.CacheKey())
-	if errors.Is(refreshErr, driplimit.ErrKeyExpired) {
-		return nil, driplimit.ErrKeyExpired
+	refreshOrder := newRefreshOrder(ctx, payload)
+	if known {
+		key, cached, err := proxy.checkCached(refreshOrder)
+		if cached {
+			return key, err
+		}
 	}
 
+	err = proxy.refreshCache(ctx, refreshOrder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh cache: %w", err)
+	}
 	key, found := proxy.cache.Keys.Get(refreshOrder.CacheKey())
 	if !found {
-		err = proxy.refreshCache(ctx, refreshOrder)
-		if err != nil {
-			return nil, fmt.Errorf("failed to refresh cache: %w", err)
+		return nil, fmt.Errorf("key not found in cache after synchronous refresh")
+	}
+	return key, nil
+}
+
+// KeyCheckBatch answers the checks from the cache where possible. The remaining
+// checks are sent upstream in a single batch and their results are cached.
+func (proxy *proxyCache) KeyCheckBatch(ctx context.Context, payload driplimit.KeysCheckBatchPayload) (batch *driplimit.KeysCheckBatch, err error) {
+	sk, err := proxy.caller(ctx, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service key: %w", err)
+	}
+
+	batch = &driplimit.KeysCheckBatch{Results: make([]driplimit.KeysCheckBatchResult, len(payload.Checks))}
+	misses := make([]int, 0, len(payload.Checks))
+	upstreamPayload := driplimit.KeysCheckBatchPayload{}
+	for i, check := range payload.Checks {
+		allowed, known := proxy.canCheck(sk, payload.ServiceToken(), check.KSID)
+		if known && !allowed {
+			batch.Results[i] = driplimit.NewKeysCheckBatchResult(nil, driplimit.ErrUnauthorized)
+			continue
 		}
-		key, found = proxy.cache.Keys.Get(refreshOrder.CacheKey())
-		if !found {
-			return nil, fmt.Errorf("key not found in cache after synchronous refresh")
+		// refresh orders are sent upstream on behalf of the caller
+		order := newRefreshOrder(ctx, *check.WithServiceToken(payload.ServiceToken()))
+		if known {
+			key, cached, err := proxy.checkCached(order)
+			if cached {
+				batch.Results[i] = driplimit.NewKeysCheckBatchResult(key, err)
+				continue
+			}
 		}
-		return key, nil
+		misses = append(misses, i)
+		upstreamPayload.Checks = append(upstreamPayload.Checks, order.KeysCheckPayload)
+	}
+	if len(misses) == 0 {
+		return batch, nil
+	}
+
+	upstreamBatch, err := proxy.upstream.KeyCheckBatch(ctx, *upstreamPayload.WithServiceToken(payload.ServiceToken()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check keys upstream: %w", err)
+	}
+	if len(upstreamBatch.Results) != len(misses) {
+		return nil, fmt.Errorf("expected %d batch results, got %d", len(misses), len(upstreamBatch.Results))
+	}
+	for j, i := range misses {
+		result := upstreamBatch.Results[j]
+		order := refreshOrder{KeysCheckPayload: upstreamPayload.Checks[j]}
+		proxy.cache.recordGrant(payload.ServiceToken(), order.KSID, result.Err())
+		if err := result.Err(); err != nil {
+			proxy.cache.Errors.Add(order.CacheKey(), err)
+		} else {
+			proxy.cache.Errors.Remove(order.CacheKey())
+			proxy.cache.Keys.Add(order.CacheKey(), result.Key)
+		}
+		batch.Results[i] = result
+	}
+	return batch, nil
+}
+
+// canCheck returns whether the service key is allowed to check the keys of the keyspace ksid
+// from the cache. Policies with patterns need the keyspace name, known is false until
+// upstream has authorized or denied the service token on the keyspace.
+func (proxy *proxyCache) canCheck(sk *driplimit.ServiceKey, serviceToken, ksid string) (allowed, known bool) {
+	if sk.Admin {
+		return true, true
+	}
+	if !sk.HasPatterns() {
+		return sk.Can(driplimit.Check, ksid), true
+	}
+	return proxy.cache.Grants.Get(grantKey(serviceToken, ksid))
+}
+
+// caller returns the service key of the caller. Service keys are cached by token hash.
+func (proxy *proxyCache) caller(ctx context.Context, payload driplimit.Payload) (sk *driplimit.ServiceKey, err error) {
+	tokenHash := generate.Hash(payload.ServiceToken())
+	sk, found := proxy.cache.ServiceKeys.Get(tokenHash)
+	if found {
+		if !sk.Expired() {
+			return sk, nil
+		}
+		// never serve an expired service key, upstream decides whether it is still valid
+		proxy.cache.ServiceKeys.Remove(tokenHash)
+	}
+	current := driplimit.ServiceKeyGetPayload{Token: payload.ServiceToken()}
+	sk, err = proxy.upstream.ServiceKeyGet(ctx, *current.WithServiceToken(payload.ServiceToken()))
+	if err != nil {
+		return nil, err
+	}
+	if sk.Expired() {
+		return nil, driplimit.ErrUnauthorized
+	}
+	// signature credentials are unique to a request and never worth caching (proxies reject
+	// signed requests, they only reach the cache in async authoritative mode)
+	if !driplimit.IsSignatureCredential(payload.ServiceToken()) {
+		proxy.cache.ServiceKeys.Add(tokenHash, sk)
+	}
+	return sk, nil
+}
+
+// checkCached checks the key from the cache and predicts the check. cached is false
+// if the key is not in the cache yet, in which case the check must be done upstream.
+func (proxy *proxyCache) checkCached(refreshOrder refreshOrder) (key *driplimit.Key, cached bool, err error) {
+	refreshErr, _ := proxy.cache.Errors.Get(refreshOrder.CacheKey())
+	if errors.Is(refreshErr, driplimit.ErrKeyExpired) {
+		return nil, true, driplimit.ErrKeyExpired
+	}
+
+	key, found := proxy.cache.Keys.Get(refreshOrder.CacheKey())
+	if !found {
+		return nil, false, nil
 	}
 	// notify ahead the cache refresher to refresh the cache asynchronously
 	proxy.refreshOrders <- refreshOrder
 
+	// the cached key may have expired or entered its grace period since the last refresh
+	if key.Expired() {
+		return nil, true, driplimit.ErrKeyExpired
+	}
+	key.InGrace = key.InGracePeriod()
+
 	if !key.ConfiguredRatelimit() {
-		return key, nil
+		return key, true, nil
 	}
 
 	if key.UpdateRemaining() && errors.Is(refreshErr, driplimit.ErrRateLimitExceeded) {
@@ -82,7 +195,7 @@ func (proxy *proxyCache) KeyCheck(ctx context.Context, payload driplimit.KeysChe
 	}
 
 	if key.Ratelimit.State.Remaining <= 0 {
-		return nil, driplimit.ErrRateLimitExceeded
+		return nil, true, driplimit.ErrRateLimitExceeded
 	}
 
 	key.Ratelimit.State.Remaining--
@@ -92,7 +205,7 @@ func (proxy *proxyCache) KeyCheck(ctx context.Context, payload driplimit.KeysChe
 	key.LastUsed = time.Now()
 	proxy.cache.Errors.Remove(refreshOrder.CacheKey())
 
-	return key, nil
+	return key, true, nil
 }
 
 func (proxy *proxyCache) KeyCreate(ctx context.Context, payload driplimit.KeyCreatePayload) (key *driplimit.Key, err error) {
@@ -111,6 +224,52 @@ func (proxy *proxyCache) KeyDelete(ctx context.Context, payload driplimit.KeyDel
 	return proxy.upstream.KeyDelete(ctx, payload)
 }
 
+// KeyDeleteMany deletes the keys upstream and invalidates their cached entries, or the cached
+// keys of the keyspace when deleting by expiration, so that deleted keys are rejected at once.
+func (proxy *proxyCache) KeyDeleteMany(ctx context.Context, payload driplimit.KeyDeleteManyPayload) (result *driplimit.KeyDeleteManyResult, err error) {
+	result, err = proxy.upstream.KeyDeleteMany(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if result.DryRun {
+		return result, nil
+	}
+	kids := make(map[string]bool, len(payload.KIDs))
+	for _, kid := range payload.KIDs {
+		kids[kid] = true
+	}
+	proxy.cache.invalidateKeys(func(cached *driplimit.Key) bool {
+		return cached.KSID == payload.KSID && (len(kids) == 0 || kids[cached.KID])
+	})
+	return result, nil
+}
+
+// KeyReset resets the key upstream and invalidates its cached entry so the
+// reset takes effect immediately.
+func (proxy *proxyCache) KeyReset(ctx context.Context, payload driplimit.KeyResetPayload) (key *driplimit.Key, err error) {
+	key, err = proxy.upstream.KeyReset(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	proxy.cache.invalidateKeys(func(cached *driplimit.Key) bool {
+		return cached.KID == payload.KID
+	})
+	return key, nil
+}
+
+// KeyMove moves the key upstream and invalidates its cached entry as it is cached
+// under its former keyspace.
+func (proxy *proxyCache) KeyMove(ctx context.Context, payload driplimit.KeyMovePayload) (key *driplimit.Key, err error) {
+	key, err = proxy.upstream.KeyMove(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	proxy.cache.invalidateKeys(func(cached *driplimit.Key) bool {
+		return cached.KID == payload.KID
+	})
+	return key, nil
+}
+
 func (proxy *proxyCache) KeyspaceCreate(ctx context.Context, payload driplimit.KeyspaceCreatePayload) (keyspace *driplimit.Keyspace, err error) {
 	return proxy.upstream.KeyspaceCreate(ctx, payload)
 }
@@ -123,21 +282,125 @@ func (proxy *proxyCache) KeyspaceList(ctx context.Context, payload driplimit.Key
 	return proxy.upstream.KeyspaceList(ctx, payload)
 }
 
+// KeyspaceUpdate updates the keyspace upstream and invalidates its cached keys
+// as they may rely on the keyspace defaults.
+func (proxy *proxyCache) KeyspaceUpdate(ctx context.Context, payload driplimit.KeyspaceUpdatePayload) (keyspace *driplimit.Keyspace, err error) {
+	keyspace, err = proxy.upstream.KeyspaceUpdate(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	proxy.cache.invalidateKeys(func(cached *driplimit.Key) bool {
+		return cached.KSID == payload.KSID
+	})
+	// a renamed keyspace may no longer match the same policies patterns
+	proxy.cache.Grants.Purge()
+	return keyspace, nil
+}
+
 func (proxy *proxyCache) KeyspaceDelete(ctx context.Context, payload driplimit.KeyspaceDeletePayload) (err error) {
 	return proxy.upstream.KeyspaceDelete(ctx, payload)
 }
 
-func (proxy *proxyCache) ServiceKeyGet(ctx context.Context, payload driplimit.ServiceKeyGetPayload) (sk *driplimit.ServiceKey, err error) {
-	sk, found := proxy.cache.ServiceKeys.Get(generate.Hash(payload.ServiceToken()))
-	if found {
-		return sk, nil
+func (proxy *proxyCache) KeyspaceClone(ctx context.Context, payload driplimit.KeyspaceClonePayload) (keyspace *driplimit.Keyspace, err error) {
+	return proxy.upstream.KeyspaceClone(ctx, payload)
+}
+
+func (proxy *proxyCache) KeyspaceStats(ctx context.Context, payload driplimit.KeyspaceStatsPayload) (stats *driplimit.KeyspaceStats, err error) {
+	return proxy.upstream.KeyspaceStats(ctx, payload)
+}
+
+func (proxy *proxyCache) KeyspaceExport(ctx context.Context, payload driplimit.KeyspaceExportPayload) (bundle *driplimit.KeyspaceBundle, err error) {
+	return proxy.upstream.KeyspaceExport(ctx, payload)
+}
+
+// KeyspaceImport imports the bundle upstream and invalidates the cached keys of the
+// keyspace as they may have been overwritten.
+func (proxy *proxyCache) KeyspaceImport(ctx context.Context, payload driplimit.KeyspaceImportPayload) (result *driplimit.KeyspaceImportResult, err error) {
+	result, err = proxy.upstream.KeyspaceImport(ctx, payload)
+	if err != nil {
+		return nil, err
 	}
-	sk, err = proxy.upstream.ServiceKeyGet(ctx, payload)
+	proxy.cache.invalidateKeys(func(cached *driplimit.Key) bool {
+		return cached.KSID == result.Keyspace.KSID
+	})
+	return result, nil
+}
+
+func (proxy *proxyCache) PlanGet(ctx context.Context, payload driplimit.PlanGetPayload) (plan *driplimit.Plan, err error) {
+	return proxy.upstream.PlanGet(ctx, payload)
+}
+
+func (proxy *proxyCache) PlanCreate(ctx context.Context, payload driplimit.PlanCreatePayload) (plan *driplimit.Plan, err error) {
+	return proxy.upstream.PlanCreate(ctx, payload)
+}
+
+func (proxy *proxyCache) PlanList(ctx context.Context, payload driplimit.PlanListPayload) (plist *driplimit.PlanList, err error) {
+	return proxy.upstream.PlanList(ctx, payload)
+}
+
+// PlanUpdate updates the plan upstream and invalidates the cached keys referencing it.
+func (proxy *proxyCache) PlanUpdate(ctx context.Context, payload driplimit.PlanUpdatePayload) (plan *driplimit.Plan, err error) {
+	plan, err = proxy.upstream.PlanUpdate(ctx, payload)
 	if err != nil {
 		return nil, err
 	}
-	proxy.cache.ServiceKeys.Add(generate.Hash(payload.ServiceToken()), sk)
-	return sk, nil
+	proxy.cache.invalidateKeys(func(cached *driplimit.Key) bool {
+		return cached.PLID == payload.PLID
+	})
+	return plan, nil
+}
+
+// PlanDelete deletes the plan upstream and invalidates the cached keys referencing it.
+func (proxy *proxyCache) PlanDelete(ctx context.Context, payload driplimit.PlanDeletePayload) (err error) {
+	err = proxy.upstream.PlanDelete(ctx, payload)
+	if err != nil {
+		return err
+	}
+	proxy.cache.invalidateKeys(func(cached *driplimit.Key) bool {
+		return cached.PLID == payload.PLID
+	})
+	return nil
+}
+
+func (proxy *proxyCache) RoleGet(ctx context.Context, payload driplimit.RoleGetPayload) (role *driplimit.Role, err error) {
+	return proxy.upstream.RoleGet(ctx, payload)
+}
+
+func (proxy *proxyCache) RoleCreate(ctx context.Context, payload driplimit.RoleCreatePayload) (role *driplimit.Role, err error) {
+	return proxy.upstream.RoleCreate(ctx, payload)
+}
+
+func (proxy *proxyCache) RoleList(ctx context.Context, payload driplimit.RoleListPayload) (rlist *driplimit.RoleList, err error) {
+	return proxy.upstream.RoleList(ctx, payload)
+}
+
+// RoleUpdate updates the role upstream and invalidates the cached service keys holding it.
+func (proxy *proxyCache) RoleUpdate(ctx context.Context, payload driplimit.RoleUpdatePayload) (role *driplimit.Role, err error) {
+	role, err = proxy.upstream.RoleUpdate(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	proxy.cache.invalidateRole(payload.RLID)
+	return role, nil
+}
+
+// RoleDelete deletes the role upstream and invalidates the cached service keys holding it.
+func (proxy *proxyCache) RoleDelete(ctx context.Context, payload driplimit.RoleDeletePayload) (err error) {
+	err = proxy.upstream.RoleDelete(ctx, payload)
+	if err != nil {
+		return err
+	}
+	proxy.cache.invalidateRole(payload.RLID)
+	return nil
+}
+
+// ServiceKeyGet returns the caller service key from the cache. Other service keys
+// are always retrieved upstream as the cache is indexed by caller token.
+func (proxy *proxyCache) ServiceKeyGet(ctx context.Context, payload driplimit.ServiceKeyGetPayload) (sk *driplimit.ServiceKey, err error) {
+	if payload.SKID == "" && payload.Token == payload.ServiceToken() {
+		return proxy.caller(ctx, payload)
+	}
+	return proxy.upstream.ServiceKeyGet(ctx, payload)
 }
 
 func (proxy *proxyCache) ServiceKeyCreate(ctx context.Context, payload driplimit.ServiceKeyCreatePayload) (sk *driplimit.ServiceKey, err error) {
@@ -149,9 +412,59 @@ func (proxy *proxyCache) ServiceKeyList(ctx context.Context, payload driplimit.S
 }
 
 func (proxy *proxyCache) ServiceKeyDelete(ctx context.Context, payload driplimit.ServiceKeyDeletePayload) (err error) {
-	return proxy.upstream.ServiceKeyDelete(ctx, payload)
+	err = proxy.upstream.ServiceKeyDelete(ctx, payload)
+	if err != nil {
+		return err
+	}
+	proxy.cache.invalidateServiceKey(payload.SKID)
+	return nil
 }
 
 func (proxy *proxyCache) ServiceKeySetToken(ctx context.Context, payload driplimit.ServiceKeySetTokenPayload) (err error) {
-	return proxy.upstream.ServiceKeySetToken(ctx, payload)
+	err = proxy.upstream.ServiceKeySetToken(ctx, payload)
+	if err != nil {
+		return err
+	}
+	proxy.cache.invalidateServiceKey(payload.SKID)
+	return nil
+}
+
+func (proxy *proxyCache) ServiceKeyRotate(ctx context.Context, payload driplimit.ServiceKeyRotatePayload) (sk *driplimit.ServiceKey, err error) {
+	return proxy.upstream.ServiceKeyRotate(ctx, payload)
+}
+
+// ServiceKeyPromote promotes the secondary token upstream. Cached entries are kept as both
+// tokens remain valid.
+func (proxy *proxyCache) ServiceKeyPromote(ctx context.Context, payload driplimit.ServiceKeyPromotePayload) (sk *driplimit.ServiceKey, err error) {
+	return proxy.upstream.ServiceKeyPromote(ctx, payload)
+}
+
+// ServiceKeyRevokePrevious revokes the secondary token upstream and invalidates the cached
+// entries of the service key so that the revoked token is rejected at once.
+func (proxy *proxyCache) ServiceKeyRevokePrevious(ctx context.Context, payload driplimit.ServiceKeyRevokePreviousPayload) (sk *driplimit.ServiceKey, err error) {
+	sk, err = proxy.upstream.ServiceKeyRevokePrevious(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	proxy.cache.invalidateServiceKey(payload.SKID)
+	return sk, nil
+}
+
+// ServiceKeyUpdate updates the service key upstream and invalidates its cached
+// entries so its new permissions apply to the next calls.
+func (proxy *proxyCache) ServiceKeyUpdate(ctx context.Context, payload driplimit.ServiceKeyUpdatePayload) (sk *driplimit.ServiceKey, err error) {
+	sk, err = proxy.upstream.ServiceKeyUpdate(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	proxy.cache.invalidateServiceKey(payload.SKID)
+	return sk, nil
+}
+
+func (proxy *proxyCache) ServiceKeyCan(ctx context.Context, payload driplimit.ServiceKeyCanPayload) (permission *driplimit.Permission, err error) {
+	return proxy.upstream.ServiceKeyCan(ctx, payload)
+}
+
+func (proxy *proxyCache) AuditList(ctx context.Context, payload driplimit.AuditListPayload) (alist *driplimit.AuditList, err error) {
+	return proxy.upstream.AuditList(ctx, payload)
 }
@@ -0,0 +1,152 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/i4n-co/driplimit"
+	"github.com/i4n-co/driplimit/pkg/generate"
+	"github.com/jmoiron/sqlx"
+)
+
+// AuditEntryModel represents the database model for an audit entry.
+type AuditEntryModel struct {
+	AEID      string   `db:"aeid"`
+	SKID      string   `db:"skid"`
+	Action    string   `db:"action"`
+	TargetIDs string   `db:"target_ids"`
+	Payload   string   `db:"payload"`
+	Result    string   `db:"result"`
+	Error     string   `db:"error"`
+	CreatedAt TimeNano `db:"created_at"`
+}
+
+// ToAuditEntry converts the audit entry model to an audit entry.
+func (a *AuditEntryModel) ToAuditEntry() (*driplimit.AuditEntry, error) {
+	entry := &driplimit.AuditEntry{
+		AEID:      a.AEID,
+		SKID:      a.SKID,
+		Action:    a.Action,
+		TargetIDs: make([]string, 0),
+		Payload:   json.RawMessage(a.Payload),
+		Result:    driplimit.AuditResult(a.Result),
+		Error:     a.Error,
+		CreatedAt: a.CreatedAt.Time,
+	}
+	err := json.Unmarshal([]byte(a.TargetIDs), &entry.TargetIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode audit entry target ids: %w", err)
+	}
+	return entry, nil
+}
+
+// RecordAudit records an audit entry.
+func (s *Store) RecordAudit(ctx context.Context, entry driplimit.AuditEntry) error {
+	targetIDs := entry.TargetIDs
+	if targetIDs == nil {
+		targetIDs = make([]string, 0)
+	}
+	encodedTargetIDs, err := json.Marshal(targetIDs)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry target ids: %w", err)
+	}
+	payload := string(entry.Payload)
+	if payload == "" {
+		payload = "{}"
+	}
+
+	model := &AuditEntryModel{
+		AEID:      generate.IDWithPrefix("ae_"),
+		SKID:      entry.SKID,
+		Action:    entry.Action,
+		TargetIDs: string(encodedTargetIDs),
+		Payload:   payload,
+		Result:    string(entry.Result),
+		Error:     entry.Error,
+		CreatedAt: TimeNano{Time: entry.CreatedAt},
+	}
+	_, err = sqlx.NamedExecContext(ctx, s.ext(), `
+		INSERT INTO audit_entries (
+			aeid,
+			skid,
+			action,
+			target_ids,
+			payload,
+			result,
+			error,
+			created_at
+		) VALUES (
+			:aeid,
+			:skid,
+			:action,
+			:target_ids,
+			:payload,
+			:result,
+			:error,
+			:created_at
+		)`, model)
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	return nil
+}
+
+// ListAuditEntries returns the audit entries matching the given payload, most recent first.
+func (s *Store) ListAuditEntries(ctx context.Context, payload driplimit.AuditListPayload) (*driplimit.AuditList, error) {
+	conditions := []string{}
+	args := []any{}
+	filter := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if payload.SKID != "" {
+		filter("skid = $%d", payload.SKID)
+	}
+	if payload.Action != "" {
+		filter("action = $%d", payload.Action)
+	}
+	if payload.TargetID != "" {
+		filter("EXISTS (SELECT 1 FROM json_each(target_ids) WHERE json_each.value = $%d)", payload.TargetID)
+	}
+	if payload.Result != "" {
+		filter("result = $%d", string(payload.Result))
+	}
+	if !payload.Since.IsZero() {
+		filter("created_at >= $%d", TimeNano{Time: payload.Since})
+	}
+	if !payload.Until.IsZero() {
+		filter("created_at < $%d", TimeNano{Time: payload.Until})
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	totalCount := 0
+	models := make([]*AuditEntryModel, 0)
+	err := sqlx.SelectContext(ctx, s.ext(), &models,
+		fmt.Sprintf("SELECT * FROM audit_entries %s ORDER BY created_at DESC LIMIT $%d OFFSET $%d", where, len(args)+1, len(args)+2),
+		append(args, payload.List.Limit, payload.List.Offset())...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	err = sqlx.GetContext(ctx, s.ext(), &totalCount, "SELECT COUNT(*) FROM audit_entries "+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count audit entries: %w", err)
+	}
+
+	alist := &driplimit.AuditList{
+		List:    driplimit.NewListMetadata(payload.List, totalCount),
+		Entries: make([]*driplimit.AuditEntry, 0, len(models)),
+	}
+	for _, model := range models {
+		entry, err := model.ToAuditEntry()
+		if err != nil {
+			return nil, err
+		}
+		alist.Entries = append(alist.Entries, entry)
+	}
+	return alist, nil
+}
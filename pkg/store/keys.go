@@ -9,17 +9,20 @@ import (
 
 	"github.com/i4n-co/driplimit"
 	"github.com/i4n-co/driplimit/pkg/generate"
+	"github.com/jmoiron/sqlx"
 )
 
 // KeyModel represents the database model for a key.
 type KeyModel struct {
-	KID       string   `db:"kid"`
-	KSID      string   `db:"ksid"`
-	TokenHash string   `db:"token_hash"`
-	LastUsed  TimeNano `db:"last_used"`
-	ExpiresAt TimeNano `db:"expires_at"`
-	CreatedAt TimeNano `db:"created_at"`
-	DeletedAt TimeNano `db:"deleted_at"`
+	KID         string         `db:"kid"`
+	KSID        string         `db:"ksid"`
+	PLID        string         `db:"plid"`
+	TokenHash   string         `db:"token_hash"`
+	LastUsed    TimeNano       `db:"last_used"`
+	ExpiresAt   TimeNano       `db:"expires_at"`
+	CreatedAt   TimeNano       `db:"created_at"`
+	DeletedAt   TimeNano       `db:"deleted_at"`
+	GracePeriod *time.Duration `db:"grace_period"`
 
 	RateLimitStateLastRefilled TimeNano      `db:"rate_limit_state_last_refilled"`
 	RateLimitStateRemaining    int64         `db:"rate_limit_state_remaining"`
@@ -38,6 +41,7 @@ func NewKeyModel(key driplimit.Key) *KeyModel {
 	model := &KeyModel{
 		KID:       key.KID,
 		KSID:      key.KSID,
+		PLID:      key.PLID,
 		LastUsed:  TimeNano{Time: key.LastUsed},
 		ExpiresAt: TimeNano{Time: key.ExpiresAt},
 		CreatedAt: TimeNano{Time: key.CreatedAt},
@@ -56,15 +60,67 @@ func NewKeyModel(key driplimit.Key) *KeyModel {
 	return model
 }
 
+// NewKeyModelFromBundle creates a new key model of the keyspace ksid from a bundle key.
+func NewKeyModelFromBundle(ksid string, key driplimit.KeyspaceBundleKey) *KeyModel {
+	model := &KeyModel{
+		KID:                        key.KID,
+		KSID:                       ksid,
+		PLID:                       key.PLID,
+		TokenHash:                  key.TokenHash,
+		LastUsed:                   TimeNano{Time: key.LastUsed},
+		ExpiresAt:                  TimeNano{Time: key.ExpiresAt},
+		CreatedAt:                  TimeNano{Time: key.CreatedAt},
+		RateLimitStateLastRefilled: TimeNano{Time: key.State.LastRefilled},
+		RateLimitStateRemaining:    key.State.Remaining,
+		RateLimitLimit:             key.Ratelimit.Limit,
+		RateLimitRefillRate:        key.Ratelimit.RefillRate,
+		RateLimitRefillInterval:    key.Ratelimit.RefillInterval.Duration,
+	}
+	if key.GracePeriod != nil {
+		model.GracePeriod = &key.GracePeriod.Duration
+	}
+	return model
+}
+
+// ToBundleKey converts the key model to a bundle key, keeping the token hash
+// and the key own rate limit configuration and state.
+func (model *KeyModel) ToBundleKey() *driplimit.KeyspaceBundleKey {
+	key := &driplimit.KeyspaceBundleKey{
+		KID:       model.KID,
+		PLID:      model.PLID,
+		TokenHash: model.TokenHash,
+		LastUsed:  model.LastUsed.Time,
+		ExpiresAt: model.ExpiresAt.Time,
+		CreatedAt: model.CreatedAt.Time,
+		Ratelimit: driplimit.RatelimitPayload{
+			Limit:          model.RateLimitLimit,
+			RefillRate:     model.RateLimitRefillRate,
+			RefillInterval: driplimit.Milliseconds{Duration: model.RateLimitRefillInterval},
+		},
+		State: driplimit.RatelimitState{
+			LastRefilled: model.RateLimitStateLastRefilled.Time,
+			Remaining:    model.RateLimitStateRemaining,
+		},
+	}
+	if model.GracePeriod != nil {
+		key.GracePeriod = &driplimit.Milliseconds{Duration: *model.GracePeriod}
+	}
+	return key
+}
+
 // ToKey converts the key model to a key.
 func (model *KeyModel) ToKey() *driplimit.Key {
 	key := &driplimit.Key{
 		KID:       model.KID,
 		KSID:      model.KSID,
+		PLID:      model.PLID,
 		LastUsed:  model.LastUsed.Time,
 		ExpiresAt: model.ExpiresAt.Time,
 		CreatedAt: model.CreatedAt.Time,
 	}
+	if model.GracePeriod != nil {
+		key.GracePeriod = driplimit.Milliseconds{Duration: *model.GracePeriod}
+	}
 
 	if model.RateLimitLimit > 0 {
 		key.Ratelimit = &driplimit.Ratelimit{
@@ -96,32 +152,66 @@ func (sqlite *Store) CreateKey(ctx context.Context, payload driplimit.KeyCreateP
 		return nil, fmt.Errorf("failed to get keyspace by id: %w", err)
 	}
 
+	expiresAt, err := ks.KeyExpiration(payload.ExpiresAt, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	var plan *PlanModel
+	if payload.PLID != "" {
+		plan, err = sqlite.getPlan(ctx, ks.KSID, payload.PLID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	model := new(KeyModel)
 	token := ks.KeysPrefix + generate.Token()
 
 	model.KID = "k_" + generate.ID()
 	model.KSID = ks.KSID
-	model.ExpiresAt = TimeNano{Time: payload.ExpiresAt}
+	model.PLID = payload.PLID
+	model.ExpiresAt = TimeNano{Time: expiresAt}
 	model.CreatedAt = TimeNano{Time: time.Now()}
 	model.LastUsed = TimeNano{Time: time.Time{}}
 	model.TokenHash = generate.Hash(token)
+	if payload.GracePeriod != nil {
+		model.GracePeriod = &payload.GracePeriod.Duration
+	}
 	if payload.Ratelimit.Configured() {
 		model.RateLimitStateRemaining = payload.Ratelimit.Limit
 		model.RateLimitStateLastRefilled = TimeNano{Time: time.Now()}
 		model.RateLimitLimit = payload.Ratelimit.Limit
 		model.RateLimitRefillRate = payload.Ratelimit.RefillRate
 		model.RateLimitRefillInterval = payload.Ratelimit.RefillInterval.Duration
+	} else if plan != nil {
+		model.RateLimitStateRemaining = plan.RateLimitLimit
+		model.RateLimitStateLastRefilled = TimeNano{Time: time.Now()}
+	}
+
+	err = sqlite.insertKey(ctx, model)
+	if err != nil {
+		return nil, err
 	}
 
-	_, err = sqlite.db.NamedExecContext(ctx, `
+	k := model.ToKey()
+	k.Token = token
+	return k, nil
+}
+
+// insertKey inserts the key model.
+func (sqlite *Store) insertKey(ctx context.Context, model *KeyModel) error {
+	_, err := sqlx.NamedExecContext(ctx, sqlite.ext(), `
 	INSERT INTO keys
 	(
 		kid,
 		ksid,
+		plid,
 		token_hash,
 		last_used,
 		expires_at,
 		created_at,
+		grace_period,
 		rate_limit_state_last_refilled,
 		rate_limit_state_remaining,
 		rate_limit_limit,
@@ -132,10 +222,12 @@ func (sqlite *Store) CreateKey(ctx context.Context, payload driplimit.KeyCreateP
 	(
 		:kid,
 		:ksid,
+		:plid,
 		:token_hash,
 		:last_used,
 		:expires_at,
 		:created_at,
+		:grace_period,
 		:rate_limit_state_last_refilled,
 		:rate_limit_state_remaining,
 		:rate_limit_limit,
@@ -143,16 +235,14 @@ func (sqlite *Store) CreateKey(ctx context.Context, payload driplimit.KeyCreateP
 		:rate_limit_refill_interval
 	)`, model)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create key: %w", err)
+		return fmt.Errorf("failed to create key: %w", err)
 	}
-
-	k := model.ToKey()
-	k.Token = token
-	return k, nil
+	return nil
 }
 
-// GetKey returns a key by the given payload. Ratelimit is set with keyspace ratelimit if not configured
-// on the key itself.
+// GetKey returns a key by the given payload. Ratelimit is resolved from the key itself, then from
+// its plan, then from the keyspace. Grace period is set with the keyspace one if not configured on the key,
+// a key configured with a zero grace period expiring without grace.
 func (sqlite *Store) GetKey(ctx context.Context, payload driplimit.KeyGetPayload) (key *driplimit.Key, err error) {
 	field, value, err := payload.GetKeyBy()
 	if err != nil {
@@ -163,17 +253,34 @@ func (sqlite *Store) GetKey(ctx context.Context, payload driplimit.KeyGetPayload
 	if err != nil {
 		return nil, err
 	}
-	if !model.ConfiguredRateLimit() {
-		ks, err := sqlite.GetKeyspaceByID(ctx, model.KSID)
+	if model.ConfiguredRateLimit() && model.GracePeriod != nil {
+		return model.ToKey(), nil
+	}
+
+	if !model.ConfiguredRateLimit() && model.PLID != "" {
+		plan, err := sqlite.getPlan(ctx, model.KSID, model.PLID)
 		if err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
-				return nil, driplimit.ErrItemNotFound("keyspace")
-			}
-			return nil, fmt.Errorf("failed to get keyspace by id: %w", err)
+			return nil, err
 		}
-		if !ks.ConfiguredRateLimit() {
+		model.RateLimitLimit = plan.RateLimitLimit
+		model.RateLimitRefillRate = plan.RateLimitRefillRate
+		model.RateLimitRefillInterval = plan.RateLimitRefillInterval
+		if model.GracePeriod != nil {
 			return model.ToKey(), nil
 		}
+	}
+
+	ks, err := sqlite.GetKeyspaceByID(ctx, model.KSID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, driplimit.ErrItemNotFound("keyspace")
+		}
+		return nil, fmt.Errorf("failed to get keyspace by id: %w", err)
+	}
+	if model.GracePeriod == nil {
+		model.GracePeriod = &ks.GracePeriod.Duration
+	}
+	if !model.ConfiguredRateLimit() && ks.ConfiguredRateLimit() {
 		model.RateLimitLimit = ks.Ratelimit.Limit
 		model.RateLimitRefillRate = ks.Ratelimit.RefillRate
 		model.RateLimitRefillInterval = ks.Ratelimit.RefillInterval.Duration
@@ -185,7 +292,7 @@ func (sqlite *Store) GetKey(ctx context.Context, payload driplimit.KeyGetPayload
 // getKeyBy returns a key by the given field.
 func (sqlite *Store) getKeyBy(ctx context.Context, ksid string, field string, value string) (*KeyModel, error) {
 	key := new(KeyModel)
-	err := sqlite.db.GetContext(ctx, key, fmt.Sprintf("SELECT * FROM v_keys WHERE %s = $1 AND ksid = $2", field), value, ksid)
+	err := sqlx.GetContext(ctx, sqlite.ext(), key, fmt.Sprintf("SELECT * FROM v_keys WHERE %s = $1 AND ksid = $2", field), value, ksid)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, driplimit.ErrItemNotFound("key")
@@ -199,7 +306,7 @@ func (sqlite *Store) getKeyBy(ctx context.Context, ksid string, field string, va
 // UpdateLastUsed updates the last used field of the key.
 func (sqlite *Store) UpdateLastUsed(ctx context.Context, key *driplimit.Key) error {
 	model := NewKeyModel(*key)
-	_, err := sqlite.db.ExecContext(ctx, "UPDATE keys SET last_used = $1 WHERE kid = $2", model.LastUsed, model.KID)
+	_, err := sqlite.ext().ExecContext(ctx, "UPDATE keys SET last_used = $1 WHERE kid = $2", model.LastUsed, model.KID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return driplimit.ErrItemNotFound("key")
@@ -212,7 +319,7 @@ func (sqlite *Store) UpdateLastUsed(ctx context.Context, key *driplimit.Key) err
 // DecrementKeyRemaining decrements the remaining field of the key.
 func (sqlite *Store) DecrementKeyRemaining(ctx context.Context, key *driplimit.Key) error {
 	model := NewKeyModel(*key)
-	row := sqlite.db.QueryRow("UPDATE keys SET rate_limit_state_remaining = rate_limit_state_remaining - 1, last_used = $1 WHERE kid = $2 RETURNING rate_limit_state_remaining",
+	row := sqlite.ext().QueryRowxContext(ctx, "UPDATE keys SET rate_limit_state_remaining = rate_limit_state_remaining - 1, last_used = $1 WHERE kid = $2 RETURNING rate_limit_state_remaining",
 		model.LastUsed,
 		model.KID,
 	)
@@ -228,7 +335,7 @@ func (sqlite *Store) DecrementKeyRemaining(ctx context.Context, key *driplimit.K
 // SetKeyRemaining sets the remaining field of the key.
 func (sqlite *Store) SetKeyRemaining(ctx context.Context, key *driplimit.Key) error {
 	model := NewKeyModel(*key)
-	_, err := sqlite.db.Exec("UPDATE keys SET rate_limit_state_remaining = $1, rate_limit_state_last_refilled = $2 WHERE kid = $3",
+	_, err := sqlite.ext().ExecContext(ctx, "UPDATE keys SET rate_limit_state_remaining = $1, rate_limit_state_last_refilled = $2 WHERE kid = $3",
 		model.RateLimitStateRemaining,
 		model.RateLimitStateLastRefilled,
 		model.KID,
@@ -242,21 +349,82 @@ func (sqlite *Store) SetKeyRemaining(ctx context.Context, key *driplimit.Key) er
 	return nil
 }
 
+// MoveKey moves a key to another keyspace. Unless the token is kept or both keyspaces share the same
+// keys prefix, a new token is generated with the target keyspace prefix and returned with the key.
+// A key without its own rate limit adopts the target keyspace one, therefore its rate limit state is reset.
+// Plans belong to a keyspace, so the key is detached from its plan.
+func (sqlite *Store) MoveKey(ctx context.Context, payload driplimit.KeyMovePayload) (key *driplimit.Key, err error) {
+	err = sqlite.WithTx(ctx, func(tx *Store) error {
+		model, err := tx.getKeyBy(ctx, payload.KSID, "kid", payload.KID)
+		if err != nil {
+			return err
+		}
+		source, err := tx.GetKeyspaceByID(ctx, payload.KSID)
+		if err != nil {
+			return err
+		}
+		target, err := tx.GetKeyspaceByID(ctx, payload.TargetKSID)
+		if err != nil {
+			return err
+		}
+
+		token := ""
+		model.KSID = target.KSID
+		model.PLID = ""
+		if !payload.KeepToken && source.KeysPrefix != target.KeysPrefix {
+			token = target.KeysPrefix + generate.Token()
+			model.TokenHash = generate.Hash(token)
+		}
+		if !model.ConfiguredRateLimit() {
+			model.RateLimitStateRemaining = 0
+			model.RateLimitStateLastRefilled = TimeNano{}
+			if target.ConfiguredRateLimit() {
+				model.RateLimitStateRemaining = target.Ratelimit.Limit
+				model.RateLimitStateLastRefilled = TimeNano{Time: time.Now()}
+			}
+		}
+
+		_, err = sqlx.NamedExecContext(ctx, tx.ext(), `
+		UPDATE keys
+		SET
+			ksid = :ksid,
+			plid = :plid,
+			token_hash = :token_hash,
+			rate_limit_state_remaining = :rate_limit_state_remaining,
+			rate_limit_state_last_refilled = :rate_limit_state_last_refilled
+		WHERE kid = :kid`, model)
+		if err != nil {
+			return fmt.Errorf("failed to move key: %w", err)
+		}
+
+		key, err = tx.GetKey(ctx, driplimit.KeyGetPayload{KSID: target.KSID, KID: model.KID})
+		if err != nil {
+			return err
+		}
+		key.Token = token
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
 // ListKeys returns a list of keys based on the given payload.
 func (sqlite *Store) ListKeys(ctx context.Context, payload driplimit.KeyListPayload) (klist *driplimit.KeyList, err error) {
 	totalCount := 0
 	keys := make([]*KeyModel, 0)
 
-	conn, err := sqlite.db.Connx(ctx)
+	ks, err := sqlite.GetKeyspaceByID(ctx, payload.KSID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create connection: %w", err)
+		return nil, fmt.Errorf("failed to get keyspace by id: %w", err)
 	}
-	defer conn.Close()
 
-	ks, err := sqlite.GetKeyspaceByID(ctx, payload.KSID)
+	conn, err := sqlite.db.Connx(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get keyspace by id: %w", err)
+		return nil, fmt.Errorf("failed to create connection: %w", err)
 	}
+	defer conn.Close()
 	err = conn.SelectContext(ctx, &keys, "SELECT * FROM v_keys WHERE ksid = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3", ks.KSID, payload.List.Limit, payload.List.Offset())
 	if err != nil {
 		return nil, fmt.Errorf("failed to list keys: %w", err)
@@ -294,3 +462,45 @@ func (sqlite *Store) DeleteKey(ctx context.Context, payload driplimit.KeyDeleteP
 	}
 	return nil
 }
+
+// DeleteKeys soft deletes the keys of a keyspace matching the payload filters in a single statement
+// and returns the number of deleted keys. In dry run mode, keys are only counted.
+func (sqlite *Store) DeleteKeys(ctx context.Context, payload driplimit.KeyDeleteManyPayload) (*driplimit.KeyDeleteManyResult, error) {
+	where := "ksid = ? AND deleted_at = 0"
+	args := []any{payload.KSID}
+	if len(payload.KIDs) > 0 {
+		where += " AND kid IN (?)"
+		args = append(args, payload.KIDs)
+	}
+	if !payload.ExpiredBefore.IsZero() {
+		where += " AND expires_at > 0 AND expires_at < ?"
+		args = append(args, TimeNano{Time: payload.ExpiredBefore})
+	}
+
+	result := &driplimit.KeyDeleteManyResult{DryRun: payload.DryRun}
+	if payload.DryRun {
+		query, args, err := sqlx.In("SELECT COUNT(*) FROM keys WHERE "+where, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build count query: %w", err)
+		}
+		err = sqlx.GetContext(ctx, sqlite.ext(), &result.Deleted, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count keys: %w", err)
+		}
+		return result, nil
+	}
+
+	query, args, err := sqlx.In("UPDATE keys SET deleted_at = ? WHERE "+where, append([]any{TimeNano{Time: time.Now()}}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build delete query: %w", err)
+	}
+	res, err := sqlite.ext().ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete keys: %w", err)
+	}
+	result.Deleted, err = res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return result, nil
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/i4n-co/driplimit"
 	"github.com/i4n-co/driplimit/pkg/generate"
+	"github.com/jmoiron/sqlx"
 	"github.com/mattn/go-sqlite3"
 )
 
@@ -18,17 +19,41 @@ type KeyspaceModel struct {
 	Name                    string        `db:"name"`
 	KeysPrefix              string        `db:"keys_prefix"`
 	DeletedAt               TimeNano      `db:"deleted_at"`
+	GracePeriod             time.Duration `db:"grace_period"`
+	DefaultExpiresIn        time.Duration `db:"default_expires_in"`
+	MaxExpiresIn            time.Duration `db:"max_expires_in"`
 	RateLimitLimit          int64         `db:"rate_limit_limit"`
 	RateLimitRefillRate     int64         `db:"rate_limit_refill_rate"`
 	RateLimitRefillInterval time.Duration `db:"rate_limit_refill_interval"`
 }
 
+// NewKeyspaceModel creates a new keyspace model from a keyspace.
+func NewKeyspaceModel(ks driplimit.Keyspace) *KeyspaceModel {
+	model := &KeyspaceModel{
+		KSID:             ks.KSID,
+		Name:             ks.Name,
+		KeysPrefix:       ks.KeysPrefix,
+		GracePeriod:      ks.GracePeriod.Duration,
+		DefaultExpiresIn: ks.DefaultExpiresIn.Duration,
+		MaxExpiresIn:     ks.MaxExpiresIn.Duration,
+	}
+	if ks.Ratelimit != nil {
+		model.RateLimitLimit = ks.Ratelimit.Limit
+		model.RateLimitRefillRate = ks.Ratelimit.RefillRate
+		model.RateLimitRefillInterval = ks.Ratelimit.RefillInterval.Duration
+	}
+	return model
+}
+
 // ToKeyspace converts the keyspace model to a keyspace.
 func (k *KeyspaceModel) ToKeyspace() *driplimit.Keyspace {
 	ks := &driplimit.Keyspace{
-		KSID:       k.KSID,
-		Name:       k.Name,
-		KeysPrefix: k.KeysPrefix,
+		KSID:             k.KSID,
+		Name:             k.Name,
+		KeysPrefix:       k.KeysPrefix,
+		GracePeriod:      driplimit.Milliseconds{Duration: k.GracePeriod},
+		DefaultExpiresIn: driplimit.Milliseconds{Duration: k.DefaultExpiresIn},
+		MaxExpiresIn:     driplimit.Milliseconds{Duration: k.MaxExpiresIn},
 	}
 	if k.RateLimitLimit > 0 {
 		ks.Ratelimit = &driplimit.Ratelimit{
@@ -46,16 +71,32 @@ func (s *Store) CreateKeyspace(ctx context.Context, payload driplimit.KeyspaceCr
 	ks.KSID = generate.IDWithPrefix("ks_")
 	ks.Name = payload.Name
 	ks.KeysPrefix = payload.KeysPrefix
+	ks.GracePeriod = payload.GracePeriod.Duration
+	ks.DefaultExpiresIn = payload.DefaultExpiresIn.Duration
+	ks.MaxExpiresIn = payload.MaxExpiresIn.Duration
 	if payload.Ratelimit.Configured() {
 		ks.RateLimitLimit = payload.Ratelimit.Limit
 		ks.RateLimitRefillRate = payload.Ratelimit.RefillRate
 		ks.RateLimitRefillInterval = payload.Ratelimit.RefillInterval.Duration
 	}
-	_, err := s.db.NamedExecContext(ctx, `
+	err := s.insertKeyspace(ctx, ks)
+	if err != nil {
+		return nil, err
+	}
+	return ks.ToKeyspace(), nil
+}
+
+// insertKeyspace inserts the keyspace model. A unique name or keys prefix
+// violation returns ErrItemAlreadyExists.
+func (s *Store) insertKeyspace(ctx context.Context, ks *KeyspaceModel) error {
+	_, err := sqlx.NamedExecContext(ctx, s.ext(), `
 		INSERT INTO keyspaces (
 			ksid, 
 			name,
 			keys_prefix,
+			grace_period,
+			default_expires_in,
+			max_expires_in,
 			rate_limit_limit,
 			rate_limit_refill_rate,
 			rate_limit_refill_interval
@@ -64,6 +105,9 @@ func (s *Store) CreateKeyspace(ctx context.Context, payload driplimit.KeyspaceCr
 			:ksid, 
 			:name,
 			:keys_prefix,
+			:grace_period,
+			:default_expires_in,
+			:max_expires_in,
 			:rate_limit_limit,
 			:rate_limit_refill_rate,
 			:rate_limit_refill_interval
@@ -73,18 +117,18 @@ func (s *Store) CreateKeyspace(ctx context.Context, payload driplimit.KeyspaceCr
 		sqliteConstraintErr := new(sqlite3.Error)
 		if errors.As(err, sqliteConstraintErr) {
 			if sqliteConstraintErr.ExtendedCode == sqlite3.ErrConstraintUnique {
-				return nil, driplimit.ErrItemAlreadyExists("keyspace")
+				return driplimit.ErrItemAlreadyExists("keyspace")
 			}
 		}
-		return nil, fmt.Errorf("failed to create keyspace: %w", err)
+		return fmt.Errorf("failed to create keyspace: %w", err)
 	}
-	return ks.ToKeyspace(), nil
+	return nil
 }
 
 // GetKeyspaceByID returns a keyspace based on the given ID.
 func (s *Store) GetKeyspaceByID(ctx context.Context, id string) (*driplimit.Keyspace, error) {
 	ks := new(KeyspaceModel)
-	err := s.db.GetContext(ctx, ks, "SELECT * FROM v_keyspaces WHERE ksid = $1", id)
+	err := sqlx.GetContext(ctx, s.ext(), ks, "SELECT * FROM v_keyspaces WHERE ksid = $1", id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, driplimit.ErrItemNotFound("keyspace")
@@ -105,24 +149,30 @@ func (s *Store) ListKeyspaces(ctx context.Context, payload driplimit.KeyspaceLis
 	}
 	defer conn.Close()
 
-	from := "v_keyspaces"
+	// the policies, including the roles ones, are evaluated like driplimit.ServiceKey.Can: deny
+	// policies override the allow ones
+	where := ""
+	args := []any{}
 	if payload.FilterBySKIDKeyspacesPolicies != "" {
-		from = fmt.Sprintf(`(
-			select v_keyspaces.* from v_keyspaces
-			join service_keys_keyspaces_policies  ON v_keyspaces.ksid = service_keys_keyspaces_policies.ksid
-			where service_keys_keyspaces_policies.skid = '%s'
-			and service_keys_keyspaces_policies.read = 1
-		)`, payload.FilterBySKIDKeyspacesPolicies)
+		where = `WHERE EXISTS (
+			SELECT 1 FROM v_service_keys_policies AS keyspaces_policies
+			WHERE skid = $1 AND can_read = 1 AND deny = 0
+			AND (v_keyspaces.ksid GLOB keyspaces_policies.ksid OR v_keyspaces.name GLOB keyspaces_policies.ksid)
+		) AND NOT EXISTS (
+			SELECT 1 FROM v_service_keys_policies AS keyspaces_policies
+			WHERE skid = $1 AND can_read = 1 AND deny = 1
+			AND (v_keyspaces.ksid GLOB keyspaces_policies.ksid OR v_keyspaces.name GLOB keyspaces_policies.ksid)
+		)`
+		args = append(args, payload.FilterBySKIDKeyspacesPolicies)
 	}
-	sql := fmt.Sprintf("SELECT * FROM %s ORDER BY name LIMIT $1 OFFSET $2", from)
+	sql := fmt.Sprintf("SELECT * FROM v_keyspaces %s ORDER BY name LIMIT $%d OFFSET $%d", where, len(args)+1, len(args)+2)
 
-	err = conn.SelectContext(ctx, &ks, sql, payload.List.Limit, payload.List.Offset())
+	err = conn.SelectContext(ctx, &ks, sql, append(args, payload.List.Limit, payload.List.Offset())...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list keyspaces: %w", err)
 	}
 
-	sql = fmt.Sprintf("SELECT COUNT(*) FROM %s", from)
-	err = conn.GetContext(ctx, &totalCount, sql)
+	err = conn.GetContext(ctx, &totalCount, "SELECT COUNT(*) FROM v_keyspaces "+where, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to count keyspaces: %w", err)
 	}
@@ -137,6 +187,240 @@ func (s *Store) ListKeyspaces(ctx context.Context, payload driplimit.KeyspaceLis
 	return kslist, nil
 }
 
+// UpdateKeyspace partially updates a keyspace based on the given payload. Keys without their
+// own rate limit pick up the new defaults as they are resolved when the key is retrieved.
+func (s *Store) UpdateKeyspace(ctx context.Context, payload driplimit.KeyspaceUpdatePayload) (*driplimit.Keyspace, error) {
+	ks := new(KeyspaceModel)
+	err := s.WithTx(ctx, func(tx *Store) error {
+		err := sqlx.GetContext(ctx, tx.ext(), ks, "SELECT * FROM v_keyspaces WHERE ksid = $1", payload.KSID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return driplimit.ErrItemNotFound("keyspace")
+			}
+			return fmt.Errorf("failed to get keyspace by id: %w", err)
+		}
+
+		if payload.Name != nil {
+			ks.Name = *payload.Name
+		}
+		if payload.GracePeriod != nil {
+			ks.GracePeriod = payload.GracePeriod.Duration
+		}
+		if payload.DefaultExpiresIn != nil {
+			ks.DefaultExpiresIn = payload.DefaultExpiresIn.Duration
+		}
+		if payload.MaxExpiresIn != nil {
+			ks.MaxExpiresIn = payload.MaxExpiresIn.Duration
+		}
+		if ks.MaxExpiresIn > 0 && ks.DefaultExpiresIn > ks.MaxExpiresIn {
+			return driplimit.ErrInvalidPayload
+		}
+		if payload.Ratelimit != nil {
+			ks.RateLimitLimit = 0
+			ks.RateLimitRefillRate = 0
+			ks.RateLimitRefillInterval = 0
+			if payload.Ratelimit.Configured() {
+				ks.RateLimitLimit = payload.Ratelimit.Limit
+				ks.RateLimitRefillRate = payload.Ratelimit.RefillRate
+				ks.RateLimitRefillInterval = payload.Ratelimit.RefillInterval.Duration
+			}
+		}
+
+		_, err = sqlx.NamedExecContext(ctx, tx.ext(), `
+			UPDATE keyspaces
+			SET
+				name = :name,
+				grace_period = :grace_period,
+				default_expires_in = :default_expires_in,
+				max_expires_in = :max_expires_in,
+				rate_limit_limit = :rate_limit_limit,
+				rate_limit_refill_rate = :rate_limit_refill_rate,
+				rate_limit_refill_interval = :rate_limit_refill_interval
+			WHERE ksid = :ksid`, ks)
+		if err != nil {
+			// unique constraint violation
+			sqliteConstraintErr := new(sqlite3.Error)
+			if errors.As(err, sqliteConstraintErr) {
+				if sqliteConstraintErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+					return driplimit.ErrItemAlreadyExists("keyspace")
+				}
+			}
+			return fmt.Errorf("failed to update keyspace: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ks.ToKeyspace(), nil
+}
+
+// CloneKeyspace creates a copy of a keyspace with its configuration, plans and the service keys
+// and roles policies on it, within a single transaction. Keys are copied with new ids when requested, their
+// token hashes, rate limits and states are kept so tokens remain valid in the clone.
+func (s *Store) CloneKeyspace(ctx context.Context, payload driplimit.KeyspaceClonePayload) (*driplimit.Keyspace, error) {
+	var clone *KeyspaceModel
+	err := s.WithTx(ctx, func(tx *Store) error {
+		source, err := tx.GetKeyspaceByID(ctx, payload.KSID)
+		if err != nil {
+			return err
+		}
+
+		clone = NewKeyspaceModel(*source)
+		clone.KSID = generate.IDWithPrefix("ks_")
+		clone.Name = source.Name + payload.NameSuffix
+		clone.KeysPrefix = payload.KeysPrefix
+		err = tx.insertKeyspace(ctx, clone)
+		if err != nil {
+			return err
+		}
+
+		plans := make([]*PlanModel, 0)
+		err = sqlx.SelectContext(ctx, tx.ext(), &plans, "SELECT * FROM v_plans WHERE ksid = $1", source.KSID)
+		if err != nil {
+			return fmt.Errorf("failed to list plans: %w", err)
+		}
+		plids := make(map[string]string, len(plans))
+		for _, plan := range plans {
+			plids[plan.PLID] = generate.IDWithPrefix("pl_")
+			plan.PLID = plids[plan.PLID]
+			plan.KSID = clone.KSID
+			err = tx.insertPlan(ctx, plan)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.ext().ExecContext(ctx, `
+			INSERT INTO keyspaces_policies (skid, ksid, can_check, can_read, can_create, can_update, can_delete, can_manage_policies, deny)
+			SELECT skid, $1, can_check, can_read, can_create, can_update, can_delete, can_manage_policies, deny
+			FROM keyspaces_policies WHERE ksid = $2`, clone.KSID, source.KSID)
+		if err != nil {
+			return fmt.Errorf("failed to copy keyspace policies: %w", err)
+		}
+		_, err = tx.ext().ExecContext(ctx, `
+			INSERT INTO roles_policies (rlid, ksid, can_check, can_read, can_create, can_update, can_delete, can_manage_policies, deny)
+			SELECT rlid, $1, can_check, can_read, can_create, can_update, can_delete, can_manage_policies, deny
+			FROM roles_policies WHERE ksid = $2`, clone.KSID, source.KSID)
+		if err != nil {
+			return fmt.Errorf("failed to copy keyspace roles policies: %w", err)
+		}
+
+		if !payload.CopyKeys {
+			return nil
+		}
+		keys := make([]*KeyModel, 0)
+		err = sqlx.SelectContext(ctx, tx.ext(), &keys, "SELECT * FROM v_keys WHERE ksid = $1", source.KSID)
+		if err != nil {
+			return fmt.Errorf("failed to list keys: %w", err)
+		}
+		for _, key := range keys {
+			key.KID = "k_" + generate.ID()
+			key.KSID = clone.KSID
+			key.PLID = plids[key.PLID]
+			key.CreatedAt = TimeNano{Time: time.Now()}
+			key.LastUsed = TimeNano{}
+			err = tx.insertKey(ctx, key)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return clone.ToKeyspace(), nil
+}
+
+// KeyspaceStatsModel represents the database model for keyspace statistics.
+type KeyspaceStatsModel struct {
+	ActiveKeys           int64 `db:"active_keys"`
+	ExpiredKeys          int64 `db:"expired_keys"`
+	DeletedKeys          int64 `db:"deleted_keys"`
+	CustomRatelimitKeys  int64 `db:"custom_ratelimit_keys"`
+	DefaultRatelimitKeys int64 `db:"default_ratelimit_keys"`
+	UsedLast24h          int64 `db:"used_last_24h"`
+	UsedLast7d           int64 `db:"used_last_7d"`
+	ExhaustedKeys        int64 `db:"exhausted_keys"`
+}
+
+// KeyspaceStats computes the key statistics of a keyspace in a single pass over its keys.
+// Expiration accounts for the grace period and the remaining of active keys accounts for the
+// refills since their last refill, both resolved from the key, its plan or its keyspace.
+func (s *Store) KeyspaceStats(ctx context.Context, payload driplimit.KeyspaceStatsPayload) (*driplimit.KeyspaceStats, error) {
+	ks, err := s.GetKeyspaceByID(ctx, payload.KSID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	model := new(KeyspaceStatsModel)
+	err = sqlx.GetContext(ctx, s.ext(), model, `
+		WITH k AS (
+			SELECT
+				keys.deleted_at <> 0 AS deleted,
+				keys.expires_at > 0 AND keys.expires_at + COALESCE(keys.grace_period, keyspaces.grace_period) < ? AS expired,
+				keys.rate_limit_limit > 0 AS custom,
+				keys.last_used,
+				keys.rate_limit_state_remaining AS remaining,
+				keys.rate_limit_state_last_refilled AS last_refilled,
+				CASE
+					WHEN keys.rate_limit_limit > 0 THEN keys.rate_limit_limit
+					WHEN plans.rate_limit_limit > 0 THEN plans.rate_limit_limit
+					ELSE keyspaces.rate_limit_limit
+				END AS lim,
+				CASE
+					WHEN keys.rate_limit_limit > 0 THEN keys.rate_limit_refill_rate
+					WHEN plans.rate_limit_limit > 0 THEN plans.rate_limit_refill_rate
+					ELSE keyspaces.rate_limit_refill_rate
+				END AS refill_rate,
+				CASE
+					WHEN keys.rate_limit_limit > 0 THEN keys.rate_limit_refill_interval
+					WHEN plans.rate_limit_limit > 0 THEN plans.rate_limit_refill_interval
+					ELSE keyspaces.rate_limit_refill_interval
+				END AS refill_interval
+			FROM keys
+			JOIN keyspaces ON keyspaces.ksid = keys.ksid
+			LEFT JOIN plans ON plans.plid = keys.plid AND plans.deleted_at = 0
+			WHERE keys.ksid = ?
+		)
+		SELECT
+			COALESCE(SUM(NOT deleted AND NOT expired), 0) AS active_keys,
+			COALESCE(SUM(NOT deleted AND expired), 0) AS expired_keys,
+			COALESCE(SUM(deleted), 0) AS deleted_keys,
+			COALESCE(SUM(NOT deleted AND custom), 0) AS custom_ratelimit_keys,
+			COALESCE(SUM(NOT deleted AND NOT custom), 0) AS default_ratelimit_keys,
+			COALESCE(SUM(NOT deleted AND last_used >= ?), 0) AS used_last_24h,
+			COALESCE(SUM(NOT deleted AND last_used >= ?), 0) AS used_last_7d,
+			COALESCE(SUM(
+				NOT deleted AND NOT expired AND lim > 0 AND
+				remaining + (CASE WHEN refill_rate > 0 AND refill_interval > 0 THEN ((? - last_refilled) / refill_interval) * refill_rate ELSE 0 END) <= 0
+			), 0) AS exhausted_keys
+		FROM k`,
+		TimeNano{Time: now},
+		ks.KSID,
+		TimeNano{Time: now.Add(-24 * time.Hour)},
+		TimeNano{Time: now.Add(-7 * 24 * time.Hour)},
+		TimeNano{Time: now},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute keyspace stats: %w", err)
+	}
+	return &driplimit.KeyspaceStats{
+		KSID:                 ks.KSID,
+		ActiveKeys:           model.ActiveKeys,
+		ExpiredKeys:          model.ExpiredKeys,
+		DeletedKeys:          model.DeletedKeys,
+		CustomRatelimitKeys:  model.CustomRatelimitKeys,
+		DefaultRatelimitKeys: model.DefaultRatelimitKeys,
+		UsedLast24h:          model.UsedLast24h,
+		UsedLast7d:           model.UsedLast7d,
+		ExhaustedKeys:        model.ExhaustedKeys,
+		ComputedAt:           now,
+	}, nil
+}
+
 // DeleteKeyspace deletes a keyspace based on the given payload.
 func (s *Store) DeleteKeyspace(ctx context.Context, payload driplimit.KeyspaceDeletePayload) error {
 	tx, err := s.db.BeginTxx(ctx, nil)
@@ -162,10 +446,20 @@ func (s *Store) DeleteKeyspace(ctx context.Context, payload driplimit.KeyspaceDe
 		return fmt.Errorf("failed to delete keys: %w", err)
 	}
 
+	_, err = tx.ExecContext(ctx, "UPDATE plans SET deleted_at = $1 WHERE ksid = $2 AND deleted_at = 0", TimeNano{Time: time.Now()}, payload.KSID)
+	if err != nil {
+		return fmt.Errorf("failed to delete plans: %w", err)
+	}
+
 	_, err = tx.ExecContext(ctx, "DELETE FROM keyspaces_policies WHERE ksid = ?", payload.KSID)
 	if err != nil {
 		return fmt.Errorf("failed to delete keyspace policies: %w", err)
 	}
 
+	_, err = tx.ExecContext(ctx, "DELETE FROM roles_policies WHERE ksid = ?", payload.KSID)
+	if err != nil {
+		return fmt.Errorf("failed to delete keyspace roles policies: %w", err)
+	}
+
 	return tx.Commit()
 }
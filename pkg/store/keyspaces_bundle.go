@@ -0,0 +1,326 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/i4n-co/driplimit"
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+)
+
+// ExportKeyspace returns a bundle of the keyspace with its plans, keys and the policies of
+// existing service keys on it. Everything is read within a single transaction.
+func (s *Store) ExportKeyspace(ctx context.Context, payload driplimit.KeyspaceExportPayload) (*driplimit.KeyspaceBundle, error) {
+	bundle := &driplimit.KeyspaceBundle{
+		Version:    driplimit.KeyspaceBundleVersion,
+		ExportedAt: time.Now(),
+		Plans:      make([]*driplimit.Plan, 0),
+		Keys:       make([]*driplimit.KeyspaceBundleKey, 0),
+		Policies:   make(driplimit.Policies),
+	}
+	err := s.WithTx(ctx, func(tx *Store) (err error) {
+		bundle.Keyspace, err = tx.GetKeyspaceByID(ctx, payload.KSID)
+		if err != nil {
+			return err
+		}
+
+		plans := make([]*PlanModel, 0)
+		err = sqlx.SelectContext(ctx, tx.ext(), &plans, "SELECT * FROM v_plans WHERE ksid = $1 ORDER BY name", payload.KSID)
+		if err != nil {
+			return fmt.Errorf("failed to list plans: %w", err)
+		}
+		for _, plan := range plans {
+			bundle.Plans = append(bundle.Plans, plan.ToPlan())
+		}
+
+		keys := make([]*KeyModel, 0)
+		err = sqlx.SelectContext(ctx, tx.ext(), &keys, "SELECT * FROM v_keys WHERE ksid = $1 ORDER BY created_at", payload.KSID)
+		if err != nil {
+			return fmt.Errorf("failed to list keys: %w", err)
+		}
+		for _, key := range keys {
+			bundle.Keys = append(bundle.Keys, key.ToBundleKey())
+		}
+
+		policies := make([]KeyspacesPoliciesModel, 0)
+		err = sqlx.SelectContext(ctx, tx.ext(), &policies, `
+			SELECT keyspaces_policies.* FROM keyspaces_policies
+			JOIN v_service_keys ON v_service_keys.skid = keyspaces_policies.skid
+			WHERE keyspaces_policies.ksid = $1`, payload.KSID)
+		if err != nil {
+			return fmt.Errorf("failed to list keyspace policies: %w", err)
+		}
+		for _, policy := range policies {
+			bundle.Policies[policy.SKID] = policy.Policy()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+// ImportKeyspace imports a keyspace bundle within a single transaction. Items are matched by id
+// within the keyspace and existing ones are handled according to the payload strategy, whereas
+// plans and keys whose id belongs to another keyspace are rejected. Policies of service keys
+// unknown to this store are skipped.
+func (s *Store) ImportKeyspace(ctx context.Context, payload driplimit.KeyspaceImportPayload) (*driplimit.KeyspaceImportResult, error) {
+	bundle := payload.Bundle
+	ksid := bundle.Keyspace.KSID
+	result := new(driplimit.KeyspaceImportResult)
+
+	// proceed tells whether an item must be written and updates the result counts.
+	proceed := func(item string, exists bool) (bool, error) {
+		if !exists {
+			result.Created++
+			return true, nil
+		}
+		switch payload.Strategy {
+		case driplimit.ImportOverwrite:
+			result.Overwritten++
+			return true, nil
+		case driplimit.ImportSkip:
+			result.Skipped++
+			return false, nil
+		default:
+			return false, driplimit.ErrItemAlreadyExists(item)
+		}
+	}
+
+	err := s.WithTx(ctx, func(tx *Store) (err error) {
+		exists, err := tx.exists(ctx, "SELECT 1 FROM v_keyspaces WHERE ksid = $1", ksid)
+		if err != nil {
+			return err
+		}
+		write, err := proceed("keyspace", exists)
+		if err != nil {
+			return err
+		}
+		if write {
+			_, err = sqlx.NamedExecContext(ctx, tx.ext(), `
+				INSERT INTO keyspaces (
+					ksid,
+					name,
+					keys_prefix,
+					grace_period,
+					default_expires_in,
+					max_expires_in,
+					rate_limit_limit,
+					rate_limit_refill_rate,
+					rate_limit_refill_interval
+				)
+				VALUES (
+					:ksid,
+					:name,
+					:keys_prefix,
+					:grace_period,
+					:default_expires_in,
+					:max_expires_in,
+					:rate_limit_limit,
+					:rate_limit_refill_rate,
+					:rate_limit_refill_interval
+				)
+				ON CONFLICT (ksid) DO UPDATE SET
+					name = excluded.name,
+					keys_prefix = excluded.keys_prefix,
+					grace_period = excluded.grace_period,
+					default_expires_in = excluded.default_expires_in,
+					max_expires_in = excluded.max_expires_in,
+					rate_limit_limit = excluded.rate_limit_limit,
+					rate_limit_refill_rate = excluded.rate_limit_refill_rate,
+					rate_limit_refill_interval = excluded.rate_limit_refill_interval,
+					deleted_at = 0`, NewKeyspaceModel(*bundle.Keyspace))
+			if err != nil {
+				return importErr("keyspace", err)
+			}
+		}
+
+		for _, plan := range bundle.Plans {
+			taken, err := tx.exists(ctx, "SELECT 1 FROM plans WHERE plid = $1 AND ksid <> $2", plan.PLID, ksid)
+			if err != nil {
+				return err
+			}
+			if taken {
+				return driplimit.ErrItemAlreadyExists("plan")
+			}
+			exists, err := tx.exists(ctx, "SELECT 1 FROM v_plans WHERE plid = $1 AND ksid = $2", plan.PLID, ksid)
+			if err != nil {
+				return err
+			}
+			write, err := proceed("plan", exists)
+			if err != nil {
+				return err
+			}
+			if !write {
+				continue
+			}
+			model := NewPlanModel(*plan)
+			model.KSID = ksid
+			_, err = sqlx.NamedExecContext(ctx, tx.ext(), `
+				INSERT INTO plans (
+					plid,
+					ksid,
+					name,
+					rate_limit_limit,
+					rate_limit_refill_rate,
+					rate_limit_refill_interval
+				)
+				VALUES (
+					:plid,
+					:ksid,
+					:name,
+					:rate_limit_limit,
+					:rate_limit_refill_rate,
+					:rate_limit_refill_interval
+				)
+				ON CONFLICT (plid) DO UPDATE SET
+					name = excluded.name,
+					rate_limit_limit = excluded.rate_limit_limit,
+					rate_limit_refill_rate = excluded.rate_limit_refill_rate,
+					rate_limit_refill_interval = excluded.rate_limit_refill_interval,
+					deleted_at = 0
+				WHERE plans.ksid = excluded.ksid`, model)
+			if err != nil {
+				return importErr("plan", err)
+			}
+		}
+
+		for _, key := range bundle.Keys {
+			taken, err := tx.exists(ctx, "SELECT 1 FROM keys WHERE kid = $1 AND ksid <> $2", key.KID, ksid)
+			if err != nil {
+				return err
+			}
+			if taken {
+				return driplimit.ErrItemAlreadyExists("key")
+			}
+			exists, err := tx.exists(ctx, "SELECT 1 FROM v_keys WHERE kid = $1 AND ksid = $2", key.KID, ksid)
+			if err != nil {
+				return err
+			}
+			write, err := proceed("key", exists)
+			if err != nil {
+				return err
+			}
+			if !write {
+				continue
+			}
+			_, err = sqlx.NamedExecContext(ctx, tx.ext(), `
+				INSERT INTO keys (
+					kid,
+					ksid,
+					plid,
+					token_hash,
+					last_used,
+					expires_at,
+					created_at,
+					grace_period,
+					rate_limit_state_last_refilled,
+					rate_limit_state_remaining,
+					rate_limit_limit,
+					rate_limit_refill_rate,
+					rate_limit_refill_interval
+				)
+				VALUES (
+					:kid,
+					:ksid,
+					:plid,
+					:token_hash,
+					:last_used,
+					:expires_at,
+					:created_at,
+					:grace_period,
+					:rate_limit_state_last_refilled,
+					:rate_limit_state_remaining,
+					:rate_limit_limit,
+					:rate_limit_refill_rate,
+					:rate_limit_refill_interval
+				)
+				ON CONFLICT (kid) DO UPDATE SET
+					plid = excluded.plid,
+					token_hash = excluded.token_hash,
+					last_used = excluded.last_used,
+					expires_at = excluded.expires_at,
+					created_at = excluded.created_at,
+					grace_period = excluded.grace_period,
+					rate_limit_state_last_refilled = excluded.rate_limit_state_last_refilled,
+					rate_limit_state_remaining = excluded.rate_limit_state_remaining,
+					rate_limit_limit = excluded.rate_limit_limit,
+					rate_limit_refill_rate = excluded.rate_limit_refill_rate,
+					rate_limit_refill_interval = excluded.rate_limit_refill_interval,
+					deleted_at = 0
+				WHERE keys.ksid = excluded.ksid`, NewKeyModelFromBundle(ksid, *key))
+			if err != nil {
+				return importErr("key", err)
+			}
+		}
+
+		for skid, policy := range bundle.Policies {
+			known, err := tx.exists(ctx, "SELECT 1 FROM v_service_keys WHERE skid = $1", skid)
+			if err != nil {
+				return err
+			}
+			if !known {
+				result.Skipped++
+				continue
+			}
+			exists, err := tx.exists(ctx, "SELECT 1 FROM keyspaces_policies WHERE skid = $1 AND ksid = $2", skid, ksid)
+			if err != nil {
+				return err
+			}
+			write, err := proceed("policy", exists)
+			if err != nil {
+				return err
+			}
+			if !write {
+				continue
+			}
+			_, err = sqlx.NamedExecContext(ctx, tx.ext(), `
+				INSERT INTO keyspaces_policies (skid, ksid, can_check, can_read, can_create, can_update, can_delete, can_manage_policies, deny)
+				VALUES (:skid, :ksid, :can_check, :can_read, :can_create, :can_update, :can_delete, :can_manage_policies, :deny)
+				ON CONFLICT (skid, ksid) DO UPDATE SET
+					can_check = excluded.can_check,
+					can_read = excluded.can_read,
+					can_create = excluded.can_create,
+					can_update = excluded.can_update,
+					can_delete = excluded.can_delete,
+					can_manage_policies = excluded.can_manage_policies,
+					deny = excluded.deny`, NewKeyspacesPoliciesModel(skid, ksid, policy))
+			if err != nil {
+				return importErr("policy", err)
+			}
+		}
+
+		result.Keyspace, err = tx.GetKeyspaceByID(ctx, ksid)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// exists returns true if the given query returns at least one row.
+func (s *Store) exists(ctx context.Context, query string, args ...any) (bool, error) {
+	found := false
+	err := sqlx.GetContext(ctx, s.ext(), &found, fmt.Sprintf("SELECT EXISTS (%s)", query), args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to check existence: %w", err)
+	}
+	return found, nil
+}
+
+// importErr maps unique constraint violations raised while importing an item
+// to ErrItemAlreadyExists.
+func importErr(item string, err error) error {
+	sqliteConstraintErr := new(sqlite3.Error)
+	if errors.As(err, sqliteConstraintErr) {
+		if sqliteConstraintErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return driplimit.ErrItemAlreadyExists(item)
+		}
+	}
+	return fmt.Errorf("failed to import %s: %w", item, err)
+}
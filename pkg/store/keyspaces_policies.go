@@ -5,78 +5,165 @@ import (
 	"fmt"
 
 	"github.com/i4n-co/driplimit"
+	"github.com/jmoiron/sqlx"
 )
 
+// PolicyModel is the database model for the actions of a keyspace policy.
+type PolicyModel struct {
+	Check          bool `db:"can_check"`
+	Read           bool `db:"can_read"`
+	Create         bool `db:"can_create"`
+	Update         bool `db:"can_update"`
+	Delete         bool `db:"can_delete"`
+	ManagePolicies bool `db:"can_manage_policies"`
+	Deny           bool `db:"deny"`
+}
+
+// NewPolicyModel creates a new policy model from a policy.
+func NewPolicyModel(policy driplimit.Policy) PolicyModel {
+	return PolicyModel{
+		Check:          policy.Check,
+		Read:           policy.Read,
+		Create:         policy.Create,
+		Update:         policy.Update,
+		Delete:         policy.Delete,
+		ManagePolicies: policy.ManagePolicies,
+		Deny:           policy.Deny,
+	}
+}
+
+// Policy returns the policy from the model.
+func (m PolicyModel) Policy() driplimit.Policy {
+	return driplimit.Policy{
+		Check:          m.Check,
+		Read:           m.Read,
+		Create:         m.Create,
+		Update:         m.Update,
+		Delete:         m.Delete,
+		ManagePolicies: m.ManagePolicies,
+		Deny:           m.Deny,
+	}
+}
+
 // KeyspacesPoliciesModel is the database model for service key keyspaces policies
 type KeyspacesPoliciesModel struct {
-	SKID  string `db:"skid"`
+	SKID string `db:"skid"`
+	KSID string `db:"ksid"`
+	PolicyModel
+}
+
+// NewKeyspacesPoliciesModel creates a new keyspaces policies model from the policy of the
+// service key skid on the keyspace ksid.
+func NewKeyspacesPoliciesModel(skid, ksid string, policy driplimit.Policy) KeyspacesPoliciesModel {
+	return KeyspacesPoliciesModel{
+		SKID:        skid,
+		KSID:        ksid,
+		PolicyModel: NewPolicyModel(policy),
+	}
+}
+
+// ownedPolicyModel is the database model of a policy in a policies table, owner is either
+// the service key or the role holding the policy.
+type ownedPolicyModel struct {
+	Owner string `db:"owner"`
 	KSID  string `db:"ksid"`
-	Read  bool   `db:"read"`
-	Write bool   `db:"write"`
+	PolicyModel
 }
 
+// policiesTable describes a table storing keyspaces policies.
+type policiesTable struct {
+	name        string
+	ownerColumn string
+}
+
+var (
+	serviceKeysPoliciesTable = policiesTable{name: "keyspaces_policies", ownerColumn: "skid"}
+	rolesPoliciesTable       = policiesTable{name: "roles_policies", ownerColumn: "rlid"}
+)
+
+// SetKeyspacesPolicies replaces the keyspaces policies of the service key skid. Nil policies are
+// left untouched while empty policies remove them all.
 func (s *Store) SetKeyspacesPolicies(ctx context.Context, skid string, policies driplimit.Policies) error {
-	if len(policies) == 0 {
-		return nil
-	}
-	tx, err := s.db.BeginTxx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("failed to start transaction: %w", err)
-	}
-	defer tx.Rollback()
+	return s.replacePolicies(ctx, serviceKeysPoliciesTable, skid, policies)
+}
 
-	_, err = s.db.ExecContext(ctx, `
-		DELETE FROM keyspaces_policies WHERE skid = ?
-	`, skid)
-	if err != nil {
-		return fmt.Errorf("failed to delete sk keyspace policies: %w", err)
-	}
+// GetKeyspacesPolicies returns the keyspaces policies of the service key skid.
+func (s *Store) GetKeyspacesPolicies(ctx context.Context, skid string) (driplimit.Policies, error) {
+	return s.getPolicies(ctx, serviceKeysPoliciesTable, skid)
+}
 
-	for id, policy := range policies {
-		ks, err := s.GetKeyspaceByID(ctx, id)
+// replacePolicies replaces the policies of the owner in the table. Nil policies are left
+// untouched while empty policies remove them all.
+func (s *Store) replacePolicies(ctx context.Context, table policiesTable, owner string, policies driplimit.Policies) error {
+	if policies == nil {
+		return nil
+	}
+	return s.WithTx(ctx, func(tx *Store) error {
+		_, err := tx.ext().ExecContext(ctx, fmt.Sprintf(`
+			DELETE FROM %s WHERE %s = ?
+		`, table.name, table.ownerColumn), owner)
 		if err != nil {
-			return err
+			return fmt.Errorf("failed to delete %s keyspace policies: %w", table.ownerColumn, err)
 		}
-		_, err = s.db.NamedExecContext(ctx, `
-			INSERT INTO keyspaces_policies (
-				skid,
-				ksid,
-				read,
-				write
-			) VALUES (
-				:skid,
-				:ksid,
-				:read,
-				:write
-			)
-		`, KeyspacesPoliciesModel{
-			SKID:  skid,
-			KSID:  ks.KSID,
-			Read:  policy.Read,
-			Write: policy.Write,
-		})
-		if err != nil {
-			return err
+
+		for id, policy := range policies {
+			// patterns, including the wildcard, are matched against keyspaces when evaluated
+			if !driplimit.IsPolicyPattern(id) {
+				ks, err := tx.GetKeyspaceByID(ctx, id)
+				if err != nil {
+					return err
+				}
+				id = ks.KSID
+			}
+			_, err = sqlx.NamedExecContext(ctx, tx.ext(), fmt.Sprintf(`
+				INSERT INTO %s (
+					%s,
+					ksid,
+					can_check,
+					can_read,
+					can_create,
+					can_update,
+					can_delete,
+					can_manage_policies,
+					deny
+				) VALUES (
+					:owner,
+					:ksid,
+					:can_check,
+					:can_read,
+					:can_create,
+					:can_update,
+					:can_delete,
+					:can_manage_policies,
+					:deny
+				)
+			`, table.name, table.ownerColumn), ownedPolicyModel{
+				Owner:       owner,
+				KSID:        id,
+				PolicyModel: NewPolicyModel(policy),
+			})
+			if err != nil {
+				return err
+			}
 		}
-	}
-	return tx.Commit()
+		return nil
+	})
 }
 
-func (s *Store) GetKeyspacesPolicies(ctx context.Context, skid string) (driplimit.Policies, error) {
-	policies := make([]KeyspacesPoliciesModel, 0)
-	err := s.db.SelectContext(ctx, &policies, `
-		SELECT * FROM keyspaces_policies WHERE skid = ?
-	`, skid)
+// getPolicies returns the policies of the owner in the table.
+func (s *Store) getPolicies(ctx context.Context, table policiesTable, owner string) (driplimit.Policies, error) {
+	policies := make([]ownedPolicyModel, 0)
+	err := sqlx.SelectContext(ctx, s.ext(), &policies, fmt.Sprintf(`
+		SELECT %[2]s AS owner, ksid, can_check, can_read, can_create, can_update, can_delete, can_manage_policies, deny
+		FROM %[1]s WHERE %[2]s = ?
+	`, table.name, table.ownerColumn), owner)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get sk keyspace policies: %w", err)
+		return nil, fmt.Errorf("failed to get %s keyspace policies: %w", table.ownerColumn, err)
 	}
 
 	kps := make(driplimit.Policies, 0)
 	for _, kp := range policies {
-		kps[kp.KSID] = driplimit.Policy{
-			Read:  kp.Read,
-			Write: kp.Write,
-		}
+		kps[kp.KSID] = kp.Policy()
 	}
 	return kps, nil
 }
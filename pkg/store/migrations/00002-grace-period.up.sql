@@ -0,0 +1,3 @@
+-- add expiration grace period to keyspaces (default for their keys) and keys (override)
+ALTER TABLE keyspaces ADD COLUMN grace_period int NOT NULL DEFAULT 0;
+ALTER TABLE keys ADD COLUMN grace_period int NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+-- add default and maximum key lifetime to keyspaces
+ALTER TABLE keyspaces ADD COLUMN default_expires_in int NOT NULL DEFAULT 0;
+ALTER TABLE keyspaces ADD COLUMN max_expires_in int NOT NULL DEFAULT 0;
@@ -0,0 +1,21 @@
+-- create plans table, reusable rate limit configurations within a keyspace
+CREATE TABLE
+    IF NOT EXISTS plans (
+        plid text PRIMARY KEY,
+        ksid text NOT NULL,
+        name text NOT NULL,
+        rate_limit_limit int NOT NULL DEFAULT 0,
+        rate_limit_refill_rate int NOT NULL DEFAULT 0,
+        rate_limit_refill_interval int NOT NULL DEFAULT 0,
+        deleted_at INT NOT NULL DEFAULT 0,
+        FOREIGN KEY (ksid) REFERENCES keyspaces (ksid)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_plans_ksid ON plans (ksid);
+CREATE UNIQUE INDEX idx_unique_plans_name ON plans (ksid, name) WHERE deleted_at = 0;
+-- Create a view to filter out deleted plans
+CREATE VIEW v_plans AS SELECT * FROM plans WHERE deleted_at = 0;
+
+-- keys may reference a plan providing their rate limit
+ALTER TABLE keys ADD COLUMN plid text NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_keys_plid ON keys (plid);
@@ -0,0 +1,3 @@
+-- indexes supporting per keyspace key statistics and bulk operations
+CREATE INDEX IF NOT EXISTS idx_keys_ksid_deleted_at_expires_at ON keys (ksid, deleted_at, expires_at);
+CREATE INDEX IF NOT EXISTS idx_keys_ksid_deleted_at_last_used ON keys (ksid, deleted_at, last_used);
@@ -0,0 +1,2 @@
+-- add an optional expiration to service keys
+ALTER TABLE service_keys ADD COLUMN expires_at int NOT NULL DEFAULT 0;
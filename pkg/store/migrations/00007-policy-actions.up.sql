@@ -0,0 +1,16 @@
+-- split the read and write permissions of keyspaces policies into distinct actions
+ALTER TABLE keyspaces_policies RENAME COLUMN read TO can_read;
+ALTER TABLE keyspaces_policies ADD COLUMN can_check INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE keyspaces_policies ADD COLUMN can_create INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE keyspaces_policies ADD COLUMN can_update INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE keyspaces_policies ADD COLUMN can_delete INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE keyspaces_policies ADD COLUMN can_manage_policies INTEGER NOT NULL DEFAULT 0;
+
+-- read used to allow checking keys, write used to allow creating, updating and deleting them
+UPDATE keyspaces_policies SET
+    can_check = can_read,
+    can_create = write,
+    can_update = write,
+    can_delete = write;
+
+ALTER TABLE keyspaces_policies DROP COLUMN write;
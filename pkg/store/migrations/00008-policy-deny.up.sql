@@ -0,0 +1,4 @@
+-- keyspaces policies keys can now be glob patterns over the keyspaces ids and names,
+-- deny policies override the allow policies matching the same keyspace
+ALTER TABLE keyspaces_policies ADD COLUMN deny INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX idx_keyspaces_policies_skid ON keyspaces_policies (skid);
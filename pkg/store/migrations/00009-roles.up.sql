@@ -0,0 +1,49 @@
+-- create roles table, named sets of keyspaces policies assignable to service keys
+CREATE TABLE
+    IF NOT EXISTS roles (
+        rlid text PRIMARY KEY,
+        name text NOT NULL,
+        created_at INT NOT NULL,
+        deleted_at INT NOT NULL DEFAULT 0
+    );
+
+CREATE UNIQUE INDEX idx_unique_roles_name ON roles (name) WHERE deleted_at = 0;
+-- Create a view to filter out deleted roles
+CREATE VIEW v_roles AS SELECT * FROM roles WHERE deleted_at = 0;
+
+-- roles policies mirror the service keys keyspaces policies
+CREATE TABLE roles_policies (
+    rlid                TEXT        NOT NULL,
+    ksid                TEXT        NOT NULL,
+    can_check           INTEGER     NOT NULL DEFAULT 0,
+    can_read            INTEGER     NOT NULL DEFAULT 0,
+    can_create          INTEGER     NOT NULL DEFAULT 0,
+    can_update          INTEGER     NOT NULL DEFAULT 0,
+    can_delete          INTEGER     NOT NULL DEFAULT 0,
+    can_manage_policies INTEGER     NOT NULL DEFAULT 0,
+    deny                INTEGER     NOT NULL DEFAULT 0,
+    PRIMARY KEY (rlid, ksid),
+    FOREIGN KEY (rlid) REFERENCES roles (rlid)
+);
+
+-- roles assigned to service keys
+CREATE TABLE service_keys_roles (
+    skid                TEXT        NOT NULL,
+    rlid                TEXT        NOT NULL,
+    PRIMARY KEY (skid, rlid),
+    FOREIGN KEY (skid) REFERENCES service_keys (skid),
+    FOREIGN KEY (rlid) REFERENCES roles (rlid)
+);
+CREATE INDEX idx_service_keys_roles_rlid ON service_keys_roles (rlid);
+
+-- effective policies of the service keys: their own policies and the policies of their roles
+CREATE VIEW v_service_keys_policies AS
+    SELECT skid, ksid, can_check, can_read, can_create, can_update, can_delete, can_manage_policies, deny
+    FROM keyspaces_policies
+    UNION ALL
+    SELECT service_keys_roles.skid, roles_policies.ksid, roles_policies.can_check, roles_policies.can_read,
+        roles_policies.can_create, roles_policies.can_update, roles_policies.can_delete,
+        roles_policies.can_manage_policies, roles_policies.deny
+    FROM service_keys_roles
+    JOIN v_roles ON v_roles.rlid = service_keys_roles.rlid
+    JOIN roles_policies ON roles_policies.rlid = service_keys_roles.rlid;
@@ -0,0 +1,16 @@
+-- audit log of the mutating calls made to the service
+CREATE TABLE
+    IF NOT EXISTS audit_entries (
+        aeid text PRIMARY KEY,
+        skid text NOT NULL,
+        action text NOT NULL,
+        target_ids text NOT NULL DEFAULT '[]',
+        payload text NOT NULL DEFAULT '{}',
+        result text NOT NULL,
+        error text NOT NULL DEFAULT '',
+        created_at INT NOT NULL
+    );
+
+CREATE INDEX idx_audit_entries_created_at ON audit_entries (created_at);
+CREATE INDEX idx_audit_entries_skid ON audit_entries (skid, created_at);
+CREATE INDEX idx_audit_entries_action ON audit_entries (action, created_at);
@@ -0,0 +1,2 @@
+-- add the secret used to verify the HMAC signed requests of service keys
+ALTER TABLE service_keys ADD COLUMN signing_secret text NOT NULL DEFAULT '';
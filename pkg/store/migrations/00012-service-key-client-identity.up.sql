@@ -0,0 +1,4 @@
+-- add the identity of the TLS client certificates authenticating as service keys
+ALTER TABLE service_keys ADD COLUMN client_identity text NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX idx_unique_service_keys_client_identity ON service_keys (client_identity) WHERE client_identity != '' AND deleted_at = 0;
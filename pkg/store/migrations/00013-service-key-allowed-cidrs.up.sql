@@ -0,0 +1,2 @@
+-- add the networks service keys can be used from, as a JSON array of CIDRs
+ALTER TABLE service_keys ADD COLUMN allowed_cidrs text NOT NULL DEFAULT '[]';
@@ -0,0 +1,7 @@
+-- add the rate limits of the service keys calls to the driplimit API
+ALTER TABLE service_keys ADD COLUMN api_rate_limit_limit int NOT NULL DEFAULT 0;
+ALTER TABLE service_keys ADD COLUMN api_rate_limit_refill_rate int NOT NULL DEFAULT 0;
+ALTER TABLE service_keys ADD COLUMN api_rate_limit_refill_interval int NOT NULL DEFAULT 0;
+ALTER TABLE service_keys ADD COLUMN checks_rate_limit_limit int NOT NULL DEFAULT 0;
+ALTER TABLE service_keys ADD COLUMN checks_rate_limit_refill_rate int NOT NULL DEFAULT 0;
+ALTER TABLE service_keys ADD COLUMN checks_rate_limit_refill_interval int NOT NULL DEFAULT 0;
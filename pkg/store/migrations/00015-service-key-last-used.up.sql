@@ -0,0 +1,3 @@
+-- add the last use of service keys, recorded in batch
+ALTER TABLE service_keys ADD COLUMN last_used_at int NOT NULL DEFAULT 0;
+ALTER TABLE service_keys ADD COLUMN last_used_addr text NOT NULL DEFAULT '';
@@ -0,0 +1,8 @@
+-- add a secondary token to service keys so that their token can be rotated without downtime
+ALTER TABLE service_keys ADD COLUMN secondary_token_hash text NOT NULL DEFAULT '';
+ALTER TABLE service_keys ADD COLUMN token_created_at int NOT NULL DEFAULT 0;
+ALTER TABLE service_keys ADD COLUMN secondary_token_created_at int NOT NULL DEFAULT 0;
+
+UPDATE service_keys SET token_created_at = created_at;
+
+CREATE INDEX idx_service_keys_secondary_token_hash ON service_keys (secondary_token_hash) WHERE secondary_token_hash != '';
@@ -0,0 +1,5 @@
+-- make keys grace period nullable, NULL meaning the keyspace grace period applies and 0 no grace period
+ALTER TABLE keys ADD COLUMN grace_period_override int;
+UPDATE keys SET grace_period_override = grace_period WHERE grace_period > 0;
+ALTER TABLE keys DROP COLUMN grace_period;
+ALTER TABLE keys RENAME COLUMN grace_period_override TO grace_period;
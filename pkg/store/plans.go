@@ -0,0 +1,232 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/i4n-co/driplimit"
+	"github.com/i4n-co/driplimit/pkg/generate"
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+)
+
+// PlanModel represents the database model for a plan.
+type PlanModel struct {
+	PLID                    string        `db:"plid"`
+	KSID                    string        `db:"ksid"`
+	Name                    string        `db:"name"`
+	DeletedAt               TimeNano      `db:"deleted_at"`
+	RateLimitLimit          int64         `db:"rate_limit_limit"`
+	RateLimitRefillRate     int64         `db:"rate_limit_refill_rate"`
+	RateLimitRefillInterval time.Duration `db:"rate_limit_refill_interval"`
+}
+
+// NewPlanModel creates a new plan model from a plan.
+func NewPlanModel(plan driplimit.Plan) *PlanModel {
+	model := &PlanModel{
+		PLID: plan.PLID,
+		KSID: plan.KSID,
+		Name: plan.Name,
+	}
+	if plan.Ratelimit != nil {
+		model.RateLimitLimit = plan.Ratelimit.Limit
+		model.RateLimitRefillRate = plan.Ratelimit.RefillRate
+		model.RateLimitRefillInterval = plan.Ratelimit.RefillInterval.Duration
+	}
+	return model
+}
+
+// ToPlan converts the plan model to a plan.
+func (p *PlanModel) ToPlan() *driplimit.Plan {
+	plan := &driplimit.Plan{
+		PLID: p.PLID,
+		KSID: p.KSID,
+		Name: p.Name,
+	}
+	if p.RateLimitLimit > 0 {
+		plan.Ratelimit = &driplimit.Ratelimit{
+			Limit:          p.RateLimitLimit,
+			RefillRate:     p.RateLimitRefillRate,
+			RefillInterval: driplimit.Milliseconds{Duration: p.RateLimitRefillInterval},
+		}
+	}
+	return plan
+}
+
+// CreatePlan creates a new plan in a keyspace.
+func (s *Store) CreatePlan(ctx context.Context, payload driplimit.PlanCreatePayload) (*driplimit.Plan, error) {
+	ks, err := s.GetKeyspaceByID(ctx, payload.KSID)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := new(PlanModel)
+	plan.PLID = generate.IDWithPrefix("pl_")
+	plan.KSID = ks.KSID
+	plan.Name = payload.Name
+	plan.RateLimitLimit = payload.Ratelimit.Limit
+	plan.RateLimitRefillRate = payload.Ratelimit.RefillRate
+	plan.RateLimitRefillInterval = payload.Ratelimit.RefillInterval.Duration
+
+	err = s.insertPlan(ctx, plan)
+	if err != nil {
+		return nil, err
+	}
+	return plan.ToPlan(), nil
+}
+
+// insertPlan inserts the plan model. A unique name violation within
+// the keyspace returns ErrItemAlreadyExists.
+func (s *Store) insertPlan(ctx context.Context, plan *PlanModel) error {
+	_, err := sqlx.NamedExecContext(ctx, s.ext(), `
+		INSERT INTO plans (
+			plid,
+			ksid,
+			name,
+			rate_limit_limit,
+			rate_limit_refill_rate,
+			rate_limit_refill_interval
+		)
+		VALUES (
+			:plid,
+			:ksid,
+			:name,
+			:rate_limit_limit,
+			:rate_limit_refill_rate,
+			:rate_limit_refill_interval
+		)`, plan)
+	if err != nil {
+		// unique constraint violation
+		sqliteConstraintErr := new(sqlite3.Error)
+		if errors.As(err, sqliteConstraintErr) {
+			if sqliteConstraintErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+				return driplimit.ErrItemAlreadyExists("plan")
+			}
+		}
+		return fmt.Errorf("failed to create plan: %w", err)
+	}
+	return nil
+}
+
+// GetPlan returns the plan of a keyspace matching the given payload.
+func (s *Store) GetPlan(ctx context.Context, payload driplimit.PlanGetPayload) (*driplimit.Plan, error) {
+	plan, err := s.getPlan(ctx, payload.KSID, payload.PLID)
+	if err != nil {
+		return nil, err
+	}
+	return plan.ToPlan(), nil
+}
+
+// getPlan returns the plan model of a keyspace by its id.
+func (s *Store) getPlan(ctx context.Context, ksid string, plid string) (*PlanModel, error) {
+	plan := new(PlanModel)
+	err := sqlx.GetContext(ctx, s.ext(), plan, "SELECT * FROM v_plans WHERE plid = $1 AND ksid = $2", plid, ksid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, driplimit.ErrItemNotFound("plan")
+		}
+		return nil, fmt.Errorf("failed to get plan by id: %w", err)
+	}
+	return plan, nil
+}
+
+// ListPlans returns the plans of a keyspace based on the given payload.
+func (s *Store) ListPlans(ctx context.Context, payload driplimit.PlanListPayload) (*driplimit.PlanList, error) {
+	totalCount := 0
+	plans := make([]*PlanModel, 0)
+
+	ks, err := s.GetKeyspaceByID(ctx, payload.KSID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = sqlx.SelectContext(ctx, s.ext(), &plans, "SELECT * FROM v_plans WHERE ksid = $1 ORDER BY name LIMIT $2 OFFSET $3", ks.KSID, payload.List.Limit, payload.List.Offset())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list plans: %w", err)
+	}
+	err = sqlx.GetContext(ctx, s.ext(), &totalCount, "SELECT COUNT(*) FROM v_plans WHERE ksid = $1", ks.KSID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count plans: %w", err)
+	}
+
+	plist := &driplimit.PlanList{
+		List:  driplimit.NewListMetadata(payload.List, totalCount),
+		Plans: make([]*driplimit.Plan, 0),
+	}
+	for _, p := range plans {
+		plist.Plans = append(plist.Plans, p.ToPlan())
+	}
+	return plist, nil
+}
+
+// UpdatePlan partially updates a plan based on the given payload. Keys referencing the plan
+// pick up the changes at once as the plan is resolved when the key is retrieved.
+func (s *Store) UpdatePlan(ctx context.Context, payload driplimit.PlanUpdatePayload) (*driplimit.Plan, error) {
+	var plan *PlanModel
+	err := s.WithTx(ctx, func(tx *Store) (err error) {
+		plan, err = tx.getPlan(ctx, payload.KSID, payload.PLID)
+		if err != nil {
+			return err
+		}
+
+		if payload.Name != nil {
+			plan.Name = *payload.Name
+		}
+		if payload.Ratelimit != nil {
+			plan.RateLimitLimit = payload.Ratelimit.Limit
+			plan.RateLimitRefillRate = payload.Ratelimit.RefillRate
+			plan.RateLimitRefillInterval = payload.Ratelimit.RefillInterval.Duration
+		}
+
+		_, err = sqlx.NamedExecContext(ctx, tx.ext(), `
+			UPDATE plans
+			SET
+				name = :name,
+				rate_limit_limit = :rate_limit_limit,
+				rate_limit_refill_rate = :rate_limit_refill_rate,
+				rate_limit_refill_interval = :rate_limit_refill_interval
+			WHERE plid = :plid`, plan)
+		if err != nil {
+			// unique constraint violation
+			sqliteConstraintErr := new(sqlite3.Error)
+			if errors.As(err, sqliteConstraintErr) {
+				if sqliteConstraintErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+					return driplimit.ErrItemAlreadyExists("plan")
+				}
+			}
+			return fmt.Errorf("failed to update plan: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plan.ToPlan(), nil
+}
+
+// DeletePlan deletes a plan. Keys referencing the plan are detached from it
+// and fall back to the keyspace defaults.
+func (s *Store) DeletePlan(ctx context.Context, payload driplimit.PlanDeletePayload) error {
+	return s.WithTx(ctx, func(tx *Store) error {
+		res, err := tx.ext().ExecContext(ctx, "UPDATE plans SET deleted_at = $1 WHERE plid = $2 AND ksid = $3 AND deleted_at = 0", TimeNano{Time: time.Now()}, payload.PLID, payload.KSID)
+		if err != nil {
+			return fmt.Errorf("failed to delete plan: %w", err)
+		}
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rows == 0 {
+			return driplimit.ErrItemNotFound("plan")
+		}
+
+		_, err = tx.ext().ExecContext(ctx, "UPDATE keys SET plid = '' WHERE plid = $1", payload.PLID)
+		if err != nil {
+			return fmt.Errorf("failed to detach keys from plan: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,237 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/i4n-co/driplimit"
+	"github.com/i4n-co/driplimit/pkg/generate"
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+)
+
+// RoleModel represents the database model for a role.
+type RoleModel struct {
+	RLID      string   `db:"rlid"`
+	Name      string   `db:"name"`
+	CreatedAt TimeNano `db:"created_at"`
+	DeletedAt TimeNano `db:"deleted_at"`
+}
+
+// ToRole converts the role model to a role.
+func (r *RoleModel) ToRole() *driplimit.Role {
+	return &driplimit.Role{
+		RLID:      r.RLID,
+		Name:      r.Name,
+		CreatedAt: r.CreatedAt.Time,
+	}
+}
+
+// CreateRole creates a new role with its keyspaces policies.
+func (s *Store) CreateRole(ctx context.Context, payload driplimit.RoleCreatePayload) (role *driplimit.Role, err error) {
+	model := new(RoleModel)
+	model.RLID = generate.IDWithPrefix("rl_")
+	model.Name = payload.Name
+	model.CreatedAt = TimeNano{Time: time.Now()}
+
+	err = s.WithTx(ctx, func(tx *Store) error {
+		_, err := sqlx.NamedExecContext(ctx, tx.ext(), `
+			INSERT INTO roles (
+				rlid,
+				name,
+				created_at
+			) VALUES (
+				:rlid,
+				:name,
+				:created_at
+			)`, model)
+		if err != nil {
+			return roleErr(err)
+		}
+
+		err = tx.replacePolicies(ctx, rolesPoliciesTable, model.RLID, payload.KeyspacesPolicies)
+		if err != nil {
+			return fmt.Errorf("failed to set role keyspaces policies: %w", err)
+		}
+
+		role, err = tx.getRole(ctx, model.RLID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// GetRole returns the role matching the given payload along with its keyspaces policies.
+func (s *Store) GetRole(ctx context.Context, payload driplimit.RoleGetPayload) (*driplimit.Role, error) {
+	return s.getRole(ctx, payload.RLID)
+}
+
+func (s *Store) getRole(ctx context.Context, rlid string) (*driplimit.Role, error) {
+	model := new(RoleModel)
+	err := sqlx.GetContext(ctx, s.ext(), model, "SELECT * FROM v_roles WHERE rlid = $1", rlid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, driplimit.ErrItemNotFound("role")
+		}
+		return nil, fmt.Errorf("failed to get role by id: %w", err)
+	}
+
+	role := model.ToRole()
+	role.KeyspacesPolicies, err = s.getPolicies(ctx, rolesPoliciesTable, role.RLID)
+	if err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// ListRoles returns a list of roles based on the given payload.
+func (s *Store) ListRoles(ctx context.Context, payload driplimit.RoleListPayload) (*driplimit.RoleList, error) {
+	totalCount := 0
+	models := make([]*RoleModel, 0)
+
+	err := sqlx.SelectContext(ctx, s.ext(), &models, "SELECT * FROM v_roles ORDER BY name LIMIT $1 OFFSET $2", payload.List.Limit, payload.List.Offset())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+	err = sqlx.GetContext(ctx, s.ext(), &totalCount, "SELECT COUNT(*) FROM v_roles")
+	if err != nil {
+		return nil, fmt.Errorf("failed to count roles: %w", err)
+	}
+
+	rlist := &driplimit.RoleList{
+		List:  driplimit.NewListMetadata(payload.List, totalCount),
+		Roles: make([]*driplimit.Role, 0),
+	}
+	for _, model := range models {
+		role := model.ToRole()
+		role.KeyspacesPolicies, err = s.getPolicies(ctx, rolesPoliciesTable, role.RLID)
+		if err != nil {
+			return nil, err
+		}
+		rlist.Roles = append(rlist.Roles, role)
+	}
+	return rlist, nil
+}
+
+// UpdateRole partially updates a role based on the given payload. Keyspaces policies are
+// replaced when provided. Service keys holding the role pick up the changes at once as the
+// roles are resolved when the service key is retrieved.
+func (s *Store) UpdateRole(ctx context.Context, payload driplimit.RoleUpdatePayload) (role *driplimit.Role, err error) {
+	err = s.WithTx(ctx, func(tx *Store) error {
+		current, err := tx.getRole(ctx, payload.RLID)
+		if err != nil {
+			return err
+		}
+
+		if payload.Name != nil {
+			_, err = tx.ext().ExecContext(ctx, "UPDATE roles SET name = $1 WHERE rlid = $2", *payload.Name, current.RLID)
+			if err != nil {
+				return roleErr(err)
+			}
+		}
+
+		err = tx.replacePolicies(ctx, rolesPoliciesTable, current.RLID, payload.KeyspacesPolicies)
+		if err != nil {
+			return fmt.Errorf("failed to set role keyspaces policies: %w", err)
+		}
+
+		role, err = tx.getRole(ctx, current.RLID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// DeleteRole deletes a role and removes it from the service keys holding it.
+func (s *Store) DeleteRole(ctx context.Context, payload driplimit.RoleDeletePayload) error {
+	return s.WithTx(ctx, func(tx *Store) error {
+		res, err := tx.ext().ExecContext(ctx, "UPDATE roles SET deleted_at = $1 WHERE rlid = $2 AND deleted_at = 0", TimeNano{Time: time.Now()}, payload.RLID)
+		if err != nil {
+			return fmt.Errorf("failed to delete role: %w", err)
+		}
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rows == 0 {
+			return driplimit.ErrItemNotFound("role")
+		}
+
+		_, err = tx.ext().ExecContext(ctx, "DELETE FROM service_keys_roles WHERE rlid = $1", payload.RLID)
+		if err != nil {
+			return fmt.Errorf("failed to remove role from service keys: %w", err)
+		}
+		_, err = tx.ext().ExecContext(ctx, "DELETE FROM roles_policies WHERE rlid = $1", payload.RLID)
+		if err != nil {
+			return fmt.Errorf("failed to delete role keyspaces policies: %w", err)
+		}
+		return nil
+	})
+}
+
+// SetServiceKeyRoles replaces the roles of the service key skid. Nil roles are left untouched
+// while empty roles remove them all.
+func (s *Store) SetServiceKeyRoles(ctx context.Context, skid string, rlids []string) error {
+	if rlids == nil {
+		return nil
+	}
+	return s.WithTx(ctx, func(tx *Store) error {
+		_, err := tx.ext().ExecContext(ctx, "DELETE FROM service_keys_roles WHERE skid = $1", skid)
+		if err != nil {
+			return fmt.Errorf("failed to delete service key roles: %w", err)
+		}
+		for _, rlid := range rlids {
+			role, err := tx.getRole(ctx, rlid)
+			if err != nil {
+				return err
+			}
+			_, err = tx.ext().ExecContext(ctx, "INSERT OR IGNORE INTO service_keys_roles (skid, rlid) VALUES ($1, $2)", skid, role.RLID)
+			if err != nil {
+				return fmt.Errorf("failed to assign role to service key: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// GetServiceKeyRoles returns the roles of the service key skid along with their policies.
+func (s *Store) GetServiceKeyRoles(ctx context.Context, skid string) ([]*driplimit.Role, error) {
+	models := make([]*RoleModel, 0)
+	err := sqlx.SelectContext(ctx, s.ext(), &models, `
+		SELECT v_roles.* FROM v_roles
+		JOIN service_keys_roles ON service_keys_roles.rlid = v_roles.rlid
+		WHERE service_keys_roles.skid = $1
+		ORDER BY v_roles.name`, skid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service key roles: %w", err)
+	}
+
+	roles := make([]*driplimit.Role, 0, len(models))
+	for _, model := range models {
+		role := model.ToRole()
+		role.KeyspacesPolicies, err = s.getPolicies(ctx, rolesPoliciesTable, role.RLID)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
+// roleErr maps a unique name violation to ErrItemAlreadyExists.
+func roleErr(err error) error {
+	sqliteConstraintErr := new(sqlite3.Error)
+	if errors.As(err, sqliteConstraintErr) {
+		if sqliteConstraintErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return driplimit.ErrItemAlreadyExists("role")
+		}
+	}
+	return fmt.Errorf("failed to save role: %w", err)
+}
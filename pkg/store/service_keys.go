@@ -5,44 +5,98 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/i4n-co/driplimit"
 	"github.com/i4n-co/driplimit/pkg/generate"
+	"github.com/jmoiron/sqlx"
 	"github.com/mattn/go-sqlite3"
 )
 
 // ServiceKeyModel represents the database model for a service key.
 type ServiceKeyModel struct {
-	SKID        string   `db:"skid"`
-	TokenHash   string   `db:"token_hash"`
-	Admin       bool     `db:"admin"`
-	Description string   `db:"description"`
-	CreatedAt   TimeNano `db:"created_at"`
-	DeletedAt   TimeNano `db:"deleted_at"`
+	SKID                          string        `db:"skid"`
+	TokenHash                     string        `db:"token_hash"`
+	TokenCreatedAt                TimeNano      `db:"token_created_at"`
+	SecondaryTokenHash            string        `db:"secondary_token_hash"`
+	SecondaryTokenCreatedAt       TimeNano      `db:"secondary_token_created_at"`
+	SigningSecret                 string        `db:"signing_secret"`
+	ClientIdentity                string        `db:"client_identity"`
+	AllowedCIDRs                  Strings       `db:"allowed_cidrs"`
+	APIRateLimitLimit             int64         `db:"api_rate_limit_limit"`
+	APIRateLimitRefillRate        int64         `db:"api_rate_limit_refill_rate"`
+	APIRateLimitRefillInterval    time.Duration `db:"api_rate_limit_refill_interval"`
+	ChecksRateLimitLimit          int64         `db:"checks_rate_limit_limit"`
+	ChecksRateLimitRefillRate     int64         `db:"checks_rate_limit_refill_rate"`
+	ChecksRateLimitRefillInterval time.Duration `db:"checks_rate_limit_refill_interval"`
+	LastUsedAt                    TimeNano      `db:"last_used_at"`
+	LastUsedAddr                  string        `db:"last_used_addr"`
+	Admin                         bool          `db:"admin"`
+	Description                   string        `db:"description"`
+	ExpiresAt                     TimeNano      `db:"expires_at"`
+	CreatedAt                     TimeNano      `db:"created_at"`
+	DeletedAt                     TimeNano      `db:"deleted_at"`
 }
 
 // ServiceKey returns the service key from the model.
 func (r *ServiceKeyModel) ServiceKey() *driplimit.ServiceKey {
 	return &driplimit.ServiceKey{
-		SKID:        r.SKID,
-		Admin:       r.Admin,
-		Description: r.Description,
-		CreatedAt:   r.CreatedAt.Time,
+		SKID:                    r.SKID,
+		Admin:                   r.Admin,
+		Description:             r.Description,
+		ExpiresAt:               r.ExpiresAt.Time,
+		ClientIdentity:          r.ClientIdentity,
+		AllowedCIDRs:            r.AllowedCIDRs,
+		APIRatelimit:            serviceKeyRatelimit(r.APIRateLimitLimit, r.APIRateLimitRefillRate, r.APIRateLimitRefillInterval),
+		ChecksRatelimit:         serviceKeyRatelimit(r.ChecksRateLimitLimit, r.ChecksRateLimitRefillRate, r.ChecksRateLimitRefillInterval),
+		LastUsedAt:              r.LastUsedAt.Time,
+		LastUsedAddr:            r.LastUsedAddr,
+		TokenCreatedAt:          r.TokenCreatedAt.Time,
+		SecondaryTokenCreatedAt: r.SecondaryTokenCreatedAt.Time,
+		CreatedAt:               r.CreatedAt.Time,
 	}
 }
 
+// serviceKeyRatelimit returns the rate limit of the service key, or nil if it has none.
+func serviceKeyRatelimit(limit, refillRate int64, refillInterval time.Duration) *driplimit.Ratelimit {
+	if limit <= 0 {
+		return nil
+	}
+	return &driplimit.Ratelimit{
+		Limit:          limit,
+		RefillRate:     refillRate,
+		RefillInterval: driplimit.Milliseconds{Duration: refillInterval},
+	}
+}
+
+// setAPIRatelimit sets the rate limit of the service key calls to the API.
+func (r *ServiceKeyModel) setAPIRatelimit(ratelimit driplimit.RatelimitPayload) {
+	r.APIRateLimitLimit = ratelimit.Limit
+	r.APIRateLimitRefillRate = ratelimit.RefillRate
+	r.APIRateLimitRefillInterval = ratelimit.RefillInterval.Duration
+}
+
+// setChecksRatelimit sets the rate limit of the service key check calls.
+func (r *ServiceKeyModel) setChecksRatelimit(ratelimit driplimit.RatelimitPayload) {
+	r.ChecksRateLimitLimit = ratelimit.Limit
+	r.ChecksRateLimitRefillRate = ratelimit.RefillRate
+	r.ChecksRateLimitRefillInterval = ratelimit.RefillInterval.Duration
+}
+
 // NewServiceKey creates a new service key model from a service key.
 func NewServiceKeyModel(sk driplimit.ServiceKey) *ServiceKeyModel {
 	if sk.CreatedAt.IsZero() {
 		sk.CreatedAt = time.Now()
 	}
 	return &ServiceKeyModel{
-		SKID:      sk.SKID,
-		TokenHash: generate.Hash(sk.Token),
-		Admin:     sk.Admin,
-		CreatedAt: TimeNano{Time: sk.CreatedAt},
-		DeletedAt: TimeNano{Time: time.Time{}},
+		SKID:           sk.SKID,
+		TokenHash:      generate.Hash(sk.Token),
+		TokenCreatedAt: TimeNano{Time: sk.CreatedAt},
+		Admin:          sk.Admin,
+		ExpiresAt:      TimeNano{Time: sk.ExpiresAt},
+		CreatedAt:      TimeNano{Time: sk.CreatedAt},
+		DeletedAt:      TimeNano{Time: time.Time{}},
 	}
 }
 
@@ -55,44 +109,87 @@ func (s *Store) CreateServiceKey(ctx context.Context, payload driplimit.ServiceK
 		model.SKID = payload.SKID
 	}
 	model.TokenHash = generate.Hash(generatedToken)
+	model.SigningSecret = newSigningSecret()
 	model.Admin = payload.Admin
 	model.Description = payload.Description
+	model.ExpiresAt = TimeNano{Time: payload.ExpiresAt}
+	model.ClientIdentity = payload.ClientIdentity
+	model.AllowedCIDRs = payload.AllowedCIDRs
+	model.setAPIRatelimit(payload.APIRatelimit)
+	model.setChecksRatelimit(payload.ChecksRatelimit)
 	model.CreatedAt = TimeNano{Time: time.Now()}
-
-	_, err = s.db.NamedExecContext(ctx, `
-		INSERT INTO service_keys (
-			skid, 
-			token_hash,
-			admin,
-			description,
-			created_at
-		) VALUES (
-			:skid,
-			:token_hash,
-			:admin,
-			:description,
-			:created_at
-		)
-	`, model)
-	if err != nil {
-		// unique constraint violation
-		sqliteConstraintErr := new(sqlite3.Error)
-		if errors.As(err, sqliteConstraintErr) {
-			if sqliteConstraintErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey {
-				return nil, nil, driplimit.ErrItemAlreadyExists("service key")
+	model.TokenCreatedAt = model.CreatedAt
+
+	err = s.WithTx(ctx, func(tx *Store) error {
+		_, err := sqlx.NamedExecContext(ctx, tx.ext(), `
+			INSERT INTO service_keys (
+				skid, 
+				token_hash,
+				token_created_at,
+				signing_secret,
+				admin,
+				description,
+				expires_at,
+				client_identity,
+				allowed_cidrs,
+				api_rate_limit_limit,
+				api_rate_limit_refill_rate,
+				api_rate_limit_refill_interval,
+				checks_rate_limit_limit,
+				checks_rate_limit_refill_rate,
+				checks_rate_limit_refill_interval,
+				created_at
+			) VALUES (
+				:skid,
+				:token_hash,
+				:token_created_at,
+				:signing_secret,
+				:admin,
+				:description,
+				:expires_at,
+				:client_identity,
+				:allowed_cidrs,
+				:api_rate_limit_limit,
+				:api_rate_limit_refill_rate,
+				:api_rate_limit_refill_interval,
+				:checks_rate_limit_limit,
+				:checks_rate_limit_refill_rate,
+				:checks_rate_limit_refill_interval,
+				:created_at
+			)
+		`, model)
+		if err != nil {
+			// unique constraint violation
+			sqliteConstraintErr := new(sqlite3.Error)
+			if errors.As(err, sqliteConstraintErr) {
+				if sqliteConstraintErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey || sqliteConstraintErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+					return driplimit.ErrItemAlreadyExists("service key")
+				}
 			}
+			return fmt.Errorf("failed to insert service key: %w", err)
+		}
+
+		err = tx.SetKeyspacesPolicies(ctx, model.SKID, payload.KeyspacesPolicies)
+		if err != nil {
+			return fmt.Errorf("failed to set keyspaces policies: %w", err)
 		}
-		return nil, nil, fmt.Errorf("failed to insert service key: %w", err)
-	}
 
-	err = s.SetKeyspacesPolicies(ctx, model.SKID, payload.KeyspacesPolicies)
+		// an unknown role rolls back the whole creation
+		err = tx.SetServiceKeyRoles(ctx, model.SKID, payload.Roles)
+		if err != nil {
+			return fmt.Errorf("failed to set roles: %w", err)
+		}
+
+		sk = model.ServiceKey()
+		sk.SigningSecret = model.SigningSecret
+		sk.KeyspacesPolicies = payload.KeyspacesPolicies
+		sk.Roles, err = tx.GetServiceKeyRoles(ctx, model.SKID)
+		return err
+	})
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to set keyspaces policies: %w", err)
+		return nil, nil, err
 	}
 
-	sk = model.ServiceKey()
-	sk.KeyspacesPolicies = payload.KeyspacesPolicies
-
 	return sk, &generatedToken, nil
 }
 
@@ -109,12 +206,22 @@ func (s *Store) GetServiceKey(ctx context.Context, payload driplimit.ServiceKeyG
 		return nil, fmt.Errorf("failed to get service key keyspace policies: %w", err)
 	}
 
+	sk.Roles, err = s.GetServiceKeyRoles(ctx, sk.SKID)
+	if err != nil {
+		return nil, err
+	}
+
 	return sk, nil
 }
 
 func (s *Store) getServiceKeyBy(ctx context.Context, field string, value string) (*driplimit.ServiceKey, error) {
+	where := field + " = $1"
+	// both tokens authenticate the service key during a rotation
+	if field == "token_hash" {
+		where = "(token_hash = $1 OR secondary_token_hash = $1)"
+	}
 	model := new(ServiceKeyModel)
-	err := s.db.GetContext(ctx, model, fmt.Sprintf("SELECT * FROM v_service_keys WHERE %s = $1", field), value)
+	err := sqlx.GetContext(ctx, s.ext(), model, "SELECT * FROM v_service_keys WHERE "+where, value)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, driplimit.ErrItemNotFound("service key")
@@ -130,18 +237,39 @@ func (s *Store) ListServiceKeys(ctx context.Context, payload driplimit.ServiceKe
 	totalCount := 0
 	models := make([]*ServiceKeyModel, 0)
 
+	conditions := []string{}
+	args := []any{}
+	if payload.Expired != nil {
+		args = append(args, TimeNano{Time: time.Now()})
+		if *payload.Expired {
+			conditions = append(conditions, "(expires_at > 0 AND expires_at <= $1)")
+		} else {
+			conditions = append(conditions, "(expires_at = 0 OR expires_at > $1)")
+		}
+	}
+	if !payload.UnusedSince.IsZero() {
+		args = append(args, TimeNano{Time: payload.UnusedSince})
+		conditions = append(conditions, fmt.Sprintf("last_used_at < $%d", len(args)))
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
 	conn, err := s.db.Connx(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create connection: %w", err)
 	}
 	defer conn.Close()
 
-	err = conn.SelectContext(ctx, &models, "SELECT * FROM v_service_keys ORDER BY created_at LIMIT $1 OFFSET $2", payload.List.Limit, payload.List.Offset())
+	err = conn.SelectContext(ctx, &models,
+		fmt.Sprintf("SELECT * FROM v_service_keys %s ORDER BY created_at LIMIT $%d OFFSET $%d", where, len(args)+1, len(args)+2),
+		append(args, payload.List.Limit, payload.List.Offset())...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list service keys: %w", err)
 	}
 
-	err = conn.GetContext(ctx, &totalCount, "SELECT COUNT(*) FROM v_service_keys")
+	err = conn.GetContext(ctx, &totalCount, "SELECT COUNT(*) FROM v_service_keys "+where, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to count keys: %w", err)
 	}
@@ -184,18 +312,166 @@ func (s *Store) DeleteServiceKey(ctx context.Context, payload driplimit.ServiceK
 		return fmt.Errorf("failed to delete keyspaces policies: %w", err)
 	}
 
+	_, err = tx.ExecContext(ctx, "DELETE FROM service_keys_roles WHERE skid = $1", payload.SKID)
+	if err != nil {
+		return fmt.Errorf("failed to delete service key roles: %w", err)
+	}
+
 	return tx.Commit()
 }
 
+// UpdateServiceKey partially updates a service key based on the given payload. Keyspaces
+// policies and roles are replaced when provided.
+func (s *Store) UpdateServiceKey(ctx context.Context, payload driplimit.ServiceKeyUpdatePayload) (sk *driplimit.ServiceKey, err error) {
+	err = s.WithTx(ctx, func(tx *Store) error {
+		model := new(ServiceKeyModel)
+		err := sqlx.GetContext(ctx, tx.ext(), model, "SELECT * FROM v_service_keys WHERE skid = $1", payload.SKID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return driplimit.ErrItemNotFound("service key")
+			}
+			return fmt.Errorf("failed to get service key by skid: %w", err)
+		}
+
+		if payload.Description != nil {
+			model.Description = *payload.Description
+		}
+		if payload.Admin != nil {
+			model.Admin = *payload.Admin
+		}
+		if payload.ExpiresAt != nil {
+			model.ExpiresAt = TimeNano{Time: *payload.ExpiresAt}
+		}
+		if payload.ClientIdentity != nil {
+			model.ClientIdentity = *payload.ClientIdentity
+		}
+		if payload.AllowedCIDRs != nil {
+			model.AllowedCIDRs = payload.AllowedCIDRs
+		}
+		if payload.APIRatelimit != nil {
+			model.setAPIRatelimit(*payload.APIRatelimit)
+		}
+		if payload.ChecksRatelimit != nil {
+			model.setChecksRatelimit(*payload.ChecksRatelimit)
+		}
+		if payload.RotateSigningSecret {
+			model.SigningSecret = newSigningSecret()
+		}
+		_, err = sqlx.NamedExecContext(ctx, tx.ext(), `
+			UPDATE service_keys
+			SET
+				description = :description,
+				admin = :admin,
+				expires_at = :expires_at,
+				signing_secret = :signing_secret,
+				client_identity = :client_identity,
+				allowed_cidrs = :allowed_cidrs,
+				api_rate_limit_limit = :api_rate_limit_limit,
+				api_rate_limit_refill_rate = :api_rate_limit_refill_rate,
+				api_rate_limit_refill_interval = :api_rate_limit_refill_interval,
+				checks_rate_limit_limit = :checks_rate_limit_limit,
+				checks_rate_limit_refill_rate = :checks_rate_limit_refill_rate,
+				checks_rate_limit_refill_interval = :checks_rate_limit_refill_interval
+			WHERE skid = :skid
+		`, model)
+		if err != nil {
+			sqliteConstraintErr := new(sqlite3.Error)
+			if errors.As(err, sqliteConstraintErr) && sqliteConstraintErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+				return driplimit.ErrItemAlreadyExists("service key")
+			}
+			return fmt.Errorf("failed to update service key: %w", err)
+		}
+
+		err = tx.SetKeyspacesPolicies(ctx, model.SKID, payload.KeyspacesPolicies)
+		if err != nil {
+			return fmt.Errorf("failed to set keyspaces policies: %w", err)
+		}
+
+		err = tx.SetServiceKeyRoles(ctx, model.SKID, payload.Roles)
+		if err != nil {
+			return fmt.Errorf("failed to set roles: %w", err)
+		}
+
+		sk, err = tx.GetServiceKey(ctx, driplimit.ServiceKeyGetPayload{SKID: model.SKID})
+		if err != nil {
+			return err
+		}
+		// the signing secret is only disclosed when generated
+		if payload.RotateSigningSecret {
+			sk.SigningSecret = model.SigningSecret
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sk, nil
+}
+
+// GetServiceKeySigningSecret returns the request signing secret of the service key skid.
+func (s *Store) GetServiceKeySigningSecret(ctx context.Context, skid string) (string, error) {
+	secret := ""
+	err := sqlx.GetContext(ctx, s.ext(), &secret, "SELECT signing_secret FROM v_service_keys WHERE skid = $1", skid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", driplimit.ErrItemNotFound("service key")
+		}
+		return "", fmt.Errorf("failed to get service key signing secret: %w", err)
+	}
+	return secret, nil
+}
+
+// RecordServiceKeysUsage records the last use of the service keys. Usages older than the
+// recorded ones are ignored.
+func (s *Store) RecordServiceKeysUsage(ctx context.Context, usages []driplimit.ServiceKeyUsage) error {
+	return s.WithTx(ctx, func(tx *Store) error {
+		for _, usage := range usages {
+			_, err := tx.ext().ExecContext(ctx,
+				"UPDATE service_keys SET last_used_at = $1, last_used_addr = $2 WHERE skid = $3 AND last_used_at < $1",
+				TimeNano{Time: usage.LastUsedAt}, usage.Addr, usage.SKID)
+			if err != nil {
+				return fmt.Errorf("failed to record service key usage: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// GetServiceKeyByClientIdentity returns the service key matching the first of the client
+// certificate identities.
+func (s *Store) GetServiceKeyByClientIdentity(ctx context.Context, identities []string) (*driplimit.ServiceKey, error) {
+	for _, identity := range identities {
+		if identity == "" {
+			continue
+		}
+		skid := ""
+		err := sqlx.GetContext(ctx, s.ext(), &skid, "SELECT skid FROM v_service_keys WHERE client_identity = $1", identity)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get service key by client identity: %w", err)
+		}
+		return s.GetServiceKey(ctx, driplimit.ServiceKeyGetPayload{SKID: skid})
+	}
+	return nil, driplimit.ErrItemNotFound("service key")
+}
+
+// newSigningSecret generates a request signing secret.
+func newSigningSecret() string {
+	return "sks_" + generate.Token()
+}
+
 // SetServiceKeyToken sets a new service key token
 func (s *Store) SetServiceKeyToken(ctx context.Context, payload driplimit.ServiceKeySetTokenPayload) (err error) {
 	model := new(ServiceKeyModel)
 	model.SKID = payload.SKID
 	model.TokenHash = generate.Hash(payload.Token)
+	model.TokenCreatedAt = TimeNano{Time: time.Now()}
 
 	res, err := s.db.NamedExecContext(ctx, `
 		UPDATE service_keys
-		SET token_hash = :token_hash
+		SET token_hash = :token_hash, token_created_at = :token_created_at
 		WHERE skid = :skid
 	`, model)
 	if err != nil {
@@ -212,6 +488,102 @@ func (s *Store) SetServiceKeyToken(ctx context.Context, payload driplimit.Servic
 	return nil
 }
 
+// RotateServiceKeyToken generates the secondary token of the service key and returns the
+// service key with its secondary token. The service key must not have a secondary token yet.
+func (s *Store) RotateServiceKeyToken(ctx context.Context, skid string) (sk *driplimit.ServiceKey, err error) {
+	token := "sk_" + generate.Token()
+	err = s.WithTx(ctx, func(tx *Store) error {
+		model, err := tx.getServiceKeyModel(ctx, skid)
+		if err != nil {
+			return err
+		}
+		if model.SecondaryTokenHash != "" {
+			return driplimit.ErrItemAlreadyExists("secondary token")
+		}
+		_, err = tx.ext().ExecContext(ctx,
+			"UPDATE service_keys SET secondary_token_hash = $1, secondary_token_created_at = $2 WHERE skid = $3",
+			generate.Hash(token), TimeNano{Time: time.Now()}, skid)
+		if err != nil {
+			return fmt.Errorf("failed to set service key secondary token: %w", err)
+		}
+		sk, err = tx.GetServiceKey(ctx, driplimit.ServiceKeyGetPayload{SKID: skid})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	sk.SecondaryToken = token
+	return sk, nil
+}
+
+// PromoteServiceKeyToken swaps the token and the secondary token of the service key, so that
+// the former token remains valid until it is revoked.
+func (s *Store) PromoteServiceKeyToken(ctx context.Context, skid string) (sk *driplimit.ServiceKey, err error) {
+	err = s.WithTx(ctx, func(tx *Store) error {
+		model, err := tx.getServiceKeyModel(ctx, skid)
+		if err != nil {
+			return err
+		}
+		if model.SecondaryTokenHash == "" {
+			return driplimit.ErrItemNotFound("secondary token")
+		}
+		_, err = tx.ext().ExecContext(ctx, `
+			UPDATE service_keys
+			SET token_hash = secondary_token_hash,
+				token_created_at = secondary_token_created_at,
+				secondary_token_hash = token_hash,
+				secondary_token_created_at = token_created_at
+			WHERE skid = $1
+		`, skid)
+		if err != nil {
+			return fmt.Errorf("failed to promote service key secondary token: %w", err)
+		}
+		sk, err = tx.GetServiceKey(ctx, driplimit.ServiceKeyGetPayload{SKID: skid})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sk, nil
+}
+
+// RevokeServiceKeySecondaryToken revokes the secondary token of the service key.
+func (s *Store) RevokeServiceKeySecondaryToken(ctx context.Context, skid string) (sk *driplimit.ServiceKey, err error) {
+	err = s.WithTx(ctx, func(tx *Store) error {
+		model, err := tx.getServiceKeyModel(ctx, skid)
+		if err != nil {
+			return err
+		}
+		if model.SecondaryTokenHash == "" {
+			return driplimit.ErrItemNotFound("secondary token")
+		}
+		_, err = tx.ext().ExecContext(ctx,
+			"UPDATE service_keys SET secondary_token_hash = '', secondary_token_created_at = 0 WHERE skid = $1", skid)
+		if err != nil {
+			return fmt.Errorf("failed to revoke service key secondary token: %w", err)
+		}
+		sk, err = tx.GetServiceKey(ctx, driplimit.ServiceKeyGetPayload{SKID: skid})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sk, nil
+}
+
+// getServiceKeyModel returns the model of the service key.
+func (s *Store) getServiceKeyModel(ctx context.Context, skid string) (*ServiceKeyModel, error) {
+	model := new(ServiceKeyModel)
+	err := sqlx.GetContext(ctx, s.ext(), model, "SELECT * FROM v_service_keys WHERE skid = $1", skid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, driplimit.ErrItemNotFound("service key")
+		}
+		return nil, fmt.Errorf("failed to get service key by skid: %w", err)
+	}
+	return model, nil
+}
+
 func (s *Store) InitRootServiceKeyToken(ctx context.Context, token string) error {
 	_, _, err := s.CreateServiceKey(ctx, driplimit.ServiceKeyCreatePayload{
 		SKID:        "sk_root",
@@ -11,6 +11,7 @@ import (
 // Store is a store implementation using SQLite.
 type Store struct {
 	db        *sqlx.DB
+	tx        *sqlx.Tx
 	validator *validator.Validate
 }
 
@@ -31,6 +32,34 @@ func New(ctx context.Context, db *sqlx.DB) (*Store, error) {
 	return sqlite, nil
 }
 
+// WithTx calls fn with a store bound to a single transaction. The transaction
+// is committed if fn succeeds and rolled back otherwise. If the store is already
+// bound to a transaction, fn reuses it.
+func (s *Store) WithTx(ctx context.Context, fn func(tx *Store) error) error {
+	if s.tx != nil {
+		return fn(s)
+	}
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = fn(&Store{db: s.db, tx: tx, validator: s.validator})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ext returns the transaction the store is bound to, or the database otherwise.
+func (s *Store) ext() sqlx.ExtContext {
+	if s.tx != nil {
+		return s.tx
+	}
+	return s.db
+}
+
 // Close closes the SQLite store.
 func (s *Store) Close() error {
 	return s.db.Close()
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Strings is a list of strings that serializes to and from a JSON array.
+type Strings []string
+
+// Scan implements the sql.Scanner interface.
+func (s *Strings) Scan(v interface{}) error {
+	var raw []byte
+	switch v := v.(type) {
+	case nil:
+		*s = nil
+		return nil
+	case string:
+		raw = []byte(v)
+	case []byte:
+		raw = v
+	default:
+		return fmt.Errorf("expected string, got %T", v)
+	}
+	var list []string
+	err := json.Unmarshal(raw, &list)
+	if err != nil {
+		return fmt.Errorf("failed to decode strings: %w", err)
+	}
+	if len(list) == 0 {
+		list = nil
+	}
+	*s = list
+	return nil
+}
+
+// Value implements the driver.Valuer interface.
+func (s Strings) Value() (driver.Value, error) {
+	if len(s) == 0 {
+		return "[]", nil
+	}
+	raw, err := json.Marshal([]string(s))
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
@@ -0,0 +1,142 @@
+package driplimit
+
+import "github.com/go-playground/validator/v10"
+
+// Plan represents a reusable rate limit configuration within a keyspace.
+// Keys referencing a plan use its rate limit unless they configure their own.
+type Plan struct {
+	PLID      string     `json:"plid"`
+	KSID      string     `json:"ksid"`
+	Name      string     `json:"name"`
+	Ratelimit *Ratelimit `json:"ratelimit,omitempty"`
+}
+
+// ConfiguredRateLimit returns true if the rate limit is configured for the plan.
+func (p *Plan) ConfiguredRateLimit() bool {
+	return p.Ratelimit.Configured()
+}
+
+// PlanCreatePayload represents the payload for creating a plan.
+type PlanCreatePayload struct {
+	*payload
+
+	KSID      string           `json:"ksid" validate:"required" description:"The id of the keyspace to which the plan belongs to"`
+	Name      string           `json:"name" validate:"required" description:"The name of the plan, unique within the keyspace"`
+	Ratelimit RatelimitPayload `json:"ratelimit" validate:"required" description:"The rate limit configuration of the plan"`
+}
+
+// Validate validates the plan create payload.
+func (p *PlanCreatePayload) Validate(validator *validator.Validate) error {
+	if !p.Ratelimit.Configured() {
+		return ErrInvalidPayload
+	}
+	return validator.Struct(p)
+}
+
+// WithServiceToken adds authentication infos to payload
+func (p *PlanCreatePayload) WithServiceToken(token string) *PlanCreatePayload {
+	p.payload = &payload{
+		serviceToken: token,
+	}
+	return p
+}
+
+// PlanGetPayload represents the payload for getting a plan.
+type PlanGetPayload struct {
+	*payload
+
+	KSID string `json:"ksid" validate:"required" description:"The id of the keyspace to which the plan belongs to"`
+	PLID string `json:"plid" validate:"required" description:"The id of the plan"`
+}
+
+// Validate validates the plan get payload.
+func (p *PlanGetPayload) Validate(validator *validator.Validate) error {
+	return validator.Struct(p)
+}
+
+// WithServiceToken adds authentication infos to payload
+func (p *PlanGetPayload) WithServiceToken(token string) *PlanGetPayload {
+	p.payload = &payload{
+		serviceToken: token,
+	}
+	return p
+}
+
+// PlanList represents a list of plans.
+type PlanList struct {
+	List  ListMetadata `json:"list"`
+	Plans []*Plan      `json:"plans"`
+}
+
+// PlanListPayload represents the payload for listing the plans of a keyspace.
+type PlanListPayload struct {
+	*payload
+
+	KSID string      `json:"ksid" validate:"required" description:"The id of the keyspace"`
+	List ListPayload `json:"list" description:"The list options"`
+}
+
+// Validate validates the plan list payload.
+func (p *PlanListPayload) Validate(validator *validator.Validate) error {
+	err := p.List.Validate(validator)
+	if err != nil {
+		return err
+	}
+	return validator.Struct(p)
+}
+
+// WithServiceToken adds authentication infos to payload
+func (p *PlanListPayload) WithServiceToken(token string) *PlanListPayload {
+	p.payload = &payload{
+		serviceToken: token,
+	}
+	return p
+}
+
+// PlanUpdatePayload represents the payload for updating a plan.
+// Only the provided fields are updated.
+type PlanUpdatePayload struct {
+	*payload
+
+	KSID      string            `json:"ksid" validate:"required" description:"The id of the keyspace to which the plan belongs to"`
+	PLID      string            `json:"plid" validate:"required" description:"The id of the plan to update"`
+	Name      *string           `json:"name,omitempty" validate:"omitempty,gte=1" description:"The new name of the plan"`
+	Ratelimit *RatelimitPayload `json:"ratelimit,omitempty" description:"The new rate limit configuration of the plan, applied to all its keys"`
+}
+
+// Validate validates the plan update payload.
+func (p *PlanUpdatePayload) Validate(validator *validator.Validate) error {
+	if p.Ratelimit != nil && !p.Ratelimit.Configured() {
+		return ErrInvalidPayload
+	}
+	return validator.Struct(p)
+}
+
+// WithServiceToken adds authentication infos to payload
+func (p *PlanUpdatePayload) WithServiceToken(token string) *PlanUpdatePayload {
+	p.payload = &payload{
+		serviceToken: token,
+	}
+	return p
+}
+
+// PlanDeletePayload represents the payload for deleting a plan.
+type PlanDeletePayload struct {
+	*payload
+
+	KSID string `json:"ksid" validate:"required" description:"The id of the keyspace to which the plan belongs to"`
+	PLID string `json:"plid" validate:"required" description:"The id of the plan to delete (its keys fall back to the keyspace defaults)"`
+}
+
+// Validate validates the plan delete payload.
+func (p *PlanDeletePayload) Validate(validator *validator.Validate) error {
+	return validator.Struct(p)
+}
+
+// WithServiceToken adds authentication infos to payload
+func (p *PlanDeletePayload) WithServiceToken(token string) *PlanDeletePayload {
+	p.payload = &payload{
+		serviceToken: token,
+	}
+	return p
+}
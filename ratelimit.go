@@ -48,6 +48,37 @@ func (r *Ratelimit) Configured() bool {
 	return r.Limit > 0
 }
 
+// UpdateRemaining refills the remaining rate limit state based on the rate limit configuration
+// and the time elapsed since the last refill. It returns true if the remaining rate limit was
+// updated.
+func (r *Ratelimit) UpdateRemaining() (updated bool) {
+	if !r.Configured() {
+		return false
+	}
+	if r.State == nil {
+		r.State = &RatelimitState{}
+	}
+	if r.RefillInterval.Duration == 0 || r.RefillRate == 0 {
+		return false
+	}
+	defer func() {
+		if updated {
+			r.State.LastRefilled = now()
+		}
+	}()
+	sinceLastRefill := since(r.State.LastRefilled)
+	refills := sinceLastRefill.Nanoseconds() / r.RefillInterval.Nanoseconds()
+	refilled := refills * r.RefillRate
+	remaining := r.State.Remaining + refilled
+	updated = remaining != r.State.Remaining
+	if remaining > r.Limit {
+		r.State.Remaining = r.Limit
+		return updated
+	}
+	r.State.Remaining = remaining
+	return updated
+}
+
 // RatelimitPayload represents the payload for configuring a rate limit.
 type RatelimitPayload struct {
 	Limit          int64        `json:"limit" validate:"gte=0" description:"The rate limit"`
@@ -0,0 +1,134 @@
+package driplimit
+
+import (
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Role represents a named set of keyspaces policies that can be assigned to service keys.
+// The permissions of a service key are the union of its own policies and the policies of its roles.
+type Role struct {
+	RLID              string    `json:"rlid"`
+	Name              string    `json:"name"`
+	KeyspacesPolicies Policies  `json:"keyspaces_policies,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// RoleCreatePayload represents the payload for creating a role.
+type RoleCreatePayload struct {
+	*payload
+
+	Name              string   `json:"name" validate:"required" description:"The name of the role"`
+	KeyspacesPolicies Policies `json:"keyspaces_policies" description:"The keyspaces policies of the role. Map keys are the keyspace ids or patterns and the values are the policies for the keyspace"`
+}
+
+// Validate validates the role create payload.
+func (r *RoleCreatePayload) Validate(validator *validator.Validate) error {
+	err := r.KeyspacesPolicies.Validate()
+	if err != nil {
+		return err
+	}
+	return validator.Struct(r)
+}
+
+// WithServiceToken adds authentication infos to payload
+func (r *RoleCreatePayload) WithServiceToken(token string) *RoleCreatePayload {
+	r.payload = &payload{
+		serviceToken: token,
+	}
+	return r
+}
+
+// RoleGetPayload represents the payload for getting a role.
+type RoleGetPayload struct {
+	*payload
+
+	RLID string `json:"rlid" validate:"required" description:"The id of the role"`
+}
+
+// Validate validates the role get payload.
+func (r *RoleGetPayload) Validate(validator *validator.Validate) error {
+	return validator.Struct(r)
+}
+
+// WithServiceToken adds authentication infos to payload
+func (r *RoleGetPayload) WithServiceToken(token string) *RoleGetPayload {
+	r.payload = &payload{
+		serviceToken: token,
+	}
+	return r
+}
+
+// RoleList represents a list of roles.
+type RoleList struct {
+	List  ListMetadata `json:"list"`
+	Roles []*Role      `json:"roles"`
+}
+
+// RoleListPayload represents the payload for listing roles.
+type RoleListPayload struct {
+	*payload
+
+	List ListPayload `json:"list" description:"The list options"`
+}
+
+// Validate validates the role list payload.
+func (r *RoleListPayload) Validate(validator *validator.Validate) error {
+	return r.List.Validate(validator)
+}
+
+// WithServiceToken adds authentication infos to payload
+func (r *RoleListPayload) WithServiceToken(token string) *RoleListPayload {
+	r.payload = &payload{
+		serviceToken: token,
+	}
+	return r
+}
+
+// RoleUpdatePayload represents the payload for updating a role.
+// Only the provided fields are updated.
+type RoleUpdatePayload struct {
+	*payload
+
+	RLID              string   `json:"rlid" validate:"required" description:"The id of the role to update"`
+	Name              *string  `json:"name,omitempty" validate:"omitempty,gte=1" description:"The new name of the role"`
+	KeyspacesPolicies Policies `json:"keyspaces_policies" description:"The new keyspaces policies of the role, replacing the current ones (an empty map removes them all). The service keys holding the role are affected at once"`
+}
+
+// Validate validates the role update payload.
+func (r *RoleUpdatePayload) Validate(validator *validator.Validate) error {
+	err := r.KeyspacesPolicies.Validate()
+	if err != nil {
+		return err
+	}
+	return validator.Struct(r)
+}
+
+// WithServiceToken adds authentication infos to payload
+func (r *RoleUpdatePayload) WithServiceToken(token string) *RoleUpdatePayload {
+	r.payload = &payload{
+		serviceToken: token,
+	}
+	return r
+}
+
+// RoleDeletePayload represents the payload for deleting a role.
+type RoleDeletePayload struct {
+	*payload
+
+	RLID string `json:"rlid" validate:"required" description:"The id of the role to delete (it is removed from the service keys holding it)"`
+}
+
+// Validate validates the role delete payload.
+func (r *RoleDeletePayload) Validate(validator *validator.Validate) error {
+	return validator.Struct(r)
+}
+
+// WithServiceToken adds authentication infos to payload
+func (r *RoleDeletePayload) WithServiceToken(token string) *RoleDeletePayload {
+	r.payload = &payload{
+		serviceToken: token,
+	}
+	return r
+}
@@ -8,21 +8,49 @@ import (
 // Service is the main driplimit service interface.
 type Service interface {
 	KeyCheck(ctx context.Context, payload KeysCheckPayload) (key *Key, err error)
+	KeyCheckBatch(ctx context.Context, payload KeysCheckBatchPayload) (batch *KeysCheckBatch, err error)
 	KeyCreate(ctx context.Context, payload KeyCreatePayload) (key *Key, err error)
 	KeyGet(ctx context.Context, payload KeyGetPayload) (key *Key, err error)
 	KeyList(ctx context.Context, payload KeyListPayload) (klist *KeyList, err error)
 	KeyDelete(ctx context.Context, payload KeyDeletePayload) (err error)
+	KeyDeleteMany(ctx context.Context, payload KeyDeleteManyPayload) (result *KeyDeleteManyResult, err error)
+	KeyReset(ctx context.Context, payload KeyResetPayload) (key *Key, err error)
+	KeyMove(ctx context.Context, payload KeyMovePayload) (key *Key, err error)
 
 	KeyspaceGet(ctx context.Context, payload KeyspaceGetPayload) (keyspace *Keyspace, err error)
 	KeyspaceCreate(ctx context.Context, payload KeyspaceCreatePayload) (keyspace *Keyspace, err error)
 	KeyspaceList(ctx context.Context, payload KeyspaceListPayload) (kslist *KeyspaceList, err error)
+	KeyspaceUpdate(ctx context.Context, payload KeyspaceUpdatePayload) (keyspace *Keyspace, err error)
 	KeyspaceDelete(ctx context.Context, payload KeyspaceDeletePayload) (err error)
+	KeyspaceClone(ctx context.Context, payload KeyspaceClonePayload) (keyspace *Keyspace, err error)
+	KeyspaceStats(ctx context.Context, payload KeyspaceStatsPayload) (stats *KeyspaceStats, err error)
+	KeyspaceExport(ctx context.Context, payload KeyspaceExportPayload) (bundle *KeyspaceBundle, err error)
+	KeyspaceImport(ctx context.Context, payload KeyspaceImportPayload) (result *KeyspaceImportResult, err error)
+
+	PlanGet(ctx context.Context, payload PlanGetPayload) (plan *Plan, err error)
+	PlanCreate(ctx context.Context, payload PlanCreatePayload) (plan *Plan, err error)
+	PlanList(ctx context.Context, payload PlanListPayload) (plist *PlanList, err error)
+	PlanUpdate(ctx context.Context, payload PlanUpdatePayload) (plan *Plan, err error)
+	PlanDelete(ctx context.Context, payload PlanDeletePayload) (err error)
+
+	RoleGet(ctx context.Context, payload RoleGetPayload) (role *Role, err error)
+	RoleCreate(ctx context.Context, payload RoleCreatePayload) (role *Role, err error)
+	RoleList(ctx context.Context, payload RoleListPayload) (rlist *RoleList, err error)
+	RoleUpdate(ctx context.Context, payload RoleUpdatePayload) (role *Role, err error)
+	RoleDelete(ctx context.Context, payload RoleDeletePayload) (err error)
 
 	ServiceKeyGet(ctx context.Context, payload ServiceKeyGetPayload) (sk *ServiceKey, err error)
 	ServiceKeyCreate(ctx context.Context, payload ServiceKeyCreatePayload) (sk *ServiceKey, err error)
 	ServiceKeyList(ctx context.Context, payload ServiceKeyListPayload) (sklist *ServiceKeyList, err error)
 	ServiceKeyDelete(ctx context.Context, payload ServiceKeyDeletePayload) (err error)
 	ServiceKeySetToken(ctx context.Context, payload ServiceKeySetTokenPayload) (err error)
+	ServiceKeyRotate(ctx context.Context, payload ServiceKeyRotatePayload) (sk *ServiceKey, err error)
+	ServiceKeyPromote(ctx context.Context, payload ServiceKeyPromotePayload) (sk *ServiceKey, err error)
+	ServiceKeyRevokePrevious(ctx context.Context, payload ServiceKeyRevokePreviousPayload) (sk *ServiceKey, err error)
+	ServiceKeyUpdate(ctx context.Context, payload ServiceKeyUpdatePayload) (sk *ServiceKey, err error)
+	ServiceKeyCan(ctx context.Context, payload ServiceKeyCanPayload) (permission *Permission, err error)
+
+	AuditList(ctx context.Context, payload AuditListPayload) (alist *AuditList, err error)
 }
 
 var (
@@ -34,6 +62,9 @@ var (
 	ErrInvalidExpiration = errors.New("invalid expiration")
 	// ErrRateLimitExceeded is returned when the rate limit is exceeded.
 	ErrRateLimitExceeded = errors.New("rate limit exceeded")
+	// ErrServiceKeyRateLimitExceeded is returned when the service key exceeds its own rate
+	// limit of the driplimit API, as opposed to the rate limit of a checked key.
+	ErrServiceKeyRateLimitExceeded = errors.New("service key rate limit exceeded")
 	// ErrKeyExpired is returned when the key is expired.
 	ErrKeyExpired = errors.New("key expired")
 	// ErrUnauthorized is returned when the request is unauthorized.
@@ -46,14 +77,15 @@ var (
 
 // errHTTPCode is a map of HTTP status codes mapped to known errors.
 var errHTTPCode = map[error]int{
-	ErrInvalidPayload:     400,
-	ErrUnauthorized:       401,
-	ErrCannotDeleteItself: 403,
-	ErrNotFound:           404,
-	ErrAlreadyExists:      409,
-	ErrKeyExpired:         419,
-	ErrRateLimitExceeded:  429,
-	ErrInvalidExpiration:  460,
+	ErrInvalidPayload:              400,
+	ErrUnauthorized:                401,
+	ErrCannotDeleteItself:          403,
+	ErrNotFound:                    404,
+	ErrAlreadyExists:               409,
+	ErrKeyExpired:                  419,
+	ErrRateLimitExceeded:           429,
+	ErrInvalidExpiration:           460,
+	ErrServiceKeyRateLimitExceeded: 461,
 }
 
 // ErrItemNotFound is returned when the requested item is not found.
@@ -1,6 +1,8 @@
 package driplimit
 
 import (
+	"encoding/json"
+	"net"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -8,12 +10,75 @@ import (
 )
 
 type ServiceKey struct {
-	SKID              string    `json:"skid"`
-	Description       string    `json:"description"`
-	Admin             bool      `json:"admin"`
-	Token             string    `json:"token,omitempty"`
-	KeyspacesPolicies Policies  `json:"keyspaces_policies,omitempty"`
-	CreatedAt         time.Time `json:"created_at"`
+	SKID                    string     `json:"skid"`
+	Description             string     `json:"description"`
+	Admin                   bool       `json:"admin"`
+	Token                   string     `json:"token,omitempty"`
+	SecondaryToken          string     `json:"secondary_token,omitempty"`
+	SigningSecret           string     `json:"signing_secret,omitempty"`
+	KeyspacesPolicies       Policies   `json:"keyspaces_policies,omitempty"`
+	Roles                   []*Role    `json:"roles,omitempty"`
+	ClientIdentity          string     `json:"client_identity,omitempty"`
+	AllowedCIDRs            []string   `json:"allowed_cidrs,omitempty"`
+	APIRatelimit            *Ratelimit `json:"api_ratelimit,omitempty"`
+	ChecksRatelimit         *Ratelimit `json:"checks_ratelimit,omitempty"`
+	LastUsedAt              time.Time  `json:"last_used_at"`
+	LastUsedAddr            string     `json:"last_used_addr,omitempty"`
+	TokenCreatedAt          time.Time  `json:"token_created_at"`
+	SecondaryTokenCreatedAt time.Time  `json:"secondary_token_created_at"`
+	ExpiresAt               time.Time  `json:"expires_at"`
+	CreatedAt               time.Time  `json:"created_at"`
+}
+
+// MarshalJSON implements the json.Marshaler interface. Zero time fields (never used keys, keys
+// without secondary token or expiration) are omitted, see Key.MarshalJSON.
+func (sk ServiceKey) MarshalJSON() ([]byte, error) {
+	type ServiceKeyAlias ServiceKey
+	return json.Marshal(&struct {
+		ServiceKeyAlias
+		LastUsedAt              string `json:"last_used_at,omitempty"`
+		TokenCreatedAt          string `json:"token_created_at,omitempty"`
+		SecondaryTokenCreatedAt string `json:"secondary_token_created_at,omitempty"`
+		ExpiresAt               string `json:"expires_at,omitempty"`
+	}{
+		ServiceKeyAlias:         (ServiceKeyAlias)(sk),
+		LastUsedAt:              formatTime(sk.LastUsedAt),
+		TokenCreatedAt:          formatTime(sk.TokenCreatedAt),
+		SecondaryTokenCreatedAt: formatTime(sk.SecondaryTokenCreatedAt),
+		ExpiresAt:               formatTime(sk.ExpiresAt),
+	})
+}
+
+// formatTime returns the RFC3339 representation of t, empty if t is zero.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// Expired returns true if the service key has an expiration time in the past.
+// Expired service keys are rejected on every call.
+func (sk *ServiceKey) Expired() bool {
+	if sk.ExpiresAt.IsZero() {
+		return false
+	}
+	return !now().Before(sk.ExpiresAt)
+}
+
+// AllowsAddr returns true if the service key can be used from the address, i.e. it has no
+// allowed CIDRs or one of them contains the address.
+func (sk *ServiceKey) AllowsAddr(addr net.IP) bool {
+	if len(sk.AllowedCIDRs) == 0 {
+		return true
+	}
+	for _, cidr := range sk.AllowedCIDRs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err == nil && network.Contains(addr) {
+			return true
+		}
+	}
+	return false
 }
 
 type ServiceKeyList struct {
@@ -53,13 +118,26 @@ func (r *ServiceKeyGetPayload) By() (field, value string) {
 type ServiceKeyCreatePayload struct {
 	*payload
 
-	SKID              string   `json:"skid" description:"the id of the service key. Automatically generated if empty"`
-	Description       string   `json:"description" description:"The description of the service key"`
-	Admin             bool     `json:"admin" description:"The admin flag of the service key"`
-	KeyspacesPolicies Policies `json:"keyspaces_policies" description:"The keyspaces policies of the service key. Map keys are the keyspace ids and the values are the policies for the keyspace"`
+	SKID              string           `json:"skid" description:"the id of the service key. Automatically generated if empty"`
+	Description       string           `json:"description" description:"The description of the service key"`
+	Admin             bool             `json:"admin" description:"The admin flag of the service key"`
+	KeyspacesPolicies Policies         `json:"keyspaces_policies" description:"The keyspaces policies of the service key. Map keys are the keyspace ids and the values are the policies for the keyspace"`
+	Roles             []string         `json:"roles,omitempty" description:"The ids of the roles assigned to the service key"`
+	ExpiresAt         time.Time        `json:"expires_at" description:"The time at which the service key expires. The service key never expires if empty"`
+	ClientIdentity    string           `json:"client_identity,omitempty" description:"The subject common name or SAN of the TLS client certificates authenticating as the service key"`
+	AllowedCIDRs      []string         `json:"allowed_cidrs,omitempty" validate:"dive,cidr" description:"The networks the service key can be used from (e.g. 10.0.0.0/8). The service key can be used from anywhere if empty"`
+	APIRatelimit      RatelimitPayload `json:"api_ratelimit,omitempty" description:"The rate limit of the service key calls to the driplimit API (the server default applies if not configured)"`
+	ChecksRatelimit   RatelimitPayload `json:"checks_ratelimit,omitempty" description:"The rate limit of the service key keys.check and keys.check_batch calls (the server default applies if not configured)"`
 }
 
 func (r *ServiceKeyCreatePayload) Validate(validator *validator.Validate) error {
+	if !r.ExpiresAt.IsZero() && !r.ExpiresAt.After(now()) {
+		return ErrInvalidExpiration
+	}
+	err := r.KeyspacesPolicies.Validate()
+	if err != nil {
+		return err
+	}
 	return validator.Struct(r)
 }
 
@@ -90,9 +168,105 @@ func (k *ServiceKeySetTokenPayload) WithServiceToken(token string) *ServiceKeySe
 	return k
 }
 
+// ServiceKeyRotatePayload represents the payload for generating the secondary token of a
+// service key. Both tokens authenticate the service key until the rotation is finished.
+type ServiceKeyRotatePayload struct {
+	*payload
+
+	SKID string `json:"skid" validate:"required" description:"The id of the service key to rotate"`
+}
+
+func (r *ServiceKeyRotatePayload) Validate(validator *validator.Validate) error {
+	return validator.Struct(r)
+}
+
+// WithServiceToken adds authentication infos to payload
+func (k *ServiceKeyRotatePayload) WithServiceToken(token string) *ServiceKeyRotatePayload {
+	k.payload = &payload{
+		serviceToken: token,
+	}
+	return k
+}
+
+// ServiceKeyPromotePayload represents the payload for promoting the secondary token of a
+// service key. The former token becomes the secondary token until it is revoked.
+type ServiceKeyPromotePayload struct {
+	*payload
+
+	SKID string `json:"skid" validate:"required" description:"The id of the service key whose secondary token is promoted"`
+}
+
+func (r *ServiceKeyPromotePayload) Validate(validator *validator.Validate) error {
+	return validator.Struct(r)
+}
+
+// WithServiceToken adds authentication infos to payload
+func (k *ServiceKeyPromotePayload) WithServiceToken(token string) *ServiceKeyPromotePayload {
+	k.payload = &payload{
+		serviceToken: token,
+	}
+	return k
+}
+
+// ServiceKeyRevokePreviousPayload represents the payload for revoking the secondary token of
+// a service key, either the former token after a promotion or a rotation not promoted yet.
+type ServiceKeyRevokePreviousPayload struct {
+	*payload
+
+	SKID string `json:"skid" validate:"required" description:"The id of the service key whose secondary token is revoked"`
+}
+
+func (r *ServiceKeyRevokePreviousPayload) Validate(validator *validator.Validate) error {
+	return validator.Struct(r)
+}
+
+// WithServiceToken adds authentication infos to payload
+func (k *ServiceKeyRevokePreviousPayload) WithServiceToken(token string) *ServiceKeyRevokePreviousPayload {
+	k.payload = &payload{
+		serviceToken: token,
+	}
+	return k
+}
+
+// ServiceKeyUpdatePayload represents the payload for updating a service key.
+// Only the provided fields are updated.
+type ServiceKeyUpdatePayload struct {
+	*payload
+
+	SKID                string            `json:"skid" validate:"required" description:"The id of the service key to update"`
+	Description         *string           `json:"description,omitempty" description:"The new description of the service key"`
+	Admin               *bool             `json:"admin,omitempty" description:"The new admin flag of the service key"`
+	KeyspacesPolicies   Policies          `json:"keyspaces_policies" description:"The new keyspaces policies of the service key, replacing the current ones (an empty map removes them all)"`
+	Roles               []string          `json:"roles" description:"The ids of the roles assigned to the service key, replacing the current ones (an empty list removes them all)"`
+	ExpiresAt           *time.Time        `json:"expires_at,omitempty" description:"The new expiration time of the service key (a zero time removes the expiration)"`
+	ClientIdentity      *string           `json:"client_identity,omitempty" description:"The new subject common name or SAN of the TLS client certificates authenticating as the service key (an empty string removes it)"`
+	AllowedCIDRs        []string          `json:"allowed_cidrs" validate:"dive,cidr" description:"The networks the service key can be used from, replacing the current ones (an empty list removes them all)"`
+	APIRatelimit        *RatelimitPayload `json:"api_ratelimit,omitempty" description:"The new rate limit of the service key calls to the driplimit API (a zero limit restores the server default)"`
+	ChecksRatelimit     *RatelimitPayload `json:"checks_ratelimit,omitempty" description:"The new rate limit of the service key keys.check and keys.check_batch calls (a zero limit restores the server default)"`
+	RotateSigningSecret bool              `json:"rotate_signing_secret,omitempty" description:"Generate a new request signing secret, returned once in the response. The current secret is revoked at once"`
+}
+
+func (r *ServiceKeyUpdatePayload) Validate(validator *validator.Validate) error {
+	err := r.KeyspacesPolicies.Validate()
+	if err != nil {
+		return err
+	}
+	return validator.Struct(r)
+}
+
+// WithServiceToken adds authentication infos to payload
+func (k *ServiceKeyUpdatePayload) WithServiceToken(token string) *ServiceKeyUpdatePayload {
+	k.payload = &payload{
+		serviceToken: token,
+	}
+	return k
+}
+
 type ServiceKeyListPayload struct {
 	*payload
-	List ListPayload `json:"list" description:"The list options"`
+	List        ListPayload `json:"list" description:"The list options"`
+	Expired     *bool       `json:"expired,omitempty" description:"Only list expired service keys when true, or only unexpired ones when false. All service keys are listed if empty"`
+	UnusedSince time.Time   `json:"unused_since" description:"Only list the service keys not used since this time, including the never used ones"`
 }
 
 func (r *ServiceKeyListPayload) Validate(validator *validator.Validate) error {
@@ -123,3 +297,33 @@ func (k *ServiceKeyDeletePayload) WithServiceToken(token string) *ServiceKeyDele
 	}
 	return k
 }
+
+// ServiceKeyCanPayload represents the payload for simulating an action of a service key on a keyspace.
+type ServiceKeyCanPayload struct {
+	*payload
+	SKID   string       `json:"skid" validate:"required" description:"The id of the service key performing the action"`
+	Action PolicyAction `json:"action" validate:"required,oneof=check read create update delete manage_policies" description:"The action performed (check, read, create, update, delete or manage_policies)"`
+	KSID   string       `json:"ksid" validate:"required" description:"The id of the keyspace the action is performed on"`
+}
+
+func (r *ServiceKeyCanPayload) Validate(validator *validator.Validate) error {
+	return validator.Struct(r)
+}
+
+// WithServiceToken adds authentication infos to payload
+func (k *ServiceKeyCanPayload) WithServiceToken(token string) *ServiceKeyCanPayload {
+	k.payload = &payload{
+		serviceToken: token,
+	}
+	return k
+}
+
+// Permission explains whether a service key is allowed to perform an action on a keyspace.
+// KeyspaceFound tells apart a missing keyspace from a missing policy.
+type Permission struct {
+	SKID          string       `json:"skid"`
+	Action        PolicyAction `json:"action"`
+	KSID          string       `json:"ksid"`
+	KeyspaceFound bool         `json:"keyspace_found"`
+	PolicyDecision
+}
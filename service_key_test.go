@@ -0,0 +1,32 @@
+package driplimit_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/i4n-co/driplimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceKeyMarshalJSON(t *testing.T) {
+	// zero times are omitted
+	data, err := json.Marshal(driplimit.ServiceKey{SKID: "sk_abc", CreatedAt: time.Now()})
+	assert.NoError(t, err)
+	fields := make(map[string]any)
+	assert.NoError(t, json.Unmarshal(data, &fields))
+	assert.Equal(t, "sk_abc", fields["skid"])
+	assert.Contains(t, fields, "created_at")
+	for _, field := range []string{"last_used_at", "token_created_at", "secondary_token_created_at", "expires_at"} {
+		assert.NotContains(t, fields, field)
+	}
+
+	// other times are kept
+	lastUsedAt := time.Date(2024, 1, 1, 1, 1, 1, 1, time.UTC)
+	data, err = json.Marshal(&driplimit.ServiceKey{SKID: "sk_abc", LastUsedAt: lastUsedAt})
+	assert.NoError(t, err)
+	sk := new(driplimit.ServiceKey)
+	assert.NoError(t, json.Unmarshal(data, sk))
+	assert.True(t, lastUsedAt.Equal(sk.LastUsedAt))
+	assert.True(t, sk.ExpiresAt.IsZero())
+}
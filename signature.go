@@ -0,0 +1,139 @@
+package driplimit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureScheme is the authorization scheme of HMAC signed requests.
+//
+//	Authorization: DRIPLIMIT-HMAC-SHA256 skid=sk_xyz,timestamp=1700000000,nonce=abc,signature=...
+//
+// The signature is the hex encoded HMAC-SHA256, keyed by the service key signing secret, of
+// the request method, path, timestamp, nonce and hex encoded SHA-256 body hash, each
+// followed by a new line.
+const SignatureScheme = "DRIPLIMIT-HMAC-SHA256"
+
+// SignatureMaxSkew is the maximum difference between a signature timestamp and the server
+// time. Older signatures are rejected, and nonces only need to be remembered that long.
+const SignatureMaxSkew = 5 * time.Minute
+
+// RequestSignature is the HMAC signature of a request made on behalf of a service key.
+type RequestSignature struct {
+	SKID      string
+	Method    string
+	Path      string
+	BodyHash  string
+	Timestamp time.Time
+	Nonce     string
+	Signature string
+}
+
+// NewRequestSignature returns the unsigned signature of the request.
+func NewRequestSignature(skid, method, path string, body []byte, timestamp time.Time, nonce string) *RequestSignature {
+	bodyHash := sha256.Sum256(body)
+	return &RequestSignature{
+		SKID:      skid,
+		Method:    strings.ToUpper(method),
+		Path:      path,
+		BodyHash:  hex.EncodeToString(bodyHash[:]),
+		Timestamp: time.Unix(timestamp.Unix(), 0),
+		Nonce:     nonce,
+	}
+}
+
+// stringToSign returns the canonical string covered by the signature.
+func (s *RequestSignature) stringToSign() string {
+	return s.Method + "\n" + s.Path + "\n" + strconv.FormatInt(s.Timestamp.Unix(), 10) + "\n" + s.Nonce + "\n" + s.BodyHash + "\n"
+}
+
+func (s *RequestSignature) compute(secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(s.stringToSign()))
+	return mac.Sum(nil)
+}
+
+// Sign signs the request with the signing secret.
+func (s *RequestSignature) Sign(secret string) *RequestSignature {
+	s.Signature = hex.EncodeToString(s.compute(secret))
+	return s
+}
+
+// Stale returns true if the signature timestamp is outside of the allowed window.
+func (s *RequestSignature) Stale() bool {
+	skew := now().Sub(s.Timestamp)
+	return skew > SignatureMaxSkew || skew < -SignatureMaxSkew
+}
+
+// Verify checks that the request was signed with the signing secret within the allowed window.
+func (s *RequestSignature) Verify(secret string) error {
+	if secret == "" || s.Stale() {
+		return ErrUnauthorized
+	}
+	signature, err := hex.DecodeString(s.Signature)
+	if err != nil || !hmac.Equal(signature, s.compute(secret)) {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+// Header returns the Authorization header value of the signed request.
+func (s *RequestSignature) Header() string {
+	return fmt.Sprintf("%s skid=%s,timestamp=%d,nonce=%s,signature=%s", SignatureScheme, s.SKID, s.Timestamp.Unix(), s.Nonce, s.Signature)
+}
+
+// Credential returns the signature along with the signed request attributes. It is used as
+// the service token of the payloads once the request has been authenticated, and resolved
+// to its service key by the authoritative service.
+func (s *RequestSignature) Credential() string {
+	return fmt.Sprintf("%s,method=%s,path=%s,body_sha256=%s", s.Header(), s.Method, s.Path, s.BodyHash)
+}
+
+// IsSignatureCredential returns true if the service token is a request signature credential.
+func IsSignatureCredential(token string) bool {
+	return strings.HasPrefix(token, SignatureScheme+" ")
+}
+
+// ParseRequestSignature parses an Authorization header value or a credential. The skid,
+// timestamp, nonce and signature attributes are required.
+func ParseRequestSignature(s string) (*RequestSignature, error) {
+	if !IsSignatureCredential(s) {
+		return nil, ErrUnauthorized
+	}
+	signature := new(RequestSignature)
+	for _, attribute := range strings.Split(strings.TrimPrefix(s, SignatureScheme+" "), ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(attribute), "=")
+		if !found {
+			return nil, ErrUnauthorized
+		}
+		switch name {
+		case "skid":
+			signature.SKID = value
+		case "timestamp":
+			timestamp, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, ErrUnauthorized
+			}
+			signature.Timestamp = time.Unix(timestamp, 0)
+		case "nonce":
+			signature.Nonce = value
+		case "signature":
+			signature.Signature = value
+		case "method":
+			signature.Method = value
+		case "path":
+			signature.Path = value
+		case "body_sha256":
+			signature.BodyHash = value
+		}
+	}
+	if signature.SKID == "" || signature.Timestamp.IsZero() || signature.Nonce == "" || signature.Signature == "" {
+		return nil, ErrUnauthorized
+	}
+	return signature, nil
+}
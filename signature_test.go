@@ -0,0 +1,48 @@
+package driplimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestSignature(t *testing.T) {
+	now = func() time.Time {
+		return time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC)
+	}
+
+	body := []byte(`{"ksid":"ks_abc"}`)
+	signature := NewRequestSignature("sk_abc", "post", "/v1/keyspaces.get", body, now(), "n0nc3").Sign("s3cr3t")
+	assert.NoError(t, signature.Verify("s3cr3t"))
+	assert.ErrorIs(t, signature.Verify("wrong"), ErrUnauthorized)
+	assert.ErrorIs(t, signature.Verify(""), ErrUnauthorized)
+
+	// the header only carries the signature, the credential also carries the signed request
+	parsed, err := ParseRequestSignature(signature.Header())
+	assert.NoError(t, err)
+	assert.Equal(t, "sk_abc", parsed.SKID)
+	assert.Empty(t, parsed.Path)
+	parsed, err = ParseRequestSignature(signature.Credential())
+	assert.NoError(t, err)
+	assert.Equal(t, signature, parsed)
+	assert.True(t, IsSignatureCredential(signature.Credential()))
+
+	// any change to the request invalidates the signature
+	tampered := NewRequestSignature("sk_abc", "POST", "/v1/keyspaces.delete", body, signature.Timestamp, "n0nc3")
+	tampered.Signature = signature.Signature
+	assert.ErrorIs(t, tampered.Verify("s3cr3t"), ErrUnauthorized)
+	tampered = NewRequestSignature("sk_abc", "POST", "/v1/keyspaces.get", []byte(`{"ksid":"ks_xyz"}`), signature.Timestamp, "n0nc3")
+	tampered.Signature = signature.Signature
+	assert.ErrorIs(t, tampered.Verify("s3cr3t"), ErrUnauthorized)
+
+	// signatures out of the window are rejected
+	stale := NewRequestSignature("sk_abc", "POST", "/v1/keyspaces.get", body, now().Add(-2*SignatureMaxSkew), "n0nc3").Sign("s3cr3t")
+	assert.True(t, stale.Stale())
+	assert.ErrorIs(t, stale.Verify("s3cr3t"), ErrUnauthorized)
+
+	_, err = ParseRequestSignature(SignatureScheme + " skid=sk_abc,timestamp=abc,nonce=n,signature=s")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	_, err = ParseRequestSignature("Bearer t0k3n")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
@@ -0,0 +1,115 @@
+package driplimit
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type contextKey string
+
+// RemoteAddrKey is the context key of the address of the caller, set by the API server.
+const RemoteAddrKey contextKey = "remote_addr"
+
+// ServiceKeyUsage is the last use of a service key.
+type ServiceKeyUsage struct {
+	SKID       string
+	LastUsedAt time.Time
+	Addr       string
+}
+
+// UsageRecorder stores the last use of the service keys.
+type UsageRecorder interface {
+	RecordServiceKeysUsage(ctx context.Context, usages []ServiceKeyUsage) error
+}
+
+// UsageTracker keeps the last use of the authenticated service keys in memory and records
+// them in batch every interval, so that authenticating a call never writes.
+type UsageTracker struct {
+	mu       sync.Mutex
+	pending  map[string]ServiceKeyUsage
+	recorder UsageRecorder
+	logger   *slog.Logger
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewUsageTracker returns a usage tracker recording to recorder every interval until ctx is
+// done or the tracker is closed. Close must be called to record the last pending usages.
+func NewUsageTracker(ctx context.Context, recorder UsageRecorder, interval time.Duration, logger *slog.Logger) *UsageTracker {
+	tracker := &UsageTracker{
+		pending:  make(map[string]ServiceKeyUsage),
+		recorder: recorder,
+		logger:   logger,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go tracker.run(ctx, interval)
+	return tracker
+}
+
+// Close stops the periodic recordings and records the pending usages, waiting for them to be
+// recorded. It is meant to be called once the API server is shut down so that the usages
+// tracked meanwhile are recorded as well.
+func (t *UsageTracker) Close(ctx context.Context) error {
+	t.stopOnce.Do(func() { close(t.stop) })
+	<-t.done
+	return t.Flush(ctx)
+}
+
+// Track marks the service key as used now, from the remote address of the context if any.
+func (t *UsageTracker) Track(ctx context.Context, skid string) {
+	addr, _ := ctx.Value(RemoteAddrKey).(string)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending[skid] = ServiceKeyUsage{SKID: skid, LastUsedAt: now(), Addr: addr}
+}
+
+// Flush records the pending usages. Usages failing to be recorded are kept for the next flush.
+func (t *UsageTracker) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	pending := t.pending
+	t.pending = make(map[string]ServiceKeyUsage)
+	t.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	usages := make([]ServiceKeyUsage, 0, len(pending))
+	for _, usage := range pending {
+		usages = append(usages, usage)
+	}
+	err := t.recorder.RecordServiceKeysUsage(ctx, usages)
+	if err != nil {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		for skid, usage := range pending {
+			// usages tracked meanwhile are more recent
+			if _, found := t.pending[skid]; !found {
+				t.pending[skid] = usage
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+func (t *UsageTracker) run(ctx context.Context, interval time.Duration) {
+	defer close(t.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := t.Flush(ctx); err != nil {
+				t.logger.Error("failed to record service keys usage", "err", err)
+			}
+		case <-ctx.Done():
+			return
+		case <-t.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package driplimit_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/i4n-co/driplimit"
+	"github.com/stretchr/testify/assert"
+)
+
+// usageRecorder records the usages in memory.
+type usageRecorder struct {
+	usages []driplimit.ServiceKeyUsage
+}
+
+func (r *usageRecorder) RecordServiceKeysUsage(ctx context.Context, usages []driplimit.ServiceKeyUsage) error {
+	r.usages = append(r.usages, usages...)
+	return nil
+}
+
+func TestUsageTrackerClose(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	recorder := new(usageRecorder)
+	tracker := driplimit.NewUsageTracker(ctx, recorder, time.Hour, slog.Default())
+
+	// usages tracked after ctx is done, e.g. during the API server shutdown, are recorded on close
+	tracker.Track(context.WithValue(context.Background(), driplimit.RemoteAddrKey, "10.1.2.3"), "sk_abc")
+	cancel()
+	tracker.Track(context.Background(), "sk_xyz")
+	assert.Empty(t, recorder.usages)
+	assert.NoError(t, tracker.Close(context.Background()))
+	assert.Len(t, recorder.usages, 2)
+	for _, usage := range recorder.usages {
+		if usage.SKID == "sk_abc" {
+			assert.Equal(t, "10.1.2.3", usage.Addr)
+		}
+	}
+}
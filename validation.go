@@ -2,6 +2,7 @@ package driplimit
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"strings"
 
@@ -40,6 +41,20 @@ func (v *Validator) KeyCheck(ctx context.Context, payload KeysCheckPayload) (key
 	return v.driplimit.KeyCheck(ctx, payload)
 }
 
+// KeyCheckBatch validates the payload and each check individually, then calls the KeyCheckBatch
+// method of the wrapped Driplimit service with the valid checks.
+func (v *Validator) KeyCheckBatch(ctx context.Context, payload KeysCheckBatchPayload) (batch *KeysCheckBatch, err error) {
+	if err := payload.Validate(v.validator); err != nil {
+		return nil, err
+	}
+	return checkBatchSubset(ctx, payload, func(check KeysCheckPayload) error {
+		if err := check.Validate(v.validator); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidPayload, err)
+		}
+		return nil
+	}, v.driplimit.KeyCheckBatch)
+}
+
 // KeyCreate validates the payload and calls the KeyCreate method of the wrapped Driplimit service.
 func (v *Validator) KeyCreate(ctx context.Context, payload KeyCreatePayload) (key *Key, err error) {
 	if err := payload.Validate(v.validator); err != nil {
@@ -72,6 +87,30 @@ func (v *Validator) KeyDelete(ctx context.Context, payload KeyDeletePayload) (er
 	return v.driplimit.KeyDelete(ctx, payload)
 }
 
+// KeyDeleteMany validates the payload and calls the KeyDeleteMany method of the wrapped Driplimit service.
+func (v *Validator) KeyDeleteMany(ctx context.Context, payload KeyDeleteManyPayload) (result *KeyDeleteManyResult, err error) {
+	if err := payload.Validate(v.validator); err != nil {
+		return nil, err
+	}
+	return v.driplimit.KeyDeleteMany(ctx, payload)
+}
+
+// KeyReset validates the payload and calls the KeyReset method of the wrapped Driplimit service.
+func (v *Validator) KeyReset(ctx context.Context, payload KeyResetPayload) (key *Key, err error) {
+	if err := payload.Validate(v.validator); err != nil {
+		return nil, err
+	}
+	return v.driplimit.KeyReset(ctx, payload)
+}
+
+// KeyMove validates the payload and calls the KeyMove method of the wrapped Driplimit service.
+func (v *Validator) KeyMove(ctx context.Context, payload KeyMovePayload) (key *Key, err error) {
+	if err := payload.Validate(v.validator); err != nil {
+		return nil, err
+	}
+	return v.driplimit.KeyMove(ctx, payload)
+}
+
 // KeyspaceGet validates the payload and calls the KeyspaceGet method of the wrapped Driplimit service.
 func (v *Validator) KeyspaceGet(ctx context.Context, payload KeyspaceGetPayload) (keyspace *Keyspace, err error) {
 	if err := payload.Validate(v.validator); err != nil {
@@ -95,6 +134,13 @@ func (v *Validator) KeyspaceList(ctx context.Context, payload KeyspaceListPayloa
 	return v.driplimit.KeyspaceList(ctx, payload)
 }
 
+func (v *Validator) KeyspaceUpdate(ctx context.Context, payload KeyspaceUpdatePayload) (keyspace *Keyspace, err error) {
+	if err := payload.Validate(v.validator); err != nil {
+		return nil, err
+	}
+	return v.driplimit.KeyspaceUpdate(ctx, payload)
+}
+
 func (v *Validator) KeyspaceDelete(ctx context.Context, payload KeyspaceDeletePayload) (err error) {
 	if err := payload.Validate(v.validator); err != nil {
 		return err
@@ -102,6 +148,104 @@ func (v *Validator) KeyspaceDelete(ctx context.Context, payload KeyspaceDeletePa
 	return v.driplimit.KeyspaceDelete(ctx, payload)
 }
 
+func (v *Validator) KeyspaceClone(ctx context.Context, payload KeyspaceClonePayload) (keyspace *Keyspace, err error) {
+	if err := payload.Validate(v.validator); err != nil {
+		return nil, err
+	}
+	return v.driplimit.KeyspaceClone(ctx, payload)
+}
+
+func (v *Validator) KeyspaceStats(ctx context.Context, payload KeyspaceStatsPayload) (stats *KeyspaceStats, err error) {
+	if err := payload.Validate(v.validator); err != nil {
+		return nil, err
+	}
+	return v.driplimit.KeyspaceStats(ctx, payload)
+}
+
+func (v *Validator) KeyspaceExport(ctx context.Context, payload KeyspaceExportPayload) (bundle *KeyspaceBundle, err error) {
+	if err := payload.Validate(v.validator); err != nil {
+		return nil, err
+	}
+	return v.driplimit.KeyspaceExport(ctx, payload)
+}
+
+func (v *Validator) KeyspaceImport(ctx context.Context, payload KeyspaceImportPayload) (result *KeyspaceImportResult, err error) {
+	if err := payload.Validate(v.validator); err != nil {
+		return nil, err
+	}
+	return v.driplimit.KeyspaceImport(ctx, payload)
+}
+
+func (v *Validator) PlanGet(ctx context.Context, payload PlanGetPayload) (plan *Plan, err error) {
+	if err := payload.Validate(v.validator); err != nil {
+		return nil, err
+	}
+	return v.driplimit.PlanGet(ctx, payload)
+}
+
+func (v *Validator) PlanCreate(ctx context.Context, payload PlanCreatePayload) (plan *Plan, err error) {
+	if err := payload.Validate(v.validator); err != nil {
+		return nil, err
+	}
+	return v.driplimit.PlanCreate(ctx, payload)
+}
+
+func (v *Validator) PlanList(ctx context.Context, payload PlanListPayload) (plist *PlanList, err error) {
+	if err := payload.Validate(v.validator); err != nil {
+		return nil, err
+	}
+	return v.driplimit.PlanList(ctx, payload)
+}
+
+func (v *Validator) PlanUpdate(ctx context.Context, payload PlanUpdatePayload) (plan *Plan, err error) {
+	if err := payload.Validate(v.validator); err != nil {
+		return nil, err
+	}
+	return v.driplimit.PlanUpdate(ctx, payload)
+}
+
+func (v *Validator) PlanDelete(ctx context.Context, payload PlanDeletePayload) (err error) {
+	if err := payload.Validate(v.validator); err != nil {
+		return err
+	}
+	return v.driplimit.PlanDelete(ctx, payload)
+}
+
+func (v *Validator) RoleGet(ctx context.Context, payload RoleGetPayload) (role *Role, err error) {
+	if err := payload.Validate(v.validator); err != nil {
+		return nil, err
+	}
+	return v.driplimit.RoleGet(ctx, payload)
+}
+
+func (v *Validator) RoleCreate(ctx context.Context, payload RoleCreatePayload) (role *Role, err error) {
+	if err := payload.Validate(v.validator); err != nil {
+		return nil, err
+	}
+	return v.driplimit.RoleCreate(ctx, payload)
+}
+
+func (v *Validator) RoleList(ctx context.Context, payload RoleListPayload) (rlist *RoleList, err error) {
+	if err := payload.Validate(v.validator); err != nil {
+		return nil, err
+	}
+	return v.driplimit.RoleList(ctx, payload)
+}
+
+func (v *Validator) RoleUpdate(ctx context.Context, payload RoleUpdatePayload) (role *Role, err error) {
+	if err := payload.Validate(v.validator); err != nil {
+		return nil, err
+	}
+	return v.driplimit.RoleUpdate(ctx, payload)
+}
+
+func (v *Validator) RoleDelete(ctx context.Context, payload RoleDeletePayload) (err error) {
+	if err := payload.Validate(v.validator); err != nil {
+		return err
+	}
+	return v.driplimit.RoleDelete(ctx, payload)
+}
+
 func (v *Validator) ServiceKeyGet(ctx context.Context, payload ServiceKeyGetPayload) (sk *ServiceKey, err error) {
 	if err := payload.Validate(v.validator); err != nil {
 		return nil, err
@@ -136,3 +280,45 @@ func (v *Validator) ServiceKeySetToken(ctx context.Context, payload ServiceKeySe
 	}
 	return v.driplimit.ServiceKeySetToken(ctx, payload)
 }
+
+func (v *Validator) ServiceKeyRotate(ctx context.Context, payload ServiceKeyRotatePayload) (sk *ServiceKey, err error) {
+	if err := payload.Validate(v.validator); err != nil {
+		return nil, err
+	}
+	return v.driplimit.ServiceKeyRotate(ctx, payload)
+}
+
+func (v *Validator) ServiceKeyPromote(ctx context.Context, payload ServiceKeyPromotePayload) (sk *ServiceKey, err error) {
+	if err := payload.Validate(v.validator); err != nil {
+		return nil, err
+	}
+	return v.driplimit.ServiceKeyPromote(ctx, payload)
+}
+
+func (v *Validator) ServiceKeyRevokePrevious(ctx context.Context, payload ServiceKeyRevokePreviousPayload) (sk *ServiceKey, err error) {
+	if err := payload.Validate(v.validator); err != nil {
+		return nil, err
+	}
+	return v.driplimit.ServiceKeyRevokePrevious(ctx, payload)
+}
+
+func (v *Validator) ServiceKeyUpdate(ctx context.Context, payload ServiceKeyUpdatePayload) (sk *ServiceKey, err error) {
+	if err := payload.Validate(v.validator); err != nil {
+		return nil, err
+	}
+	return v.driplimit.ServiceKeyUpdate(ctx, payload)
+}
+
+func (v *Validator) ServiceKeyCan(ctx context.Context, payload ServiceKeyCanPayload) (permission *Permission, err error) {
+	if err := payload.Validate(v.validator); err != nil {
+		return nil, err
+	}
+	return v.driplimit.ServiceKeyCan(ctx, payload)
+}
+
+func (v *Validator) AuditList(ctx context.Context, payload AuditListPayload) (alist *AuditList, err error) {
+	if err := payload.Validate(v.validator); err != nil {
+		return nil, err
+	}
+	return v.driplimit.AuditList(ctx, payload)
+}